@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mr-tron/base58"
+)
+
+// defaultMaxAccountData caps how many bytes of raw account data are returned.
+const defaultMaxAccountData = 64 * 1024
+
+type DataSlice struct {
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+}
+
+// AccountInfoOptions controls the optional parts of an account lookup. The
+// zero value fetches only the summary fields.
+type AccountInfoOptions struct {
+	DataEncoding string
+	DataSlice    *DataSlice
+	// JSONParsed asks the node to parse the data of the accounts it knows,
+	// such as token and stake accounts; see AccountInfo.ParsedData.
+	JSONParsed bool
+}
+
+func parseAccountInfoOptions(c *gin.Context) (AccountInfoOptions, error) {
+	var opts AccountInfoOptions
+
+	switch encoding := c.Query("encoding"); encoding {
+	case "":
+	case "jsonParsed":
+		if c.Query("data") != "" {
+			return opts, fmt.Errorf("encoding=jsonParsed cannot be combined with data")
+		}
+		opts.JSONParsed = true
+		return opts, nil
+	default:
+		return opts, fmt.Errorf("encoding must be jsonParsed")
+	}
+
+	// An unescaped "+" in the query string reads as a space.
+	switch encoding := strings.ReplaceAll(c.Query("data"), " ", "+"); encoding {
+	case "":
+		return opts, nil
+	case "hex", "base64", zstdEncoding:
+		opts.DataEncoding = encoding
+	default:
+		return opts, fmt.Errorf("data must be hex, base64 or base64+zstd")
+	}
+
+	offsetStr, lengthStr := c.Query("offset"), c.Query("length")
+	if offsetStr == "" && lengthStr == "" {
+		return opts, nil
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		return opts, fmt.Errorf("offset must be a non-negative integer")
+	}
+	length, err := strconv.Atoi(lengthStr)
+	if err != nil || length < 0 {
+		return opts, fmt.Errorf("length must be a non-negative integer")
+	}
+
+	opts.DataSlice = &DataSlice{Offset: offset, Length: length}
+	return opts, nil
+}
+
+// accountDataConfig builds the getAccountInfo config for a data request. The
+// slice is bounded by maxAccountData (plus one byte to detect truncation) so
+// large accounts are never transferred in full.
+func (s *SolanaRPCClient) accountDataConfig(opts AccountInfoOptions) map[string]interface{} {
+	slice := DataSlice{Offset: 0, Length: s.maxAccountData + 1}
+	if opts.DataSlice != nil {
+		slice = *opts.DataSlice
+		if slice.Length > s.maxAccountData+1 {
+			slice.Length = s.maxAccountData + 1
+		}
+	}
+
+	return map[string]interface{}{
+		"encoding":  s.accountDataEncoding(opts.DataEncoding),
+		"dataSlice": slice,
+	}
+}
+
+// accountDataLength returns the size in bytes of an account's data. The
+// space field is the authoritative size and also covers requests made with a
+// dataSlice. Without it the returned data is decoded and measured, which
+// under a dataSlice only gives the slice's length; jsonParsed data carries
+// its own space field.
+func accountDataLength(value map[string]interface{}) int {
+	if space, ok := parseUint64Result(value["space"]); ok {
+		return int(space)
+	}
+
+	switch data := value["data"].(type) {
+	case []interface{}:
+		if len(data) < 2 {
+			return 0
+		}
+		encoded, _ := data[0].(string)
+		encoding, _ := data[1].(string)
+		switch encoding {
+		case "base64", zstdEncoding:
+			decoded, err := decodeAccountBytes(data)
+			if err != nil {
+				return 0
+			}
+			return len(decoded)
+		case "base58":
+			decoded, err := base58.Decode(encoded)
+			if err != nil {
+				return 0
+			}
+			return len(decoded)
+		}
+	case string:
+		// The legacy binary encoding is base58 without the encoding tag.
+		decoded, err := base58.Decode(data)
+		if err != nil {
+			return 0
+		}
+		return len(decoded)
+	case map[string]interface{}:
+		if space, ok := parseUint64Result(data["space"]); ok {
+			return int(space)
+		}
+	}
+	return 0
+}
+
+// attachAccountData decodes the returned data, decompressing it if it was
+// fetched as base64+zstd, truncates it to maxAccountData and re-encodes it as
+// requested. Data requested as base64+zstd is compressed again once it has
+// been measured and truncated.
+func (s *SolanaRPCClient) attachAccountData(accountInfo *AccountInfo, rawData interface{}, opts AccountInfoOptions) error {
+	decoded, err := decodeAccountBytes(rawData)
+	if err != nil {
+		return err
+	}
+
+	if len(decoded) > s.maxAccountData {
+		decoded = decoded[:s.maxAccountData]
+		accountInfo.DataTruncated = true
+	}
+
+	accountInfo.DataEncoding = opts.DataEncoding
+	switch opts.DataEncoding {
+	case "hex":
+		accountInfo.Data = hex.EncodeToString(decoded)
+	case zstdEncoding:
+		accountInfo.Data = base64.StdEncoding.EncodeToString(zstdEncoder.EncodeAll(decoded, nil))
+	default:
+		accountInfo.Data = base64.StdEncoding.EncodeToString(decoded)
+	}
+
+	return nil
+}
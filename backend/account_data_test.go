@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/mr-tron/base58"
+)
+
+// tokenAccountSize is the size of an SPL token account.
+const tokenAccountSize = 165
+
+// tokenAccountData is tokenAccountSize bytes of data that are not all zero,
+// so base58 does not collapse them to leading ones.
+func tokenAccountData() []byte {
+	data := make([]byte, tokenAccountSize)
+	for i := range data {
+		data[i] = byte(i + 1)
+	}
+	return data
+}
+
+func TestAccountDataLength(t *testing.T) {
+	data := tokenAccountData()
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	tests := []struct {
+		name  string
+		value map[string]interface{}
+		want  int
+	}{
+		{"base64", map[string]interface{}{"data": []interface{}{encoded, "base64"}}, tokenAccountSize},
+		{"base64+zstd", map[string]interface{}{"data": []interface{}{base64.StdEncoding.EncodeToString(zstdEncoder.EncodeAll(data, nil)), zstdEncoding}}, tokenAccountSize},
+		{"base58", map[string]interface{}{"data": []interface{}{base58.Encode(data), "base58"}}, tokenAccountSize},
+		{"legacy binary", map[string]interface{}{"data": base58.Encode(data)}, tokenAccountSize},
+		{"jsonParsed", map[string]interface{}{"data": map[string]interface{}{"program": "spl-token", "parsed": map[string]interface{}{}, "space": float64(tokenAccountSize)}}, tokenAccountSize},
+		{"space over sliced data", map[string]interface{}{"data": []interface{}{base64.StdEncoding.EncodeToString(data[:32]), "base64"}, "space": float64(tokenAccountSize)}, tokenAccountSize},
+		{"empty", map[string]interface{}{"data": []interface{}{"", "base64"}}, 0},
+		{"invalid base64", map[string]interface{}{"data": []interface{}{"not base64!", "base64"}}, 0},
+		{"missing data", map[string]interface{}{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := accountDataLength(tt.value); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAccountInfoDataLength(t *testing.T) {
+	data := tokenAccountData()
+
+	tests := []struct {
+		name string
+		opts AccountInfoOptions
+	}{
+		{"summary", AccountInfoOptions{}},
+		{"hex", AccountInfoOptions{DataEncoding: "hex"}},
+		{"base64+zstd", AccountInfoOptions{DataEncoding: zstdEncoding}},
+		{"sliced", AccountInfoOptions{DataEncoding: "base64", DataSlice: &DataSlice{Offset: 0, Length: 32}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, client := newTestRPCNode(t, func(method string, params []interface{}) interface{} {
+				if method != "getAccountInfo" {
+					return nil
+				}
+				returned := data
+				config, _ := params[len(params)-1].(map[string]interface{})
+				if slice, ok := config["dataSlice"].(map[string]interface{}); ok {
+					if length, ok := slice["length"].(float64); ok && int(length) < len(returned) {
+						returned = returned[:int(length)]
+					}
+				}
+				encoding, _ := config["encoding"].(string)
+				encoded := base64.StdEncoding.EncodeToString(returned)
+				if encoding == zstdEncoding {
+					encoded = base64.StdEncoding.EncodeToString(zstdEncoder.EncodeAll(returned, nil))
+				} else if encoding == "base58" {
+					encoded = base58.Encode(returned)
+				}
+				return map[string]interface{}{
+					"context": map[string]interface{}{"slot": 1},
+					"value": map[string]interface{}{
+						"lamports":   2039280,
+						"owner":      tokenProgramID,
+						"executable": false,
+						"rentEpoch":  0,
+						"data":       []interface{}{encoded, encoding},
+						"space":      tokenAccountSize,
+					},
+				}
+			})
+
+			account, err := client.GetAccountInfoWithOptions(context.Background(), "Account1111111111111111111111111111111111111", tt.opts)
+			if err != nil {
+				t.Fatalf("GetAccountInfoWithOptions: %v", err)
+			}
+			if account.DataLength != tokenAccountSize {
+				t.Errorf("DataLength = %d, want %d", account.DataLength, tokenAccountSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxSignaturesPerPage = 1000
+	maxCreationPages     = 20
+	// defaultTransactionsPageSize is the /api/account/:address/transactions
+	// page size when ?limit= is not given.
+	defaultTransactionsPageSize = 25
+)
+
+type SignatureInfo struct {
+	Signature          string      `json:"signature"`
+	Slot               BigUint     `json:"slot"`
+	BlockTime          *int64      `json:"blockTime"`
+	Err                interface{} `json:"err"`
+	Memo               *string     `json:"memo"`
+	ConfirmationStatus string      `json:"confirmationStatus,omitempty"`
+}
+
+type AccountCreation struct {
+	Address      string  `json:"address"`
+	Signature    string  `json:"signature"`
+	Slot         BigUint `json:"slot"`
+	BlockTime    *int64  `json:"blockTime"`
+	Approximate  bool    `json:"approximate"`
+	PagesScanned int     `json:"pagesScanned"`
+}
+
+func (s *SolanaRPCClient) GetSignaturesForAddress(ctx context.Context, address string, limit int, before string) ([]SignatureInfo, error) {
+	if limit <= 0 || limit > maxSignaturesPerPage {
+		limit = maxSignaturesPerPage
+	}
+
+	options := map[string]interface{}{"limit": limit}
+	if before != "" {
+		options["before"] = before
+	}
+
+	resp, err := s.makeRPCCallWithRetry(ctx, "getSignaturesForAddress", []interface{}{address, options})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	return parseSignatureInfos(resp.Result)
+}
+
+func parseSignatureInfos(result interface{}) ([]SignatureInfo, error) {
+	entries, ok := result.([]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getSignaturesForAddress", Detail: "result is not an array"}
+	}
+
+	signatures := make([]SignatureInfo, 0, len(entries))
+	for _, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		info := SignatureInfo{Err: fields["err"]}
+		info.Signature, _ = fields["signature"].(string)
+		info.ConfirmationStatus, _ = fields["confirmationStatus"].(string)
+		if slot, ok := fields["slot"].(float64); ok {
+			info.Slot = BigUint(slot)
+		}
+		if blockTime, ok := fields["blockTime"].(float64); ok {
+			t := int64(blockTime)
+			info.BlockTime = &t
+		}
+		if memo, ok := fields["memo"].(string); ok {
+			info.Memo = &memo
+		}
+		signatures = append(signatures, info)
+	}
+
+	return signatures, nil
+}
+
+// GetAccountCreation pages backward through an address's signatures to find
+// the oldest one. Scanning stops after maxCreationPages pages, in which case
+// the result is the oldest signature seen so far and is marked approximate.
+// Returns nil when the address has no transactions.
+func (s *SolanaRPCClient) GetAccountCreation(ctx context.Context, address string) (*AccountCreation, error) {
+	cacheKey := s.cacheKey("account_creation", address)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if creation, ok := cached.(*AccountCreation); ok {
+			return creation, nil
+		}
+	}
+
+	var oldest *SignatureInfo
+	before := ""
+	exhausted := false
+	pages := 0
+
+	for pages < maxCreationPages {
+		signatures, err := s.GetSignaturesForAddress(ctx, address, maxSignaturesPerPage, before)
+		if err != nil {
+			return nil, err
+		}
+		pages++
+
+		if len(signatures) > 0 {
+			oldest = &signatures[len(signatures)-1]
+			before = oldest.Signature
+		}
+
+		if len(signatures) < maxSignaturesPerPage {
+			exhausted = true
+			break
+		}
+	}
+
+	if oldest == nil {
+		return nil, nil
+	}
+
+	creation := &AccountCreation{
+		Address:      address,
+		Signature:    oldest.Signature,
+		Slot:         oldest.Slot,
+		BlockTime:    oldest.BlockTime,
+		Approximate:  !exhausted,
+		PagesScanned: pages,
+	}
+
+	// The true creation transaction never changes; an approximate answer is
+	// kept for a shorter time so the scan is retried eventually.
+	if creation.Approximate {
+		s.setCache(ctx, cacheKey, creation, 1*time.Hour)
+	} else {
+		s.setImmutableCache(ctx, cacheKey, creation, 24*time.Hour)
+	}
+
+	return creation, nil
+}
+
+func handleAccountCreation(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		address := c.Param("address")
+		if address == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Address parameter is required"})
+			return
+		}
+
+		creation, err := client.GetAccountCreation(c.Request.Context(), address)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get account creation"})
+			return
+		}
+
+		if creation == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No transactions found for address"})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, creation)
+	}
+}
+
+// handleAccountTransactions serves an address's signatures newest first, a
+// page at a time. nextBefore is the cursor for the following page and is
+// omitted once a page comes back short, at the end of the history.
+func handleAccountTransactions(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		address := c.Param("address")
+		if _, err := decodePublicKey(address); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+			return
+		}
+
+		limit := defaultTransactionsPageSize
+		if raw := c.Query("limit"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+				return
+			}
+			limit = min(parsed, maxSignaturesPerPage)
+		}
+
+		before := c.Query("before")
+		if before != "" && !isValidSignature(before) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before must be a transaction signature"})
+			return
+		}
+
+		signatures, err := client.GetSignaturesForAddress(c.Request.Context(), address, limit, before)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get transactions"})
+			return
+		}
+
+		response := gin.H{"address": address, "signatures": signatures, "limit": limit}
+		if len(signatures) == limit {
+			response["nextBefore"] = signatures[len(signatures)-1].Signature
+		}
+		respondJSON(c, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdEncoding is the getAccountInfo and getProgramAccounts encoding for
+// base64 of zstd-compressed data. Clients can ask for it with
+// ?data=base64+zstd, and RPC_ZSTD_ACCOUNT_DATA requests it upstream for every
+// data lookup.
+const zstdEncoding = "base64+zstd"
+
+// maxDecompressedAccountData is the largest account the runtime allows
+// (MAX_PERMITTED_DATA_LENGTH). It bounds decompression so a corrupt or
+// hostile payload cannot expand without limit.
+const maxDecompressedAccountData = 10 * 1024 * 1024
+
+var (
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(maxDecompressedAccountData))
+	zstdEncoder, _ = zstd.NewWriter(nil)
+)
+
+// decodeAccountBytes returns the raw bytes of account data in the [data,
+// encoding] form, decompressing base64+zstd.
+func decodeAccountBytes(rawData interface{}) ([]byte, error) {
+	data, ok := rawData.([]interface{})
+	if !ok || len(data) == 0 {
+		return nil, fmt.Errorf("invalid account data")
+	}
+	encoded, ok := data[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid account data")
+	}
+	encoding := "base64"
+	if len(data) > 1 {
+		encoding, _ = data[1].(string)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account data: %w", err)
+	}
+
+	switch encoding {
+	case "base64":
+		return decoded, nil
+	case zstdEncoding:
+		decompressed, err := zstdDecoder.DecodeAll(decoded, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account data: zstd: %w", err)
+		}
+		return decompressed, nil
+	default:
+		return nil, fmt.Errorf("unexpected account data encoding %q", encoding)
+	}
+}
+
+// accountDataEncoding is the upstream encoding for a data lookup.
+func (s *SolanaRPCClient) accountDataEncoding(requested string) string {
+	if requested == zstdEncoding || s.zstdAccountData {
+		return zstdEncoding
+	}
+	return "base64"
+}
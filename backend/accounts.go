@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// multipleAccountsLimit is the most addresses getMultipleAccounts accepts.
+	multipleAccountsLimit = 100
+	// maxBatchAccounts bounds the upstream cost of a single /api/accounts request.
+	maxBatchAccounts                = 1000
+	defaultAccountsChunkConcurrency = 4
+)
+
+type MultipleAccountsRequest struct {
+	Addresses []string `json:"addresses" binding:"required"`
+}
+
+// GetMultipleAccounts fetches accounts in input order. Repeated addresses are
+// fetched once and their result is returned at every position they appear
+// in. Lists of more than multipleAccountsLimit distinct addresses are split
+// into chunks fetched by at most accountsChunkConcurrency workers; any
+// failing chunk fails the whole call.
+func (s *SolanaRPCClient) GetMultipleAccounts(ctx context.Context, requested []string) ([]*AccountInfo, error) {
+	var addresses []string
+	positions := make(map[string]int, len(requested))
+	for _, address := range requested {
+		if _, seen := positions[address]; !seen {
+			positions[address] = len(addresses)
+			addresses = append(addresses, address)
+		}
+	}
+
+	accounts := make([]*AccountInfo, len(addresses))
+
+	var chunks [][2]int
+	for start := 0; start < len(addresses); start += multipleAccountsLimit {
+		end := start + multipleAccountsLimit
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+		chunks = append(chunks, [2]int{start, end})
+	}
+
+	concurrency := s.accountsChunkConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+	for _, chunk := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			fetched, err := s.getMultipleAccountsChunk(ctx, addresses[start:end])
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				return
+			}
+			// Chunks write disjoint ranges, so no locking is needed here.
+			copy(accounts[start:end], fetched)
+		}(chunk[0], chunk[1])
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	results := make([]*AccountInfo, len(requested))
+	for i, address := range requested {
+		results[i] = accounts[positions[address]]
+	}
+	return results, nil
+}
+
+func (s *SolanaRPCClient) getMultipleAccountsChunk(ctx context.Context, addresses []string) ([]*AccountInfo, error) {
+	params := []interface{}{addresses, map[string]interface{}{"encoding": "base64"}}
+	resp, err := s.makeRPCCallWithRetry(ctx, "getMultipleAccounts", params)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, contextSlot, err := unwrapContext("getMultipleAccounts", resp.Result)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := result.([]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getMultipleAccounts", Detail: "value is not an array"}
+	}
+	if len(values) != len(addresses) {
+		return nil, &ParseError{Method: "getMultipleAccounts", Detail: fmt.Sprintf("expected %d accounts, got %d", len(addresses), len(values))}
+	}
+
+	accounts := make([]*AccountInfo, len(addresses))
+	for i, value := range values {
+		if account, ok := value.(map[string]interface{}); ok {
+			accounts[i] = parseAccountValue(addresses[i], account)
+			accounts[i].ContextSlot = BigUint(contextSlot)
+		} else {
+			accounts[i] = &AccountInfo{Address: addresses[i], IsValid: false}
+		}
+	}
+
+	return accounts, nil
+}
+
+// Batch error modes selected with ?onError=. "null" keeps index alignment
+// with null placeholders, "skip" drops failed entries and "fail" rejects the
+// whole batch. All modes report the failures in an errors map keyed by
+// address.
+const (
+	onErrorNull = "null"
+	onErrorSkip = "skip"
+	onErrorFail = "fail"
+)
+
+func handleMultipleAccounts(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MultipleAccountsRequest
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.Addresses) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "At least one address is required"})
+			return
+		}
+		if len(req.Addresses) > maxBatchAccounts {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d addresses are allowed per request", maxBatchAccounts)})
+			return
+		}
+
+		onError := c.DefaultQuery("onError", onErrorNull)
+		if onError != onErrorNull && onError != onErrorSkip && onError != onErrorFail {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "onError must be one of skip, null or fail"})
+			return
+		}
+
+		// Malformed addresses would make the RPC reject the whole chunk, so
+		// they are filtered out before the upstream call.
+		errs := map[string]string{}
+		var lookup []string
+		for _, address := range req.Addresses {
+			if _, err := decodePublicKey(address); err != nil {
+				errs[address] = "Invalid address"
+				continue
+			}
+			lookup = append(lookup, address)
+		}
+		if onError == onErrorFail && len(errs) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The batch contains invalid addresses", "errors": errs})
+			return
+		}
+
+		fetched, err := client.GetMultipleAccounts(c.Request.Context(), lookup)
+		if err != nil {
+			respondBatchFailure(c, "accounts", err)
+			return
+		}
+		byAddress := make(map[string]*AccountInfo, len(fetched))
+		for _, account := range fetched {
+			byAddress[account.Address] = account
+		}
+
+		accounts := make([]*AccountInfo, 0, len(req.Addresses))
+		for _, address := range req.Addresses {
+			account := byAddress[address]
+			if account != nil && !account.IsValid {
+				errs[address] = "Account not found"
+				account = nil
+			}
+			if account == nil && onError == onErrorSkip {
+				continue
+			}
+			accounts = append(accounts, account)
+		}
+		if onError == onErrorFail && len(errs) > 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Some accounts were not found", "errors": errs})
+			return
+		}
+
+		respondBatch(c, "accounts", accounts, len(accounts), errs)
+	}
+}
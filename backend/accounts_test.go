@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// echoAccounts answers getMultipleAccounts with one account per address,
+// owned by the address itself so results can be matched to their input.
+func echoAccounts(method string, params []interface{}) interface{} {
+	if method != "getMultipleAccounts" {
+		return nil
+	}
+	addresses, _ := params[0].([]interface{})
+	values := make([]interface{}, len(addresses))
+	for i, address := range addresses {
+		values[i] = map[string]interface{}{
+			"lamports":   1000000000,
+			"owner":      address,
+			"executable": false,
+			"rentEpoch":  0,
+			"data":       []interface{}{"", "base64"},
+			"space":      0,
+		}
+	}
+	return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": values}
+}
+
+func TestGetMultipleAccountsChunksInOrder(t *testing.T) {
+	addresses := make([]string, 250)
+	for i := range addresses {
+		addresses[i] = fmt.Sprintf("Address%03d", i)
+	}
+
+	tests := []struct {
+		name        string
+		concurrency int
+	}{
+		{"one worker", 1},
+		{"default workers", defaultAccountsChunkConcurrency},
+		{"worker per chunk", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, client := newTestRPCNode(t, echoAccounts)
+			client.accountsChunkConcurrency = tt.concurrency
+
+			accounts, err := client.GetMultipleAccounts(context.Background(), addresses)
+			if err != nil {
+				t.Fatalf("GetMultipleAccounts: %v", err)
+			}
+			if len(accounts) != len(addresses) {
+				t.Fatalf("got %d accounts, want %d", len(accounts), len(addresses))
+			}
+			for i, account := range accounts {
+				if account == nil || account.Owner != addresses[i] {
+					t.Fatalf("account %d is %+v, want the account of %s", i, account, addresses[i])
+				}
+			}
+
+			calls := node.callsTo("getMultipleAccounts")
+			if len(calls) != 3 {
+				t.Fatalf("got %d getMultipleAccounts calls, want 3", len(calls))
+			}
+			for _, call := range calls {
+				chunk, _ := call.Params[0].([]interface{})
+				if len(chunk) > multipleAccountsLimit {
+					t.Errorf("chunk of %d addresses exceeds the limit of %d", len(chunk), multipleAccountsLimit)
+				}
+			}
+		})
+	}
+}
+
+func TestGetMultipleAccountsDeduplicates(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []string
+		distinct  int
+	}{
+		{"no duplicates", []string{"A", "B", "C"}, 3},
+		{"adjacent duplicates", []string{"A", "A", "B"}, 2},
+		{"scattered duplicates", []string{"A", "B", "A", "C", "B", "A"}, 3},
+		{"one address repeated", []string{"A", "A", "A", "A"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, client := newTestRPCNode(t, echoAccounts)
+
+			accounts, err := client.GetMultipleAccounts(context.Background(), tt.addresses)
+			if err != nil {
+				t.Fatalf("GetMultipleAccounts: %v", err)
+			}
+			if len(accounts) != len(tt.addresses) {
+				t.Fatalf("got %d accounts, want %d", len(accounts), len(tt.addresses))
+			}
+			for i, account := range accounts {
+				if account == nil || account.Owner != tt.addresses[i] {
+					t.Errorf("account %d is %+v, want the account of %s", i, account, tt.addresses[i])
+				}
+			}
+
+			calls := node.callsTo("getMultipleAccounts")
+			if len(calls) != 1 {
+				t.Fatalf("got %d getMultipleAccounts calls, want 1", len(calls))
+			}
+			if requested, _ := calls[0].Params[0].([]interface{}); len(requested) != tt.distinct {
+				t.Errorf("requested %v upstream, want %d distinct addresses", requested, tt.distinct)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBatchBalances bounds a /api/balances request, which is sent upstream as
+// a single JSON-RPC batch.
+const maxBatchBalances = 100
+
+type RPCRequest struct {
+	Method string
+	Params []interface{}
+}
+
+type batchRPCResponse struct {
+	ID *int `json:"id"`
+	RPCResponse
+}
+
+// makeBatchRPCCall sends calls as one JSON-RPC batch, so they cost a single
+// HTTP request, and returns their responses in call order. Nodes may answer
+// a batch in any order, so responses are matched by id; a call left
+// unanswered gets an RPC error. The batch goes to the endpoint of its first
+// call's method and, like makeRPCCall, is not retried.
+func (s *SolanaRPCClient) makeBatchRPCCall(ctx context.Context, calls []RPCRequest) ([]RPCResponse, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+
+	payload := make([]map[string]interface{}, len(calls))
+	for i, call := range calls {
+		payload[i] = map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      i,
+			"method":  call.Method,
+			"params":  call.Params,
+		}
+	}
+
+	if err := s.waitTokenBucket(ctx, calls[0].Method, len(calls)); err != nil {
+		return nil, err
+	}
+	var responses []batchRPCResponse
+	var rateLimited error
+	err := s.withFailover(ctx, calls[0].Method, func(endpoint *rpcEndpoint) error {
+		started := time.Now()
+		var err error
+		responses, err = postBatchRPC(ctx, s.httpClient, endpoint.url, payload)
+		observeRPC("batch", started, nil, err)
+		// As for a single call, a 429 is an answer rather than an outage:
+		// it neither counts against the endpoint nor fails over.
+		var rateLimitErr *RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			rateLimited = err
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rateLimited != nil {
+		return nil, rateLimited
+	}
+
+	ordered := make([]RPCResponse, len(calls))
+	answered := make([]bool, len(calls))
+	for _, resp := range responses {
+		if resp.ID == nil || *resp.ID < 0 || *resp.ID >= len(calls) {
+			continue
+		}
+		ordered[*resp.ID] = resp.RPCResponse
+		answered[*resp.ID] = true
+	}
+	for i := range ordered {
+		if !answered[i] {
+			ordered[i].Error = map[string]interface{}{"message": "no response in batch"}
+		}
+	}
+
+	return ordered, nil
+}
+
+// postBatchRPC posts a JSON-RPC batch. A node that refuses the batch as a
+// whole, for instance when rate limited, answers with a single error object
+// instead of an array, which is returned as an error.
+func postBatchRPC(ctx context.Context, httpClient *http.Client, endpointURL string, payload []map[string]interface{}) ([]batchRPCResponse, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return nil, fmt.Errorf("batch: RPC endpoint returned HTTP %d", resp.StatusCode)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &RateLimitError{Method: "batch"}
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	var responses []batchRPCResponse
+	if err := json.Unmarshal(raw, &responses); err != nil {
+		var single RPCResponse
+		if json.Unmarshal(raw, &single) == nil && single.Error != nil {
+			return nil, fmt.Errorf("batch rejected: %v", single.Error)
+		}
+		return nil, &ParseError{Method: "batch", Detail: "response is not an array"}
+	}
+
+	return responses, nil
+}
+
+// GetMultipleBalances fetches the SOL balances of addresses with one
+// getBalance batch. Cached balances of pinned addresses are used as is; an
+// address whose call failed is reported in the errors map instead.
+func (s *SolanaRPCClient) GetMultipleBalances(ctx context.Context, addresses []string) (map[string]float64, map[string]string, error) {
+	balances := make(map[string]float64, len(addresses))
+	errs := map[string]string{}
+
+	var calls []RPCRequest
+	var lookup []string
+	seen := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		if cached, found := s.getFromCache(ctx, s.cacheKey("balance", address)); found {
+			if balance, ok := cached.(solBalance); ok {
+				balances[address] = balance.sol
+				continue
+			}
+		}
+		lookup = append(lookup, address)
+		calls = append(calls, RPCRequest{Method: "getBalance", Params: []interface{}{address}})
+	}
+
+	responses, err := s.makeBatchRPCCall(ctx, calls)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, address := range lookup {
+		balance, err := parseBalanceResponse(&responses[i])
+		if err != nil {
+			errs[address] = err.Error()
+			continue
+		}
+		balances[address] = balance.sol
+		s.setPinnedCache(ctx, s.cacheKey("balance", address), balance)
+	}
+
+	return balances, errs, nil
+}
+
+// respondBatchFailure answers a batch whose upstream call failed as a whole,
+// for instance on a transport error or a rejected JSON-RPC batch. None of the
+// entries could be looked up, so this is a 502 rather than a partial result.
+func respondBatchFailure(c *gin.Context, what string, err error) {
+	c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to get " + what, "details": err.Error()})
+}
+
+// respondBatch answers a batch whose upstream call succeeded. Entries that
+// are missing, null or invalid are not a failure of the batch: they are
+// listed in errs, keyed by address, and partial says whether there are any.
+func respondBatch(c *gin.Context, field string, results interface{}, count int, errs map[string]string) {
+	respondJSON(c, http.StatusOK, gin.H{field: results, "count": count, "errors": errs, "partial": len(errs) > 0})
+}
+
+func handleMultipleBalances(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MultipleAccountsRequest
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.Addresses) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "At least one address is required"})
+			return
+		}
+		if len(req.Addresses) > maxBatchBalances {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d addresses are allowed per request", maxBatchBalances)})
+			return
+		}
+
+		// A malformed address only fails its own call, but there is no
+		// point sending it.
+		errs := map[string]string{}
+		var lookup []string
+		for _, address := range req.Addresses {
+			if !isValidSolanaAddress(address) {
+				errs[address] = "Invalid address"
+				continue
+			}
+			lookup = append(lookup, address)
+		}
+
+		balances, failed, err := client.GetMultipleBalances(c.Request.Context(), lookup)
+		if err != nil {
+			respondBatchFailure(c, "balances", err)
+			return
+		}
+		for address, reason := range failed {
+			errs[address] = reason
+		}
+
+		respondBatch(c, "balances", balances, len(balances), errs)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetMultipleBalancesDeduplicates(t *testing.T) {
+	balances := map[string]float64{"A": 1e9, "B": 2.5e9, "C": 0}
+	respond := func(method string, params []interface{}) interface{} {
+		if method != "getBalance" {
+			return nil
+		}
+		address, _ := params[0].(string)
+		return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": balances[address]}
+	}
+
+	tests := []struct {
+		name      string
+		addresses []string
+		want      map[string]float64
+	}{
+		{"no duplicates", []string{"A", "B"}, map[string]float64{"A": 1, "B": 2.5}},
+		{"duplicates", []string{"A", "B", "A", "C", "B"}, map[string]float64{"A": 1, "B": 2.5, "C": 0}},
+		{"one address repeated", []string{"C", "C", "C"}, map[string]float64{"C": 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, client := newTestRPCNode(t, respond)
+
+			got, errs, err := client.GetMultipleBalances(context.Background(), tt.addresses)
+			if err != nil {
+				t.Fatalf("GetMultipleBalances: %v", err)
+			}
+			if len(errs) != 0 {
+				t.Fatalf("got errors %v", errs)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for address, want := range tt.want {
+				if got[address] != want {
+					t.Errorf("balance of %s = %v, want %v", address, got[address], want)
+				}
+			}
+
+			if calls := node.callsTo("getBalance"); len(calls) != len(tt.want) {
+				t.Errorf("got %d getBalance calls in the batch, want %d", len(calls), len(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// bigIntStringsVersion is the API version from which BigUint values are
+// encoded as decimal strings.
+const bigIntStringsVersion = 2
+
+// BigUint is a uint64 response field that can exceed 2^53, the largest
+// integer JavaScript numbers represent exactly: token supplies, lamport
+// balances, slots. It is a JSON number in API version 1; from
+// bigIntStringsVersion responses go through withBigIntStrings, which encodes
+// it as a decimal string so browser clients do not silently round it.
+type BigUint uint64
+
+// bigUintString is a BigUint that marshals as a decimal string.
+type bigUintString uint64
+
+func (n bigUintString) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + strconv.FormatUint(uint64(n), 10) + `"`), nil
+}
+
+var (
+	bigUintType       = reflect.TypeOf(BigUint(0))
+	bigUintStringType = reflect.TypeOf(bigUintString(0))
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// withBigIntStrings returns a copy of v in which every BigUint, however deeply
+// nested, encodes as a string. Structs are copied into equivalent types
+// built with the same JSON tags, so field names and omitempty behave as
+// before; unexported fields, which JSON skips anyway, are dropped. Types
+// with their own JSON or text encoding are left alone, as are values that
+// cannot hold a BigUint.
+func withBigIntStrings(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	value := reflect.ValueOf(v)
+	converted := convertBigInts(value, stringsType(value.Type()))
+	return converted.Interface()
+}
+
+// bigIntTypes caches stringsType, which is called for every response.
+var bigIntTypes sync.Map
+
+// stringsType returns the type that t converts to: t itself when none of its
+// values can hold a BigUint.
+func stringsType(t reflect.Type) reflect.Type {
+	if cached, ok := bigIntTypes.Load(t); ok {
+		return cached.(reflect.Type)
+	}
+	converted := buildStringsType(t, map[reflect.Type]bool{})
+	bigIntTypes.Store(t, converted)
+	return converted
+}
+
+func buildStringsType(t reflect.Type, visiting map[reflect.Type]bool) reflect.Type {
+	if t == bigUintType {
+		return bigUintStringType
+	}
+	// Recursive types are left as they are rather than expanded forever.
+	if visiting[t] || ownEncoding(t) {
+		return t
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return reflect.PointerTo(buildStringsType(t.Elem(), visiting))
+	case reflect.Slice:
+		return reflect.SliceOf(buildStringsType(t.Elem(), visiting))
+	case reflect.Array:
+		return reflect.ArrayOf(t.Len(), buildStringsType(t.Elem(), visiting))
+	case reflect.Map:
+		return reflect.MapOf(t.Key(), buildStringsType(t.Elem(), visiting))
+	case reflect.Struct:
+		if !mayHoldBigInts(t, visiting) {
+			return t
+		}
+		fields := make([]reflect.StructField, 0, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			field.Type = buildStringsType(field.Type, visiting)
+			fields = append(fields, field)
+		}
+		return reflect.StructOf(fields)
+	default:
+		return t
+	}
+}
+
+// mayHoldBigInts reports whether a value of struct type t can contain a
+// BigUint, either in its fields or behind an interface.
+func mayHoldBigInts(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		elem := field.Type
+		for elem.Kind() == reflect.Pointer || elem.Kind() == reflect.Slice || elem.Kind() == reflect.Array || elem.Kind() == reflect.Map {
+			elem = elem.Elem()
+		}
+		if elem == bigUintType || elem.Kind() == reflect.Interface {
+			return true
+		}
+		if elem.Kind() == reflect.Struct && !visiting[elem] && buildStringsType(elem, visiting) != elem {
+			return true
+		}
+	}
+	return false
+}
+
+// ownEncoding reports whether t marshals itself, like time.Time and
+// json.RawMessage, and so must not be rebuilt.
+func ownEncoding(t reflect.Type) bool {
+	if t.Kind() == reflect.Interface {
+		return false
+	}
+	return t.Implements(marshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(marshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)
+}
+
+// convertBigInts copies v into a value of type to, which stringsType built
+// from v's type.
+func convertBigInts(v reflect.Value, to reflect.Type) reflect.Value {
+	if v.Type() == bigUintType {
+		return reflect.ValueOf(bigUintString(v.Uint()))
+	}
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v
+		}
+		inner := v.Elem()
+		converted := convertBigInts(inner, stringsType(inner.Type()))
+		if !converted.Type().AssignableTo(to) {
+			return v
+		}
+		result := reflect.New(to).Elem()
+		result.Set(converted)
+		return result
+	}
+	if v.Type() == to && v.Kind() != reflect.Map && v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return reflect.Zero(to)
+		}
+		result := reflect.New(to.Elem())
+		result.Elem().Set(convertBigInts(v.Elem(), to.Elem()))
+		return result
+	case reflect.Slice:
+		if v.IsNil() || !holdsBigInts(v.Type().Elem()) {
+			return v
+		}
+		result := reflect.MakeSlice(to, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			result.Index(i).Set(convertBigInts(v.Index(i), to.Elem()))
+		}
+		return result
+	case reflect.Array:
+		if !holdsBigInts(v.Type().Elem()) {
+			return v
+		}
+		result := reflect.New(to).Elem()
+		for i := 0; i < v.Len(); i++ {
+			result.Index(i).Set(convertBigInts(v.Index(i), to.Elem()))
+		}
+		return result
+	case reflect.Map:
+		if v.IsNil() || !holdsBigInts(v.Type().Elem()) {
+			return v
+		}
+		result := reflect.MakeMapWithSize(to, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			result.SetMapIndex(iter.Key(), convertBigInts(iter.Value(), to.Elem()))
+		}
+		return result
+	case reflect.Struct:
+		result := reflect.New(to).Elem()
+		for i, j := 0, 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			result.Field(j).Set(convertBigInts(v.Field(i), to.Field(j).Type))
+			j++
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// holdsBigInts reports whether values of type t may need converting: t
+// changes under stringsType or is an interface that may hold a BigUint.
+func holdsBigInts(t reflect.Type) bool {
+	return t.Kind() == reflect.Interface || stringsType(t) != t
+}
+
+// uint64FromFloat converts a JSON number to uint64, saturating instead of
+// overflowing. rentEpoch, for one, is u64::MAX on rent-exempt accounts, which
+// float64 rounds up to 2^64.
+func uint64FromFloat(value float64) uint64 {
+	switch {
+	case math.IsNaN(value) || value <= 0:
+		return 0
+	case value >= math.MaxUint64:
+		return math.MaxUint64
+	default:
+		return uint64(value)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWithBigIntStrings(t *testing.T) {
+	rentEpoch := BigUint(18446744073709551615)
+	timestamp := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		value      interface{}
+		wantNumber string
+		wantString string
+	}{
+		{"bare", BigUint(9007199254740993), `9007199254740993`, `"9007199254740993"`},
+		{
+			"struct fields",
+			&AccountInfo{Address: "A", Lamports: 9007199254740993, RentEpoch: &rentEpoch, rpcResults: map[string]interface{}{"getAccountInfo": nil}},
+			`{"address":"A","balance":0,"executable":false,"owner":"","lamports":9007199254740993,"dataLength":0,"isValid":false,"rentEpoch":18446744073709551615,"rentExempt":false}`,
+			`{"address":"A","balance":0,"executable":false,"owner":"","lamports":"9007199254740993","dataLength":0,"isValid":false,"rentEpoch":"18446744073709551615","rentExempt":false}`,
+		},
+		{
+			"omitempty kept",
+			SlotInfo{Slot: 5, Commitment: "confirmed"},
+			`{"slot":5,"commitment":"confirmed"}`,
+			`{"slot":"5","commitment":"confirmed"}`,
+		},
+		{
+			"nested in gin.H and slices",
+			gin.H{"accounts": []*AccountInfo{nil, {Address: "B", Lamports: 1}}, "slot": BigUint(7), "count": 2},
+			`{"accounts":[null,{"address":"B","balance":0,"executable":false,"owner":"","lamports":1,"dataLength":0,"isValid":false,"rentExempt":false}],"count":2,"slot":7}`,
+			`{"accounts":[null,{"address":"B","balance":0,"executable":false,"owner":"","lamports":"1","dataLength":0,"isValid":false,"rentExempt":false}],"count":2,"slot":"7"}`,
+		},
+		{
+			"own encodings untouched",
+			gin.H{"at": timestamp, "raw": json.RawMessage(`{"slot":1}`), "bytes": []byte{1}},
+			`{"at":"2025-01-02T03:04:05Z","bytes":"AQ==","raw":{"slot":1}}`,
+			`{"at":"2025-01-02T03:04:05Z","bytes":"AQ==","raw":{"slot":1}}`,
+		},
+		{"nil", nil, `null`, `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			number, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if string(number) != tt.wantNumber {
+				t.Errorf("version 1:\n got %s\nwant %s", number, tt.wantNumber)
+			}
+			str, err := json.Marshal(withBigIntStrings(tt.value))
+			if err != nil {
+				t.Fatalf("marshal with strings: %v", err)
+			}
+			if string(str) != tt.wantString {
+				t.Errorf("version 2:\n got %s\nwant %s", str, tt.wantString)
+			}
+		})
+	}
+}
+
+func TestRespondJSONVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		defaultVersion int
+		acceptVersion  string
+		wantStatus     int
+		wantVersion    string
+		wantSlot       string
+	}{
+		{"default", stableAPIVersion, "", http.StatusOK, "1", `5`},
+		{"pinned to 2", stableAPIVersion, "2", http.StatusOK, "2", `"5"`},
+		{"strings by default", bigIntStringsVersion, "", http.StatusOK, "2", `"5"`},
+		{"strings by default, pinned to 1", bigIntStringsVersion, "v1", http.StatusOK, "1", `5`},
+		{"unsupported", stableAPIVersion, "3", http.StatusBadRequest, "1", ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(apiVersionMiddleware(tt.defaultVersion))
+			r.GET("/api/slot", func(c *gin.Context) {
+				respondJSON(c, http.StatusOK, gin.H{"slot": BigUint(5)})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/slot", nil)
+			if tt.acceptVersion != "" {
+				req.Header.Set(acceptVersionHeader, tt.acceptVersion)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d (%s), want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if got := w.Header().Get(apiVersionHeader); got != tt.wantVersion {
+				t.Errorf("%s = %q, want %q", apiVersionHeader, got, tt.wantVersion)
+			}
+			if tt.wantSlot == "" {
+				return
+			}
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding %s: %v", w.Body, err)
+			}
+			if string(body["slot"]) != tt.wantSlot {
+				t.Errorf("slot = %s, want %s", body["slot"], tt.wantSlot)
+			}
+		})
+	}
+}
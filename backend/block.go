@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BlockInfo struct {
+	Slot              BigUint  `json:"slot"`
+	Blockhash         string   `json:"blockhash"`
+	PreviousBlockhash string   `json:"previousBlockhash"`
+	ParentSlot        BigUint  `json:"parentSlot"`
+	BlockTime         *int64   `json:"blockTime"`
+	BlockHeight       BigUint  `json:"blockHeight"`
+	TransactionCount  int      `json:"transactionCount"`
+	Signatures        []string `json:"signatures,omitempty"`
+}
+
+// GetBlock fetches a block with its transaction signatures. Passing
+// maxSupportedTransactionVersion matters even without full transaction
+// details: the node rejects any block containing a v0 transaction otherwise.
+func (s *SolanaRPCClient) GetBlock(ctx context.Context, slot uint64, maxVersion int) (*BlockInfo, error) {
+	cacheKey := s.cacheKey("block", slot, maxVersion)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if block, ok := cached.(*BlockInfo); ok {
+			return block, nil
+		}
+	}
+
+	params := []interface{}{
+		slot,
+		map[string]interface{}{
+			"transactionDetails":             "signatures",
+			"rewards":                        false,
+			"maxSupportedTransactionVersion": maxVersion,
+		},
+	}
+	resp, err := s.makeRPCCallWithRetry(ctx, "getBlock", params)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, ok := resp.Result.(map[string]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getBlock", Detail: "result is not an object"}
+	}
+
+	blockhash, _ := result["blockhash"].(string)
+	previousBlockhash, _ := result["previousBlockhash"].(string)
+	parentSlot, _ := result["parentSlot"].(float64)
+	blockHeight, _ := result["blockHeight"].(float64)
+
+	block := &BlockInfo{
+		Slot:              BigUint(slot),
+		Blockhash:         blockhash,
+		PreviousBlockhash: previousBlockhash,
+		ParentSlot:        BigUint(parentSlot),
+		BlockHeight:       BigUint(blockHeight),
+		Signatures:        []string{},
+	}
+	if blockTime, ok := result["blockTime"].(float64); ok {
+		t := int64(blockTime)
+		block.BlockTime = &t
+	}
+	if signatures, ok := result["signatures"].([]interface{}); ok {
+		for _, signature := range signatures {
+			if sig, ok := signature.(string); ok {
+				block.Signatures = append(block.Signatures, sig)
+			}
+		}
+	}
+	block.TransactionCount = len(block.Signatures)
+
+	s.setCache(ctx, cacheKey, block, 10*time.Minute)
+
+	return block, nil
+}
+
+func handleGetBlock(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slot, err := strconv.ParseUint(c.Param("slot"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Slot must be a non-negative integer"})
+			return
+		}
+
+		maxVersion, err := parseMaxTransactionVersion(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "maxSupportedTransactionVersion must be a non-negative integer"})
+			return
+		}
+
+		block, err := client.GetBlock(c.Request.Context(), slot, maxVersion)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get block", "details": err.Error()})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, block)
+	}
+}
+
+const (
+	defaultRecentBlocks = 10
+	maxRecentBlocks     = 50
+	// recentBlockConcurrency bounds the getBlock calls run at once for one
+	// recent blocks request.
+	recentBlockConcurrency = 4
+)
+
+// BlockSummary is the part of a block shown in a recent blocks feed.
+type BlockSummary struct {
+	Slot             BigUint `json:"slot"`
+	Blockhash        string  `json:"blockhash"`
+	BlockTime        *int64  `json:"blockTime"`
+	BlockHeight      BigUint `json:"blockHeight"`
+	TransactionCount int     `json:"transactionCount"`
+	Leader           string  `json:"leader,omitempty"`
+}
+
+// GetRecentBlocks summarizes the produced blocks among the last count slots,
+// newest first; skipped slots are left out, so fewer than count blocks may
+// be returned. getBlock with transactionDetails "none" carries no
+// transaction count, so the signatures are fetched and only counted, and the
+// leaders come from a single getSlotLeaders call. Summaries of finalized
+// blocks never change and are cached for an hour each.
+func (s *SolanaRPCClient) GetRecentBlocks(ctx context.Context, count int) ([]BlockSummary, error) {
+	currentSlot, err := s.GetSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	startSlot := uint64(0)
+	if currentSlot >= uint64(count) {
+		startSlot = currentSlot - uint64(count) + 1
+	}
+
+	resp, err := s.makeRPCCallWithRetry(ctx, "getBlocks", []interface{}{startSlot, currentSlot})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+	entries, ok := resp.Result.([]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getBlocks", Detail: "result is not an array"}
+	}
+
+	slots := make([]uint64, 0, len(entries))
+	for _, entry := range entries {
+		if slot, ok := parseUint64Result(entry); ok {
+			slots = append(slots, slot)
+		}
+	}
+	if len(slots) > count {
+		slots = slots[len(slots)-count:]
+	}
+
+	summaries := make([]BlockSummary, len(slots))
+	missing := []int{}
+	for i, slot := range slots {
+		if cached, found := s.getFromCache(ctx, s.cacheKey("block_summary", slot)); found {
+			if summary, ok := cached.(BlockSummary); ok {
+				summaries[i] = summary
+				continue
+			}
+		}
+		missing = append(missing, i)
+	}
+
+	if len(missing) > 0 {
+		leaders, err := s.getSlotLeaders(ctx, slots[missing[0]], slots[missing[len(missing)-1]])
+		if err != nil {
+			return nil, err
+		}
+
+		var wg sync.WaitGroup
+		var errMutex sync.Mutex
+		var firstErr error
+		sem := make(chan struct{}, recentBlockConcurrency)
+		for _, i := range missing {
+			wg.Add(1)
+			sem <- struct{}{}
+			// Each worker only writes its own summary.
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				summary, err := s.getBlockSummary(ctx, slots[i])
+				if err != nil {
+					errMutex.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					errMutex.Unlock()
+					return
+				}
+				summary.Leader = leaders[slots[i]]
+				summaries[i] = summary
+				s.setImmutableCache(ctx, s.cacheKey("block_summary", slots[i]), summary, time.Hour)
+			}(i)
+		}
+		wg.Wait()
+
+		if firstErr != nil {
+			return nil, firstErr
+		}
+	}
+
+	for i, j := 0, len(summaries)-1; i < j; i, j = i+1, j-1 {
+		summaries[i], summaries[j] = summaries[j], summaries[i]
+	}
+
+	return summaries, nil
+}
+
+func (s *SolanaRPCClient) getBlockSummary(ctx context.Context, slot uint64) (BlockSummary, error) {
+	params := []interface{}{
+		slot,
+		map[string]interface{}{
+			"transactionDetails":             "signatures",
+			"rewards":                        false,
+			"maxSupportedTransactionVersion": 0,
+		},
+	}
+	resp, err := s.makeRPCCallWithRetry(ctx, "getBlock", params)
+	if err != nil {
+		return BlockSummary{}, err
+	}
+
+	if resp.Error != nil {
+		return BlockSummary{}, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, ok := resp.Result.(map[string]interface{})
+	if !ok {
+		return BlockSummary{}, &ParseError{Method: "getBlock", Detail: "result is not an object"}
+	}
+
+	blockhash, _ := result["blockhash"].(string)
+	blockHeight, _ := result["blockHeight"].(float64)
+	signatures, _ := result["signatures"].([]interface{})
+
+	summary := BlockSummary{
+		Slot:             BigUint(slot),
+		Blockhash:        blockhash,
+		BlockHeight:      BigUint(blockHeight),
+		TransactionCount: len(signatures),
+	}
+	if blockTime, ok := result["blockTime"].(float64); ok {
+		t := int64(blockTime)
+		summary.BlockTime = &t
+	}
+
+	return summary, nil
+}
+
+// getSlotLeaders maps each slot from first to last to its leader's identity.
+func (s *SolanaRPCClient) getSlotLeaders(ctx context.Context, first, last uint64) (map[uint64]string, error) {
+	resp, err := s.makeRPCCallWithRetry(ctx, "getSlotLeaders", []interface{}{first, last - first + 1})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	entries, ok := resp.Result.([]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getSlotLeaders", Detail: "result is not an array"}
+	}
+
+	leaders := make(map[uint64]string, len(entries))
+	for i, entry := range entries {
+		if leader, ok := entry.(string); ok {
+			leaders[first+uint64(i)] = leader
+		}
+	}
+
+	return leaders, nil
+}
+
+func handleRecentBlocks(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(defaultRecentBlocks)))
+		if err != nil || count < 1 || count > maxRecentBlocks {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("count must be between 1 and %d", maxRecentBlocks)})
+			return
+		}
+
+		blocks, err := client.GetRecentBlocks(c.Request.Context(), count)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get recent blocks", "details": err.Error()})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, gin.H{"blocks": blocks, "count": len(blocks)})
+	}
+}
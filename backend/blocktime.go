@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// blockTimeSlots is how many slots back blockTimeFromTimestamps compares
+// block timestamps across, about a minute on mainnet.
+const blockTimeSlots = 150
+
+// GetBlockTime returns the estimated production time of the block at slot,
+// as a Unix timestamp. ok is false when the node has no time for it, e.g.
+// for a skipped slot or one it has already purged.
+func (s *SolanaRPCClient) GetBlockTime(ctx context.Context, slot uint64) (timestamp int64, ok bool, err error) {
+	resp, err := s.makeRPCCallWithRetry(ctx, "getBlockTime", []interface{}{slot})
+	if err != nil {
+		return 0, false, err
+	}
+	if resp.Error != nil {
+		return 0, false, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+	if resp.Result == nil {
+		return 0, false, nil
+	}
+
+	value, ok := parseUint64Result(resp.Result)
+	if !ok {
+		return 0, false, &ParseError{Method: "getBlockTime", Detail: "result is not a number"}
+	}
+	return int64(value), true, nil
+}
+
+// blockTimeFromTimestamps measures seconds per slot from the timestamps of
+// the current block and the one blockTimeSlots before it. ok is false when
+// either has no timestamp, or the timestamps do not move forward, and the
+// caller should measure another way.
+func (s *SolanaRPCClient) blockTimeFromTimestamps(ctx context.Context) (blockTime float64, ok bool, err error) {
+	slot, err := s.GetSlot(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	if slot < blockTimeSlots {
+		return 0, false, nil
+	}
+
+	latest, ok, err := s.GetBlockTime(ctx, slot)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	earlier, ok, err := s.GetBlockTime(ctx, slot-blockTimeSlots)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	if latest <= earlier {
+		return 0, false, nil
+	}
+
+	return float64(latest-earlier) / blockTimeSlots, true, nil
+}
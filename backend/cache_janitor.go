@@ -0,0 +1,45 @@
+package main
+
+import "time"
+
+// cacheJanitorInterval is how often expired cache entries are swept.
+const cacheJanitorInterval = time.Minute
+
+// startCacheJanitor deletes expired cache entries every
+// cacheJanitorInterval until Close is called. Lookups already ignore expired
+// entries, but without the sweep the keys that are never read again would
+// stay in the map for good.
+func (s *SolanaRPCClient) startCacheJanitor() {
+	ticker := time.NewTicker(cacheJanitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.evictExpired()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// evictExpired deletes every cache entry past its expiry.
+func (s *SolanaRPCClient) evictExpired() {
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for key, entry := range s.cache {
+		if now.After(entry.ExpiresAt) {
+			s.deleteCacheEntry(key)
+		}
+	}
+}
+
+// Close stops the client's cache janitor. It is safe to call more than once.
+func (s *SolanaRPCClient) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEvictExpired(t *testing.T) {
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		wantKept  bool
+		immutable bool
+	}{
+		{"expired", -time.Second, false, false},
+		{"expired immutable", -time.Second, false, true},
+		{"live", time.Hour, true, false},
+		{"live immutable", time.Hour, true, true},
+	}
+
+	_, client := newTestRPCNode(t, func(string, []interface{}) interface{} { return nil })
+	for _, tt := range tests {
+		key := client.cacheKey("test", tt.name)
+		client.storeCache(context.Background(), key, tt.name, tt.ttl, tt.immutable)
+	}
+
+	client.evictExpired()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := client.cacheKey("test", tt.name)
+			client.mutex.Lock()
+			_, inMap := client.cache[key]
+			_, inLRU := client.cacheLRU.elements[key]
+			client.mutex.Unlock()
+			if inMap != tt.wantKept || inLRU != tt.wantKept {
+				t.Errorf("in cache %v, in LRU %v; want %v", inMap, inLRU, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestCloseStopsCacheJanitor(t *testing.T) {
+	_, client := newTestRPCNode(t, func(string, []interface{}) interface{} { return nil })
+
+	stopped := make(chan struct{})
+	go func() {
+		client.startCacheJanitor()
+		close(stopped)
+	}()
+
+	client.Close()
+	client.Close()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("startCacheJanitor still running after Close")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"container/list"
+	"strings"
+)
+
+// defaultCacheMaxEntries caps the number of cache entries, on top of their
+// TTLs. Override with CACHE_MAX_ENTRIES; 0 removes the cap.
+const defaultCacheMaxEntries = 1000
+
+// cacheLRU orders cache keys from most to least recently used. The entries
+// themselves stay in SolanaRPCClient.cache; every method must be called with
+// the client's mutex held for writing.
+type cacheLRU struct {
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+func newCacheLRU() *cacheLRU {
+	return &cacheLRU{order: list.New(), elements: make(map[string]*list.Element)}
+}
+
+func (l *cacheLRU) touch(key string) {
+	if element, ok := l.elements[key]; ok {
+		l.order.MoveToFront(element)
+		return
+	}
+	l.elements[key] = l.order.PushFront(key)
+}
+
+func (l *cacheLRU) remove(key string) {
+	if element, ok := l.elements[key]; ok {
+		l.order.Remove(element)
+		delete(l.elements, key)
+	}
+}
+
+// putCacheEntry stores entry under key as the most recently used and evicts
+// the least recently used entries beyond cacheMaxEntries. Callers must hold
+// the mutex for writing.
+func (s *SolanaRPCClient) putCacheEntry(key string, entry CacheEntry) {
+	s.cache[key] = entry
+	s.cacheLRU.touch(key)
+	s.evictOverCapacity()
+}
+
+// deleteCacheEntry drops key from the cache. Callers must hold the mutex for
+// writing.
+func (s *SolanaRPCClient) deleteCacheEntry(key string) {
+	delete(s.cache, key)
+	s.cacheLRU.remove(key)
+}
+
+// evictOverCapacity drops least recently used entries until the cache fits
+// in cacheMaxEntries. Pinned entries are kept warm on purpose and
+// idempotency keys guard against double sends, so neither is evicted; they
+// are moved to the front instead, and when nothing else is left the cache
+// may stay over the cap. Callers must hold the mutex for writing.
+func (s *SolanaRPCClient) evictOverCapacity() {
+	if s.cacheMaxEntries <= 0 {
+		return
+	}
+	for skipped := 0; len(s.cache) > s.cacheMaxEntries && skipped < len(s.cache); {
+		oldest := s.cacheLRU.order.Back()
+		key := oldest.Value.(string)
+		if s.cache[key].Pinned || cacheKeyKind(key) == "idempotency" {
+			s.cacheLRU.order.MoveToFront(oldest)
+			skipped++
+			continue
+		}
+		s.deleteCacheEntry(key)
+		s.cacheEvictions++
+	}
+}
+
+// cacheKeyKind returns the kind part of a key built by cacheKey.
+func cacheKeyKind(key string) string {
+	parts := strings.SplitN(key, "|", 4)
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[2]
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheLRUEviction(t *testing.T) {
+	tests := []struct {
+		name    string
+		max     int
+		set     []string
+		get     []string
+		then    []string
+		want    []string
+		evicted []string
+	}{
+		{
+			name:    "oldest first",
+			max:     2,
+			set:     []string{"a", "b"},
+			then:    []string{"c"},
+			want:    []string{"b", "c"},
+			evicted: []string{"a"},
+		},
+		{
+			name:    "hit bumps recency",
+			max:     2,
+			set:     []string{"a", "b"},
+			get:     []string{"a"},
+			then:    []string{"c"},
+			want:    []string{"a", "c"},
+			evicted: []string{"b"},
+		},
+		{
+			name:    "rewrite bumps recency",
+			max:     3,
+			set:     []string{"a", "b", "c"},
+			then:    []string{"a", "d", "e"},
+			want:    []string{"a", "d", "e"},
+			evicted: []string{"b", "c"},
+		},
+		{
+			name: "no cap",
+			max:  0,
+			set:  []string{"a", "b", "c"},
+			then: []string{"d"},
+			want: []string{"a", "b", "c", "d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, client := newTestRPCNode(t, func(string, []interface{}) interface{} { return nil })
+			client.cacheMaxEntries = tt.max
+
+			for _, key := range tt.set {
+				client.setCache(context.Background(), client.cacheKey("test", key), key, time.Hour)
+			}
+			for _, key := range tt.get {
+				if _, found := client.getFromCache(context.Background(), client.cacheKey("test", key)); !found {
+					t.Fatalf("%s missing before eviction", key)
+				}
+			}
+			for _, key := range tt.then {
+				client.setCache(context.Background(), client.cacheKey("test", key), key, time.Hour)
+			}
+
+			for _, key := range tt.want {
+				if _, found := client.getFromCache(context.Background(), client.cacheKey("test", key)); !found {
+					t.Errorf("%s was evicted", key)
+				}
+			}
+			for _, key := range tt.evicted {
+				if _, found := client.getFromCache(context.Background(), client.cacheKey("test", key)); found {
+					t.Errorf("%s was kept", key)
+				}
+			}
+			client.mutex.Lock()
+			entries := len(client.cache)
+			client.mutex.Unlock()
+			if tt.max > 0 && entries > tt.max {
+				t.Errorf("cache holds %d entries, want at most %d", entries, tt.max)
+			}
+		})
+	}
+}
+
+func TestCacheLRUKeepsTTL(t *testing.T) {
+	_, client := newTestRPCNode(t, func(string, []interface{}) interface{} { return nil })
+	client.cacheMaxEntries = 2
+
+	expired, live := client.cacheKey("test", "expired"), client.cacheKey("test", "live")
+	client.setCache(context.Background(), expired, "expired", -time.Second)
+	client.setCache(context.Background(), live, "live", time.Hour)
+
+	if _, found := client.getFromCache(context.Background(), expired); found {
+		t.Error("expired entry served while under the cap")
+	}
+	if _, found := client.getFromCache(context.Background(), live); !found {
+		t.Error("live entry missing")
+	}
+
+	// The expired entry is still the least recently used, so it is the one
+	// the cap drops.
+	client.setCache(context.Background(), client.cacheKey("test", "new"), "new", time.Hour)
+	client.mutex.Lock()
+	_, kept := client.cache[expired]
+	client.mutex.Unlock()
+	if kept {
+		t.Error("expired entry kept over the cap")
+	}
+	if _, found := client.getFromCache(context.Background(), live); !found {
+		t.Error("live entry evicted before the expired one")
+	}
+}
+
+func TestCacheLRUKeepsPinnedAndIdempotencyKeys(t *testing.T) {
+	_, client := newTestRPCNode(t, func(string, []interface{}) interface{} { return nil })
+	client.cacheMaxEntries = 2
+
+	idempotency := client.cacheKey("idempotency", "key")
+	client.setCache(context.Background(), idempotency, "sent", time.Hour)
+	client.setCache(context.Background(), client.cacheKey("test", "a"), "a", time.Hour)
+	client.setCache(context.Background(), client.cacheKey("test", "b"), "b", time.Hour)
+
+	if _, found := client.getFromCache(context.Background(), idempotency); !found {
+		t.Error("idempotency key evicted")
+	}
+	if _, found := client.getFromCache(context.Background(), client.cacheKey("test", "a")); found {
+		t.Error("least recently used entry kept")
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// knownClusters maps genesis hashes to the public cluster names.
+var knownClusters = map[string]string{
+	"5eykt4UsFv8P8NJdTREpY1vzqKqZKvdpKuc147dw2N9d": "mainnet-beta",
+	"EtWTRABZaYq6iMfeYKouRu166VU2xqa1wcaWoxPkrZBG": "devnet",
+	"4uhcVJyU9pJkvQyS88uRDiswHXSCkY3zQawwpjk2NsNY": "testnet",
+}
+
+// Capabilities describes what this deployment has enabled so a frontend can
+// adapt to it. It is built from the loaded configuration and must never
+// carry secrets such as the admin key or RPC URLs, which often embed API
+// keys.
+type Capabilities struct {
+	Network      string               `json:"network"`
+	Commitment   string               `json:"commitment"`
+	RPCEndpoints int                  `json:"rpcEndpoints"`
+	Features     CapabilityFeatures   `json:"features"`
+	Limits       CapabilityLimits     `json:"limits"`
+	RateLimits   CapabilityRateLimits `json:"rateLimits"`
+	CacheTTLs    CapabilityCacheTTLs  `json:"cacheTtlSeconds"`
+
+	// APIVersion is the response shape served without Accept-Version.
+	APIVersion           int   `json:"apiVersion"`
+	SupportedAPIVersions []int `json:"supportedApiVersions"`
+}
+
+type CapabilityFeatures struct {
+	AdminEndpoints   bool     `json:"adminEndpoints"`
+	RawResults       bool     `json:"rawResults"`
+	MetricsWebSocket bool     `json:"metricsWebSocket"`
+	HeavyEndpoint    bool     `json:"heavyEndpoint"`
+	LBStrategy       string   `json:"lbStrategy"`
+	RPCDiscovery     bool     `json:"rpcDiscovery"`
+	SOLPriceSource   string   `json:"solPriceSource,omitempty"`
+	TokenPriceSource string   `json:"tokenPriceSource,omitempty"`
+	DomainResolution bool     `json:"domainResolution"`
+	Webhooks         bool     `json:"webhooks"`
+	FlaggedAddresses bool     `json:"flaggedAddresses"`
+	AnchorPrograms   []string `json:"anchorPrograms"`
+	BigIntsAsStrings bool     `json:"bigIntsAsStrings"`
+	StrictRPCResults bool     `json:"strictRpcResults"`
+	ZstdAccountData  bool     `json:"zstdAccountData"`
+	RentEpochMode    string   `json:"rentEpochSentinel"`
+	TPSCrossCheck    bool     `json:"tpsCrossCheck"`
+	ConsensusMetrics bool     `json:"consensusMetrics"`
+	PinnedMints      int      `json:"pinnedMints"`
+	PinnedAccounts   int      `json:"pinnedAccounts"`
+}
+
+type CapabilityLimits struct {
+	RequestTimeoutSeconds    float64 `json:"requestTimeoutSeconds"`
+	RPCTimeoutSeconds        float64 `json:"rpcTimeoutSeconds"`
+	MaxAccountDataBytes      int     `json:"maxAccountDataBytes"`
+	MaxWatches               int     `json:"maxWatches"`
+	WatchPollIntervalSeconds float64 `json:"watchPollIntervalSeconds"`
+	MetricsTPSSamples        int     `json:"metricsTpsSamples"`
+	MetricsWaitSeconds       float64 `json:"metricsWaitSeconds"`
+	PortfolioDustUSD         float64 `json:"portfolioDustUsd"`
+	CacheMaxEntries          int     `json:"cacheMaxEntries"`
+	SlotCacheBlocks          float64 `json:"slotCacheBlocks"`
+	MaxProgramAccounts       int     `json:"maxProgramAccounts"`
+}
+
+// CapabilityRateLimits describes the adaptive limiter's bounds on the spacing
+// of calls to each RPC method once it has been throttled.
+type CapabilityRateLimits struct {
+	MinCallIntervalMs    int64   `json:"minCallIntervalMs"`
+	MaxCallIntervalMs    int64   `json:"maxCallIntervalMs"`
+	MaxTotalWaitSeconds  float64 `json:"maxTotalWaitSeconds"`
+	MinRetryAfterSeconds float64 `json:"minRetryAfterSeconds"`
+	// GlobalRate and GlobalBurst describe the RPC_RATE token bucket; 0 is
+	// no global cap, set with RPC_RATE=0.
+	GlobalRate  float64 `json:"globalRate"`
+	GlobalBurst int     `json:"globalBurst"`
+}
+
+type CapabilityCacheTTLs struct {
+	Price        float64 `json:"price"`
+	Pinned       float64 `json:"pinned"`
+	MaxStaleness float64 `json:"maxStaleness"`
+}
+
+// GetGenesisHash returns the cluster's genesis hash, which never changes.
+func (s *SolanaRPCClient) GetGenesisHash(ctx context.Context) (string, error) {
+	cacheKey := s.cacheKey("genesis_hash")
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if hash, ok := cached.(string); ok {
+			return hash, nil
+		}
+	}
+
+	resp, err := s.makeRPCCallWithRetry(ctx, "getGenesisHash", []interface{}{})
+	if err != nil {
+		return "", err
+	}
+
+	if resp.Error != nil {
+		return "", fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	hash, ok := resp.Result.(string)
+	if !ok {
+		return "", &ParseError{Method: "getGenesisHash", Detail: "result is not a string"}
+	}
+
+	s.setImmutableCache(ctx, cacheKey, hash, 30*24*time.Hour)
+
+	return hash, nil
+}
+
+// clusterName identifies the network by its genesis hash: a public cluster
+// name, "custom" for any other cluster, or "unknown" when the node cannot be
+// reached.
+func (s *SolanaRPCClient) clusterName(ctx context.Context) string {
+	hash, err := s.GetGenesisHash(ctx)
+	if err != nil {
+		return "unknown"
+	}
+	if name, ok := knownClusters[hash]; ok {
+		return name
+	}
+	return "custom"
+}
+
+// newCapabilities fills in the client-level settings; the caller adds the
+// ones that only exist in main.
+func newCapabilities(client *SolanaRPCClient, idls map[string]*anchorIDL) Capabilities {
+	programs := make([]string, 0, len(idls))
+	for program := range idls {
+		programs = append(programs, program)
+	}
+	sort.Strings(programs)
+
+	var globalRate float64
+	var globalBurst int
+	if client.globalRateLimiter != nil {
+		globalRate = float64(client.globalRateLimiter.Limit())
+		globalBurst = client.globalRateLimiter.Burst()
+	}
+
+	return Capabilities{
+		Commitment:   client.commitment,
+		RPCEndpoints: len(client.endpoints),
+		Features: CapabilityFeatures{
+			MetricsWebSocket: true,
+			HeavyEndpoint:    client.heavyEndpoint != nil,
+			LBStrategy:       client.lbStrategy,
+			RPCDiscovery:     client.discovery != nil,
+			SOLPriceSource:   client.priceSource,
+			TokenPriceSource: client.tokenPriceSource,
+			DomainResolution: true,
+			Webhooks:         true,
+			AnchorPrograms:   programs,
+			StrictRPCResults: strictRPCResults,
+			ZstdAccountData:  client.zstdAccountData,
+			RentEpochMode:    rentEpochSentinel,
+			TPSCrossCheck:    client.tpsCrossCheck,
+			ConsensusMetrics: client.consensusMetrics,
+			PinnedMints:      len(client.pinnedMints),
+			PinnedAccounts:   len(client.pinnedAccounts),
+		},
+		Limits: CapabilityLimits{
+			MaxAccountDataBytes: client.maxAccountData,
+			RPCTimeoutSeconds:   client.httpClient.Timeout.Seconds(),
+			MetricsTPSSamples:   client.metricsSampleCount,
+			MetricsWaitSeconds:  client.metricsWait.Seconds(),
+			PortfolioDustUSD:    client.portfolioDustUSD,
+			CacheMaxEntries:     client.cacheMaxEntries,
+			SlotCacheBlocks:     client.slotCacheBlocks,
+			MaxProgramAccounts:  client.maxProgramAccounts,
+		},
+		RateLimits: CapabilityRateLimits{
+			MinCallIntervalMs:    minCallInterval.Milliseconds(),
+			MaxCallIntervalMs:    maxCallInterval.Milliseconds(),
+			MaxTotalWaitSeconds:  client.maxTotalWait.Seconds(),
+			MinRetryAfterSeconds: client.minRetryAfter.Seconds(),
+			GlobalRate:           globalRate,
+			GlobalBurst:          globalBurst,
+		},
+		CacheTTLs: CapabilityCacheTTLs{
+			Price:        client.priceCacheTTL.Seconds(),
+			Pinned:       pinnedCacheTTL.Seconds(),
+			MaxStaleness: client.maxCacheStaleness.Seconds(),
+		},
+
+		APIVersion:           stableAPIVersion,
+		SupportedAPIVersions: supportedAPIVersions,
+	}
+}
+
+func handleCapabilities(client *SolanaRPCClient, capabilities Capabilities) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response := capabilities
+		response.Network = client.clusterName(c.Request.Context())
+
+		respondJSON(c, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+)
+
+// defaultConsensusSlotTolerance is how many slots an endpoint may trail the
+// highest reported slot and still agree, about ten seconds of blocks, so
+// ordinary propagation delay is not flagged. Override with
+// CONSENSUS_SLOT_TOLERANCE.
+const defaultConsensusSlotTolerance = 25
+
+// ConsensusEndpoint is one endpoint's answer in a consensus round. Slot is
+// omitted when getSlot failed, and Error says why.
+type ConsensusEndpoint struct {
+	Index   int     `json:"index"`
+	URL     string  `json:"url"`
+	Slot    BigUint `json:"slot,omitempty"`
+	SlotLag uint64  `json:"slotLag,omitempty"`
+	Healthy bool    `json:"healthy"`
+	Agrees  bool    `json:"agrees"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// MetricsConsensus reconciles the slot and health reported by every
+// configured endpoint: Slot is the highest slot reported and Healthy the
+// majority getHealth answer. Disagreement is set when any endpoint failed,
+// trailed Slot by more than the tolerance, or went against the majority.
+type MetricsConsensus struct {
+	Slot          BigUint             `json:"slot"`
+	Healthy       bool                `json:"healthy"`
+	Disagreement  bool                `json:"disagreement"`
+	SlotTolerance uint64              `json:"slotTolerance"`
+	Endpoints     []ConsensusEndpoint `json:"endpoints"`
+}
+
+// consensusEndpoints are the endpoints polled for consensus: the primary,
+// the backups and the heavy endpoint, with their allEndpoints indexes.
+// Discovered nodes are left out, since a consensus is only as trustworthy
+// as its voters.
+func (s *SolanaRPCClient) consensusEndpoints() ([]*rpcEndpoint, []int) {
+	var endpoints []*rpcEndpoint
+	var indexes []int
+	for i, endpoint := range s.allEndpoints() {
+		if s.isDiscovered(endpoint) {
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+		indexes = append(indexes, i)
+	}
+	return endpoints, indexes
+}
+
+// queryConsensusEndpoint asks one endpoint for its slot and health. The
+// calls are pinned to the endpoint like a ?rpc= override, so they share the
+// rate limits, concurrency caps and failure tracking of live traffic.
+func (s *SolanaRPCClient) queryConsensusEndpoint(ctx context.Context, endpoint *rpcEndpoint) ConsensusEndpoint {
+	ctx, _ = withEndpointOverride(ctx, endpoint)
+	ctx, cancel := context.WithTimeout(ctx, endpointCheckTimeout)
+	defer cancel()
+
+	answer := ConsensusEndpoint{URL: redactURL(endpoint.url)}
+
+	resp, err := s.makeRPCCall(ctx, "getSlot", []interface{}{})
+	switch {
+	case err != nil:
+		answer.Error = redactError(endpoint.url, err)
+	case resp.Error != nil:
+		answer.Error = fmt.Sprintf("RPC error: %v", resp.Error)
+	default:
+		if slot, ok := parseUint64Result(resp.Result); ok {
+			answer.Slot = BigUint(slot)
+		} else {
+			answer.Error = (&ParseError{Method: "getSlot", Detail: "result is not a number"}).Error()
+		}
+	}
+
+	// An unhealthy node answers getHealth with an error rather than a
+	// status, so anything but "ok" is unhealthy.
+	resp, err = s.makeRPCCall(ctx, "getHealth", []interface{}{})
+	if err == nil && resp.Error == nil {
+		status, _ := resp.Result.(string)
+		answer.Healthy = status == "ok"
+	}
+
+	return answer
+}
+
+// MetricsConsensus polls every consensus endpoint concurrently and
+// reconciles their answers. It returns nil when no endpoint reported a slot,
+// leaving the caller to its usual fallbacks.
+func (s *SolanaRPCClient) MetricsConsensus(ctx context.Context) *MetricsConsensus {
+	endpoints, indexes := s.consensusEndpoints()
+
+	answers := make([]ConsensusEndpoint, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint *rpcEndpoint) {
+			defer wg.Done()
+			answers[i] = s.queryConsensusEndpoint(ctx, endpoint)
+			answers[i].Index = indexes[i]
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	consensus := &MetricsConsensus{SlotTolerance: s.consensusSlotTolerance, Endpoints: answers}
+	reported, healthy := 0, 0
+	for _, answer := range answers {
+		if answer.Error == "" {
+			reported++
+			if answer.Slot > consensus.Slot {
+				consensus.Slot = answer.Slot
+			}
+		}
+		if answer.Healthy {
+			healthy++
+		}
+	}
+	if reported == 0 {
+		return nil
+	}
+	consensus.Healthy = healthy*2 > len(answers)
+
+	var disagreeing []string
+	for i := range answers {
+		answer := &answers[i]
+		if answer.Error == "" {
+			answer.SlotLag = uint64(consensus.Slot - answer.Slot)
+		}
+		answer.Agrees = answer.Error == "" && answer.SlotLag <= s.consensusSlotTolerance && answer.Healthy == consensus.Healthy
+		if !answer.Agrees {
+			consensus.Disagreement = true
+			disagreeing = append(disagreeing, strconv.Itoa(answer.Index)+" "+answer.URL)
+		}
+	}
+	// Metrics are polled every few seconds, so only changes are logged.
+	s.mutex.Lock()
+	changed := consensus.Disagreement != s.consensusDisagreement
+	s.consensusDisagreement = consensus.Disagreement
+	s.mutex.Unlock()
+	if changed && consensus.Disagreement {
+		log.Printf("RPC endpoints disagree on metrics (slot %d, healthy %t): %v", consensus.Slot, consensus.Healthy, disagreeing)
+	} else if changed {
+		log.Printf("RPC endpoints agree on metrics again (slot %d)", consensus.Slot)
+	}
+
+	return consensus
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultDiscoveryInterval is how often AUTO_DISCOVER_RPC refreshes the
+	// discovered pool. Override with RPC_DISCOVERY_INTERVAL.
+	defaultDiscoveryInterval = 10 * time.Minute
+	// defaultDiscoveryMaxPool caps the discovered pool. Override with
+	// RPC_DISCOVERY_MAX.
+	defaultDiscoveryMaxPool = 5
+
+	discoveryProbeTimeout     = 3 * time.Second
+	discoveryProbeConcurrency = 8
+	// discoveryMaxProbes bounds the getHealth probes of one run, since most
+	// advertised RPC ports are firewalled or unhealthy.
+	discoveryMaxProbes = 64
+)
+
+// rpcDiscovery fills a pool of extra endpoints from the nodes getClusterNodes
+// reports with an RPC address, keeping the ones that answer getHealth with
+// "ok". The primary is the seed. Each run rebuilds the pool, so nodes that
+// stop answering or advertising drop out, while nodes that stay keep their
+// failure history.
+type rpcDiscovery struct {
+	client       *SolanaRPCClient
+	maxPool      int
+	interval     time.Duration
+	allowPrivate bool
+	httpClient   *http.Client
+
+	// runMutex keeps runs from the background loop and the admin endpoint
+	// from overlapping.
+	runMutex sync.Mutex
+
+	mutex      sync.Mutex
+	candidates int
+}
+
+func newRPCDiscovery(client *SolanaRPCClient, maxPool int, interval time.Duration, allowPrivate bool) *rpcDiscovery {
+	return &rpcDiscovery{
+		client:       client,
+		maxPool:      maxPool,
+		interval:     interval,
+		allowPrivate: allowPrivate,
+		httpClient:   &http.Client{Timeout: discoveryProbeTimeout},
+	}
+}
+
+func (d *rpcDiscovery) run() {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.discover(context.Background()); err != nil {
+			log.Printf("RPC discovery failed, keeping the current pool: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// discover rebuilds the discovered pool and returns it. A failed
+// getClusterNodes call leaves the pool as it was.
+func (d *rpcDiscovery) discover(ctx context.Context) ([]*rpcEndpoint, error) {
+	d.runMutex.Lock()
+	defer d.runMutex.Unlock()
+
+	candidates, err := d.advertisedNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.mutex.Lock()
+	d.candidates = len(candidates)
+	d.mutex.Unlock()
+
+	healthy := d.probe(ctx, candidates)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := d.client
+	s.discoveredMutex.Lock()
+	existing := make(map[string]*rpcEndpoint, len(s.discovered))
+	for _, endpoint := range s.discovered {
+		existing[endpoint.url] = endpoint
+	}
+	pool := make([]*rpcEndpoint, 0, len(healthy))
+	for _, endpointURL := range healthy {
+		endpoint, ok := existing[endpointURL]
+		if !ok {
+			endpoint = newRPCEndpoint(endpointURL)
+		}
+		pool = append(pool, endpoint)
+	}
+	s.discovered = pool
+	s.discoveredMutex.Unlock()
+
+	log.Printf("RPC discovery: %d of %d advertised RPC nodes in the pool", len(pool), len(candidates))
+	return pool, nil
+}
+
+// advertisedNodes lists the RPC URLs advertised by getClusterNodes, skipping the
+// configured endpoints and, unless allowPrivate is set, non-public addresses.
+// Nodes already in the pool come first so that they keep their place; the
+// rest are shuffled to spread the load across runs.
+func (d *rpcDiscovery) advertisedNodes(ctx context.Context) ([]string, error) {
+	s := d.client
+	resp, err := s.makeRPCCallWithRetry(ctx, "getClusterNodes", []interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	nodes, ok := resp.Result.([]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getClusterNodes", Detail: "result is not an array"}
+	}
+
+	skip := map[string]bool{}
+	for _, endpoint := range s.endpoints {
+		skip[endpoint.url] = true
+	}
+	if s.heavyEndpoint != nil {
+		skip[s.heavyEndpoint.url] = true
+	}
+
+	advertised := map[string]bool{}
+	var fresh []string
+	for _, node := range nodes {
+		info, _ := node.(map[string]interface{})
+		address, _ := info["rpc"].(string)
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			continue
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			continue
+		}
+		if !d.allowPrivate && (ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()) {
+			continue
+		}
+		endpointURL := "http://" + address
+		if skip[endpointURL] || advertised[endpointURL] {
+			continue
+		}
+		advertised[endpointURL] = true
+		fresh = append(fresh, endpointURL)
+	}
+
+	var ordered []string
+	s.discoveredMutex.RLock()
+	for _, endpoint := range s.discovered {
+		if advertised[endpoint.url] {
+			ordered = append(ordered, endpoint.url)
+			delete(advertised, endpoint.url)
+		}
+	}
+	s.discoveredMutex.RUnlock()
+
+	rand.Shuffle(len(fresh), func(i, j int) { fresh[i], fresh[j] = fresh[j], fresh[i] })
+	for _, endpointURL := range fresh {
+		if advertised[endpointURL] {
+			ordered = append(ordered, endpointURL)
+		}
+	}
+	return ordered, nil
+}
+
+// probe checks candidates with getHealth, discoveryProbeConcurrency at a
+// time, and returns the healthy ones in candidate order until maxPool are
+// found or discoveryMaxProbes have been sent.
+func (d *rpcDiscovery) probe(ctx context.Context, candidates []string) []string {
+	if len(candidates) > discoveryMaxProbes {
+		candidates = candidates[:discoveryMaxProbes]
+	}
+
+	var healthy []string
+	for start := 0; start < len(candidates) && len(healthy) < d.maxPool; start += discoveryProbeConcurrency {
+		end := start + discoveryProbeConcurrency
+		if end > len(candidates) {
+			end = len(candidates)
+		}
+
+		ok := make([]bool, end-start)
+		var wg sync.WaitGroup
+		for i, endpointURL := range candidates[start:end] {
+			wg.Add(1)
+			go func(i int, endpointURL string) {
+				defer wg.Done()
+				resp, err := postRPC(ctx, d.httpClient, endpointURL, "getHealth", []interface{}{})
+				ok[i] = err == nil && resp.Error == nil && resp.Result == "ok"
+			}(i, endpointURL)
+		}
+		wg.Wait()
+
+		for i, endpointURL := range candidates[start:end] {
+			if ok[i] && len(healthy) < d.maxPool {
+				healthy = append(healthy, endpointURL)
+			}
+		}
+	}
+	return healthy
+}
+
+func (s *SolanaRPCClient) isDiscovered(endpoint *rpcEndpoint) bool {
+	s.discoveredMutex.RLock()
+	defer s.discoveredMutex.RUnlock()
+
+	for _, discovered := range s.discovered {
+		if discovered == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+func handleDiscoverRPC(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		discovery := client.discovery
+		if discovery == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "RPC discovery is disabled; set AUTO_DISCOVER_RPC=true"})
+			return
+		}
+
+		pool, err := discovery.discover(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to discover RPC nodes", "details": err.Error()})
+			return
+		}
+
+		urls := make([]string, len(pool))
+		for i, endpoint := range pool {
+			urls[i] = redactURL(endpoint.url)
+		}
+
+		discovery.mutex.Lock()
+		candidates := discovery.candidates
+		discovery.mutex.Unlock()
+
+		respondJSON(c, http.StatusOK, gin.H{"endpoints": urls, "candidates": candidates, "maxPool": discovery.maxPool, "timestamp": time.Now()})
+	}
+}
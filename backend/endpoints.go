@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// endpointCheckTimeout bounds each probe made by the endpoint health check.
+const endpointCheckTimeout = 3 * time.Second
+
+// defaultHeavyMethods are the scans sent to SOLANA_RPC_URL_HEAVY when it is
+// set, keeping them from contending with the latency-sensitive calls on the
+// primary. Override with RPC_HEAVY_METHODS.
+var defaultHeavyMethods = []string{"getProgramAccounts", "getLargestAccounts", "getTokenLargestAccounts"}
+
+type rpcEndpoint struct {
+	url string
+	// slots caps the calls in flight to the endpoint at its capacity, the
+	// endpoint's RPC_MAX_CONCURRENCY. It is nil when there is no cap.
+	slots chan struct{}
+
+	mutex               sync.Mutex
+	lastError           string
+	lastFailure         time.Time
+	consecutiveFailures int
+	inFlight            int
+	queued              int
+	// latencyEWMA averages successful calls for LB_STRATEGY=least-latency;
+	// see observeLatency.
+	latencyEWMA time.Duration
+}
+
+func newRPCEndpoint(url string) *rpcEndpoint {
+	return &rpcEndpoint{url: url}
+}
+
+// record tracks the outcome of a call: transport errors and 5xx responses
+// count as failures, anything else resets the failure streak.
+func (e *rpcEndpoint) record(err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if err != nil {
+		e.lastError = redactError(e.url, err)
+		e.lastFailure = time.Now()
+		e.consecutiveFailures++
+		return
+	}
+	e.consecutiveFailures = 0
+}
+
+// redactError describes a call to endpointURL that failed with err. net/http
+// errors quote the request URL, which may carry an API key, so it is
+// redacted.
+func redactError(endpointURL string, err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Sprintf("%s %s: %v", urlErr.Op, redactURL(endpointURL), urlErr.Err)
+	}
+	return err.Error()
+}
+
+// acquire waits for a free slot when the endpoint's concurrency is capped,
+// giving up when ctx is done. Every successful acquire must be followed by
+// release.
+func (e *rpcEndpoint) acquire(ctx context.Context) error {
+	if e.slots != nil {
+		e.mutex.Lock()
+		e.queued++
+		e.mutex.Unlock()
+
+		var err error
+		select {
+		case e.slots <- struct{}{}:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+
+		e.mutex.Lock()
+		e.queued--
+		e.mutex.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	e.mutex.Lock()
+	e.inFlight++
+	e.mutex.Unlock()
+	return nil
+}
+
+func (e *rpcEndpoint) release() {
+	e.mutex.Lock()
+	e.inFlight--
+	e.mutex.Unlock()
+
+	if e.slots != nil {
+		<-e.slots
+	}
+}
+
+func (e *rpcEndpoint) load() (inFlight, queued int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.inFlight, e.queued
+}
+
+// setEndpointConcurrency caps each endpoint's calls in flight, in
+// allEndpoints order. A single limit applies to every endpoint; 0 leaves an
+// endpoint uncapped. It must be called before the server starts.
+func (s *SolanaRPCClient) setEndpointConcurrency(limits []int) {
+	endpoints := s.allEndpoints()
+	for i, endpoint := range endpoints {
+		limit := limits[0]
+		if len(limits) > 1 {
+			if i >= len(limits) {
+				break
+			}
+			limit = limits[i]
+		}
+		if limit > 0 {
+			endpoint.slots = make(chan struct{}, limit)
+		}
+	}
+}
+
+func (e *rpcEndpoint) state() (string, int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.lastError, e.consecutiveFailures
+}
+
+// redactURL keeps only the scheme and host of an RPC URL. Providers embed
+// API keys in the user info, the path or the query, so all three are masked.
+func redactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return "***"
+	}
+	redacted := parsed.Scheme + "://" + parsed.Host
+	if parsed.Path != "" && parsed.Path != "/" {
+		redacted += "/***"
+	}
+	if parsed.RawQuery != "" {
+		redacted += "?***"
+	}
+	return redacted
+}
+
+// rpcEndpointHeader names the endpoint that answered a request pinned with
+// ?rpc=.
+const rpcEndpointHeader = "X-RPC-Endpoint"
+
+type endpointOverrideKey struct{}
+
+// endpointOverride pins a request's RPC calls to one endpoint. withFailover
+// sets answered once the endpoint returned a response, which may happen in
+// several of the request's calls at once.
+type endpointOverride struct {
+	endpoint *rpcEndpoint
+	answered atomic.Bool
+}
+
+// withEndpointOverride returns ctx with its RPC calls pinned to endpoint.
+func withEndpointOverride(ctx context.Context, endpoint *rpcEndpoint) (context.Context, *endpointOverride) {
+	override := &endpointOverride{endpoint: endpoint}
+	return context.WithValue(ctx, endpointOverrideKey{}, override), override
+}
+
+// endpointOverrideFrom returns the endpoint override of ctx, if any.
+func endpointOverrideFrom(ctx context.Context) (*endpointOverride, bool) {
+	override, ok := ctx.Value(endpointOverrideKey{}).(*endpointOverride)
+	return override, ok
+}
+
+// endpointOverrideWriter sets the X-RPC-Endpoint header just before the
+// response headers are sent, and only if the pinned endpoint answered one of
+// the request's calls.
+type endpointOverrideWriter struct {
+	gin.ResponseWriter
+	override *endpointOverride
+	label    string
+	sent     bool
+}
+
+func (w *endpointOverrideWriter) setHeader() {
+	if w.sent || w.ResponseWriter.Written() {
+		return
+	}
+	w.sent = true
+	if w.override.answered.Load() {
+		w.Header().Set(rpcEndpointHeader, w.label)
+	}
+}
+
+func (w *endpointOverrideWriter) WriteHeaderNow() {
+	w.setHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *endpointOverrideWriter) Write(data []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *endpointOverrideWriter) WriteString(data string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(data)
+}
+
+type EndpointHealth struct {
+	Index               int     `json:"index"`
+	URL                 string  `json:"url"`
+	Primary             bool    `json:"primary"`
+	Heavy               bool    `json:"heavy,omitempty"`
+	Discovered          bool    `json:"discovered,omitempty"`
+	Healthy             bool    `json:"healthy"`
+	Slot                BigUint `json:"slot,omitempty"`
+	Version             string  `json:"version,omitempty"`
+	LatencyMs           int64   `json:"latencyMs"`
+	LastError           string  `json:"lastError,omitempty"`
+	ConsecutiveFailures int     `json:"consecutiveFailures"`
+	// CoolingDown is set while failover skips the endpoint after repeated
+	// failures; see endpointsFor.
+	CoolingDown bool `json:"coolingDown,omitempty"`
+	// AverageLatencyMs is the moving average of the endpoint's successful
+	// calls, which LB_STRATEGY=least-latency orders endpoints by.
+	AverageLatencyMs float64 `json:"averageLatencyMs,omitempty"`
+
+	// InFlight and Queued count the calls being sent to the endpoint and
+	// those waiting for one of its MaxConcurrency slots; 0 is no cap.
+	InFlight       int `json:"inFlight"`
+	Queued         int `json:"queued"`
+	MaxConcurrency int `json:"maxConcurrency"`
+}
+
+// checkEndpoint probes an endpoint with getSlot and getVersion. The outcome
+// is recorded like any other call, so the failure streak reflects both
+// probes and live traffic. Probes skip the concurrency cap, so a saturated
+// endpoint still reports its latency rather than its queue.
+func checkEndpoint(ctx context.Context, httpClient *http.Client, endpoint *rpcEndpoint) EndpointHealth {
+	health := EndpointHealth{URL: redactURL(endpoint.url)}
+
+	start := time.Now()
+	err := func() error {
+		resp, err := postRPC(ctx, httpClient, endpoint.url, "getSlot", []interface{}{})
+		if err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return fmt.Errorf("RPC error: %v", resp.Error)
+		}
+		slot, ok := parseUint64Result(resp.Result)
+		if !ok {
+			return &ParseError{Method: "getSlot", Detail: "result is not a number"}
+		}
+		health.Slot = BigUint(slot)
+
+		resp, err = postRPC(ctx, httpClient, endpoint.url, "getVersion", []interface{}{})
+		if err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return fmt.Errorf("RPC error: %v", resp.Error)
+		}
+		if result, ok := resp.Result.(map[string]interface{}); ok {
+			health.Version, _ = result["solana-core"].(string)
+		}
+		return nil
+	}()
+	health.LatencyMs = time.Since(start).Milliseconds()
+
+	endpoint.record(err)
+	health.Healthy = err == nil
+	health.LastError, health.ConsecutiveFailures = endpoint.state()
+	health.InFlight, health.Queued = endpoint.load()
+	health.MaxConcurrency = cap(endpoint.slots)
+	return health
+}
+
+// allEndpoints lists every endpoint: the primary, the backups, the heavy
+// endpoint if any, and last the nodes found by AUTO_DISCOVER_RPC, which come
+// and go. Positions in it are what ?rpc= selects.
+func (s *SolanaRPCClient) allEndpoints() []*rpcEndpoint {
+	endpoints := s.endpoints
+	if s.heavyEndpoint != nil {
+		endpoints = append(endpoints[:len(endpoints):len(endpoints)], s.heavyEndpoint)
+	}
+	s.discoveredMutex.RLock()
+	if len(s.discovered) > 0 {
+		endpoints = append(endpoints[:len(endpoints):len(endpoints)], s.discovered...)
+	}
+	s.discoveredMutex.RUnlock()
+	return endpoints
+}
+
+// CheckEndpoints probes every configured endpoint concurrently, in
+// allEndpoints order.
+func (s *SolanaRPCClient) CheckEndpoints(ctx context.Context) []EndpointHealth {
+	httpClient := &http.Client{Timeout: endpointCheckTimeout}
+
+	endpoints := s.allEndpoints()
+
+	results := make([]EndpointHealth, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint *rpcEndpoint) {
+			defer wg.Done()
+			results[i] = checkEndpoint(ctx, httpClient, endpoint)
+			results[i].Index = i
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	results[0].Primary = true
+	for i, endpoint := range endpoints {
+		results[i].Heavy = endpoint == s.heavyEndpoint
+		results[i].Discovered = s.isDiscovered(endpoint)
+		results[i].CoolingDown = endpoint.coolingDown(s.failoverThreshold, s.failoverCooldown)
+		results[i].AverageLatencyMs = float64(endpoint.averageLatency().Microseconds()) / 1000
+	}
+	return results
+}
+
+func handleEndpointHealth(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		endpoints := client.CheckEndpoints(c.Request.Context())
+
+		healthy := 0
+		for _, endpoint := range endpoints {
+			if endpoint.Healthy {
+				healthy++
+			}
+		}
+
+		status, code := "ok", http.StatusOK
+		switch {
+		case healthy == 0:
+			status, code = "down", http.StatusServiceUnavailable
+		case healthy < len(endpoints):
+			status = "degraded"
+		}
+
+		respondJSON(c, code, gin.H{"status": status, "endpoints": endpoints, "timestamp": time.Now()})
+	}
+}
+
+// endpointOverrideMiddleware lets operators pin a request's RPC calls to one
+// configured endpoint with ?rpc=<index>, the index reported by
+// /api/health/endpoints, to compare providers on the same query. Only
+// configured endpoints can be chosen, never a URL, and the override needs the
+// admin key, so it is unavailable without ADMIN_API_KEY. Pinned requests
+// bypass the cache and the shared fetches, so every answer comes from the
+// chosen endpoint, and X-RPC-Endpoint is only set when it did answer.
+func endpointOverrideMiddleware(client *SolanaRPCClient, adminKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := c.Query("rpc")
+		if raw == "" {
+			c.Next()
+			return
+		}
+
+		if adminKey == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "RPC endpoint override requires ADMIN_API_KEY"})
+			return
+		}
+		if !validAdminKey(c, adminKey) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin key"})
+			return
+		}
+
+		endpoints := client.allEndpoints()
+		index, err := strconv.Atoi(raw)
+		if err != nil || index < 0 || index >= len(endpoints) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("rpc must be an endpoint index from 0 to %d", len(endpoints)-1)})
+			return
+		}
+
+		endpoint := endpoints[index]
+		ctx, override := withEndpointOverride(c.Request.Context(), endpoint)
+		c.Request = c.Request.WithContext(ctx)
+		writer := &endpointOverrideWriter{ResponseWriter: c.Writer, override: override, label: strconv.Itoa(index) + " " + redactURL(endpoint.url)}
+		c.Writer = writer
+		c.Next()
+		writer.setHeader()
+		c.Writer = writer.ResponseWriter
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEndpointOverride(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	slotNode := func(slot float64) *testRPCNode {
+		return &testRPCNode{respond: func(method string, _ []interface{}) interface{} {
+			if method == "getSlot" {
+				return slot
+			}
+			return nil
+		}}
+	}
+	primary, backup := slotNode(100), slotNode(200)
+	primaryServer := httptest.NewServer(http.HandlerFunc(primary.serveHTTP))
+	t.Cleanup(primaryServer.Close)
+	backupServer := httptest.NewServer(http.HandlerFunc(backup.serveHTTP))
+	t.Cleanup(backupServer.Close)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	t.Cleanup(failing.Close)
+
+	client := NewSolanaClient(primaryServer.URL, backupServer.URL, failing.URL)
+	t.Cleanup(client.Close)
+
+	const adminKey = "secret"
+	r := gin.New()
+	r.Use(endpointOverrideMiddleware(client, adminKey))
+	r.GET("/api/slot", handleSlot(client))
+	r.POST("/api/transaction/send", func(c *gin.Context) {
+		if _, err := client.SendTransaction(c.Request.Context(), "AQAB", "base64", false); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send transaction"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantSlot   uint64
+		wantHeader string
+	}{
+		{"warms the cache", http.MethodGet, "/api/slot", http.StatusOK, 100, ""},
+		{"pinned request skips the cache", http.MethodGet, "/api/slot?rpc=1", http.StatusOK, 200, "1 " + redactURL(backupServer.URL)},
+		{"pinned answer is not cached", http.MethodGet, "/api/slot", http.StatusOK, 100, ""},
+		{"no header when the endpoint failed", http.MethodPost, "/api/transaction/send?rpc=2", http.StatusBadGateway, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("X-Admin-Key", adminKey)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d (%s), want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if got := w.Header().Get(rpcEndpointHeader); got != tt.wantHeader {
+				t.Errorf("%s = %q, want %q", rpcEndpointHeader, got, tt.wantHeader)
+			}
+			if tt.wantSlot == 0 {
+				return
+			}
+			var info SlotInfo
+			if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+				t.Fatalf("decoding %s: %v", w.Body, err)
+			}
+			if uint64(info.Slot) != tt.wantSlot {
+				t.Errorf("slot = %d, want %d", info.Slot, tt.wantSlot)
+			}
+		})
+	}
+
+	t.Run("pinned metrics are fetched from the endpoint", func(t *testing.T) {
+		ctx, _ := withEndpointOverride(context.Background(), client.allEndpoints()[1])
+		before := len(backup.callsTo("getSlot"))
+		client.sharedMetrics(ctx, 0)
+		if len(backup.callsTo("getSlot")) == before {
+			t.Error("the pinned endpoint received no getSlot call")
+		}
+		if _, found := client.getFromCache(context.Background(), client.cacheKey("last_good", "slot")); found {
+			t.Error("pinned metrics were stored as the last good values")
+		}
+	})
+}
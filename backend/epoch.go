@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// minimumSlotsPerEpoch is the length of epoch 0 when warmup is enabled.
+const minimumSlotsPerEpoch = 32
+
+// epochScheduleRetryInterval spaces startup attempts to load the schedule.
+const epochScheduleRetryInterval = 30 * time.Second
+
+var errFutureEpoch = errors.New("epoch has not started")
+
+type EpochSchedule struct {
+	SlotsPerEpoch            uint64 `json:"slotsPerEpoch"`
+	LeaderScheduleSlotOffset uint64 `json:"leaderScheduleSlotOffset"`
+	Warmup                   bool   `json:"warmup"`
+	FirstNormalEpoch         uint64 `json:"firstNormalEpoch"`
+	FirstNormalSlot          uint64 `json:"firstNormalSlot"`
+}
+
+// SlotsInEpoch mirrors EpochSchedule::get_slots_in_epoch: warmup epochs
+// double in length from minimumSlotsPerEpoch until FirstNormalEpoch.
+func (e EpochSchedule) SlotsInEpoch(epoch uint64) uint64 {
+	if e.Warmup && epoch < e.FirstNormalEpoch {
+		return minimumSlotsPerEpoch << epoch
+	}
+	return e.SlotsPerEpoch
+}
+
+func (e EpochSchedule) FirstSlotInEpoch(epoch uint64) uint64 {
+	if e.Warmup && epoch <= e.FirstNormalEpoch {
+		return ((uint64(1) << epoch) - 1) * minimumSlotsPerEpoch
+	}
+	return (epoch-e.FirstNormalEpoch)*e.SlotsPerEpoch + e.FirstNormalSlot
+}
+
+type BlockProductionSummary struct {
+	LeaderSlots    uint64  `json:"leaderSlots"`
+	BlocksProduced uint64  `json:"blocksProduced"`
+	SkippedSlots   uint64  `json:"skippedSlots"`
+	SkipRate       float64 `json:"skipRate"`
+	Validators     int     `json:"validators"`
+}
+
+type EpochDetails struct {
+	Epoch           uint64                  `json:"epoch"`
+	FirstSlot       BigUint                 `json:"firstSlot"`
+	LastSlot        BigUint                 `json:"lastSlot"`
+	SlotsInEpoch    uint64                  `json:"slotsInEpoch"`
+	Current         bool                    `json:"current"`
+	DataRetained    bool                    `json:"dataRetained"`
+	BlockProduction *BlockProductionSummary `json:"blockProduction,omitempty"`
+	Note            string                  `json:"note,omitempty"`
+}
+
+// EpochSchedule returns the cluster's epoch schedule without making an RPC
+// call. It is loaded once at startup, or by the first GetEpochSchedule call
+// if that failed; until then the zero value, with SlotsPerEpoch 0, is
+// returned.
+func (s *SolanaRPCClient) EpochSchedule() EpochSchedule {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.epochSchedule == nil {
+		return EpochSchedule{}
+	}
+	return *s.epochSchedule
+}
+
+// GetEpochSchedule returns the epoch schedule, fetching it only if it has
+// not been loaded yet. The schedule is fixed at genesis, so once fetched it
+// is kept on the client for good.
+func (s *SolanaRPCClient) GetEpochSchedule(ctx context.Context) (*EpochSchedule, error) {
+	if schedule := s.EpochSchedule(); schedule.SlotsPerEpoch > 0 {
+		return &schedule, nil
+	}
+
+	resp, err := s.makeRPCCallWithRetry(ctx, "getEpochSchedule", []interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, ok := resp.Result.(map[string]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getEpochSchedule", Detail: "result is not an object"}
+	}
+
+	slotsPerEpoch, ok := result["slotsPerEpoch"].(float64)
+	if !ok || slotsPerEpoch <= 0 {
+		return nil, &ParseError{Method: "getEpochSchedule", Detail: "slotsPerEpoch is not a positive number"}
+	}
+	leaderScheduleSlotOffset, _ := result["leaderScheduleSlotOffset"].(float64)
+	warmup, _ := result["warmup"].(bool)
+	firstNormalEpoch, _ := result["firstNormalEpoch"].(float64)
+	firstNormalSlot, _ := result["firstNormalSlot"].(float64)
+
+	schedule := &EpochSchedule{
+		SlotsPerEpoch:            uint64(slotsPerEpoch),
+		LeaderScheduleSlotOffset: uint64(leaderScheduleSlotOffset),
+		Warmup:                   warmup,
+		FirstNormalEpoch:         uint64(firstNormalEpoch),
+		FirstNormalSlot:          uint64(firstNormalSlot),
+	}
+
+	s.mutex.Lock()
+	s.epochSchedule = schedule
+	s.mutex.Unlock()
+
+	copied := *schedule
+	return &copied, nil
+}
+
+// loadEpochSchedule fetches the epoch schedule at startup, retrying until
+// it succeeds so features built on it rarely have to fetch it on demand.
+func (s *SolanaRPCClient) loadEpochSchedule() {
+	for {
+		_, err := s.GetEpochSchedule(context.Background())
+		if err == nil {
+			return
+		}
+		log.Printf("Failed to load the epoch schedule, retrying in %v: %v", epochScheduleRetryInterval, err)
+		time.Sleep(epochScheduleRetryInterval)
+	}
+}
+
+// GetBlockProduction summarizes getBlockProduction over a slot range.
+func (s *SolanaRPCClient) GetBlockProduction(ctx context.Context, firstSlot, lastSlot uint64) (*BlockProductionSummary, error) {
+	params := []interface{}{
+		map[string]interface{}{
+			"range": map[string]interface{}{"firstSlot": firstSlot, "lastSlot": lastSlot},
+		},
+	}
+	resp, err := s.makeRPCCallWithRetry(ctx, "getBlockProduction", params)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, _, err := unwrapContext("getBlockProduction", resp.Result)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getBlockProduction", Detail: "value is not an object"}
+	}
+	byIdentity, ok := value["byIdentity"].(map[string]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getBlockProduction", Detail: "byIdentity is not an object"}
+	}
+
+	summary := &BlockProductionSummary{Validators: len(byIdentity)}
+	for _, production := range byIdentity {
+		counts, ok := production.([]interface{})
+		if !ok || len(counts) < 2 {
+			continue
+		}
+		leaderSlots, _ := counts[0].(float64)
+		blocksProduced, _ := counts[1].(float64)
+		summary.LeaderSlots += uint64(leaderSlots)
+		summary.BlocksProduced += uint64(blocksProduced)
+	}
+
+	if summary.LeaderSlots > summary.BlocksProduced {
+		summary.SkippedSlots = summary.LeaderSlots - summary.BlocksProduced
+	}
+	summary.SkipRate = safeDivide(float64(summary.SkippedSlots), float64(summary.LeaderSlots)) * 100
+
+	return summary, nil
+}
+
+// GetEpochDetails returns the slot range of an epoch and, when the node
+// still has it, the block production for that epoch. Completed epochs are
+// immutable and cached for a long time.
+func (s *SolanaRPCClient) GetEpochDetails(ctx context.Context, epoch uint64) (*EpochDetails, error) {
+	cacheKey := s.cacheKey("epoch_details", epoch)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if details, ok := cached.(*EpochDetails); ok {
+			return details, nil
+		}
+	}
+
+	epochInfo, err := s.GetEpochInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	currentEpoch, ok := epochInfo["epoch"].(float64)
+	if !ok {
+		return nil, &ParseError{Method: "getEpochInfo", Detail: "epoch is not a number"}
+	}
+	if epoch > uint64(currentEpoch) {
+		return nil, errFutureEpoch
+	}
+
+	schedule, err := s.GetEpochSchedule(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	firstSlot := schedule.FirstSlotInEpoch(epoch)
+	slotsInEpoch := schedule.SlotsInEpoch(epoch)
+	details := &EpochDetails{
+		Epoch:        epoch,
+		FirstSlot:    BigUint(firstSlot),
+		LastSlot:     BigUint(firstSlot + slotsInEpoch - 1),
+		SlotsInEpoch: slotsInEpoch,
+		Current:      epoch == uint64(currentEpoch),
+	}
+
+	productionLastSlot := firstSlot + slotsInEpoch - 1
+	if details.Current {
+		if absoluteSlot, ok := epochInfo["absoluteSlot"].(float64); ok && uint64(absoluteSlot) < productionLastSlot {
+			productionLastSlot = uint64(absoluteSlot)
+		}
+	}
+
+	production, err := s.GetBlockProduction(ctx, firstSlot, productionLastSlot)
+	if err != nil {
+		details.Note = "Block production for this epoch is not retained by the RPC node"
+	} else {
+		details.DataRetained = true
+		details.BlockProduction = production
+	}
+
+	switch {
+	case details.Current:
+		s.setCache(ctx, cacheKey, details, 1*time.Minute)
+	case !details.DataRetained:
+		s.setCache(ctx, cacheKey, details, 1*time.Hour)
+	default:
+		s.setImmutableCache(ctx, cacheKey, details, 30*24*time.Hour)
+	}
+
+	return details, nil
+}
+
+func handleEpochDetails(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		epoch, err := strconv.ParseUint(c.Param("number"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Epoch must be a non-negative integer"})
+			return
+		}
+
+		details, err := client.GetEpochDetails(c.Request.Context(), epoch)
+		if errors.Is(err, errFutureEpoch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Epoch has not started yet"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get epoch details"})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, details)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	// defaultFailoverThreshold is how many failures in a row take an
+	// endpoint out of the failover order. Override with
+	// RPC_FAILOVER_THRESHOLD.
+	defaultFailoverThreshold = 3
+	// defaultFailoverCooldown is how long such an endpoint is skipped before
+	// a call is let through to see whether it recovered. Override with
+	// RPC_FAILOVER_COOLDOWN.
+	defaultFailoverCooldown = 30 * time.Second
+)
+
+// coolingDown reports whether the endpoint failed threshold times in a row
+// less than cooldown ago.
+func (e *rpcEndpoint) coolingDown(threshold int, cooldown time.Duration) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.consecutiveFailures >= threshold && time.Since(e.lastFailure) < cooldown
+}
+
+// endpointsFor lists the endpoints to try for method, in order. A request
+// pinned with ?rpc= only ever uses its endpoint. Otherwise the heavy
+// endpoint leads for the heavy methods, followed by the primary and the
+// backups in LB_STRATEGY order and then the discovered nodes, leaving out
+// those cooling down after repeated failures. When every endpoint is
+// cooling down, all are tried rather than none. Methods whose retry policy
+// is not Retryable, such as sendTransaction, get only the first endpoint:
+// the one that failed may still have accepted the call, so trying the next
+// would resend it.
+func (s *SolanaRPCClient) endpointsFor(ctx context.Context, method string) []*rpcEndpoint {
+	if override, ok := endpointOverrideFrom(ctx); ok {
+		return []*rpcEndpoint{override.endpoint}
+	}
+
+	var order []*rpcEndpoint
+	if s.heavyEndpoint != nil && s.heavyMethods[method] {
+		order = append(order, s.heavyEndpoint)
+	}
+	order = append(order, s.balanceEndpoints()...)
+	s.discoveredMutex.RLock()
+	order = append(order, s.discovered...)
+	s.discoveredMutex.RUnlock()
+
+	available := make([]*rpcEndpoint, 0, len(order))
+	for _, endpoint := range order {
+		if !endpoint.coolingDown(s.failoverThreshold, s.failoverCooldown) {
+			available = append(available, endpoint)
+		}
+	}
+	if len(available) == 0 {
+		available = order
+	}
+	if !s.retryPolicyFor(method).Retryable && len(available) > 1 {
+		available = available[:1]
+	}
+	return available
+}
+
+// withFailover runs call against the endpoints for method in turn until one
+// succeeds, moving on after a transport error or a 5xx response, which call
+// reports as a non-nil error. Each attempt holds one of the endpoint's
+// concurrency slots and its outcome is recorded, unless ctx was done by
+// then: a call abandoned by its caller says nothing about the endpoint, and
+// there is nobody left to fail over for. The last error is returned when
+// every endpoint failed.
+func (s *SolanaRPCClient) withFailover(ctx context.Context, method string, call func(endpoint *rpcEndpoint) error) error {
+	endpoints := s.endpointsFor(ctx, method)
+
+	var err error
+	for i, endpoint := range endpoints {
+		if err = endpoint.acquire(ctx); err != nil {
+			return err
+		}
+		started := time.Now()
+		err = call(endpoint)
+		endpoint.release()
+		if ctx.Err() != nil {
+			return err
+		}
+
+		endpoint.record(err)
+		if err == nil {
+			endpoint.observeLatency(time.Since(started))
+			if override, ok := endpointOverrideFrom(ctx); ok {
+				override.answered.Store(true)
+			}
+			return nil
+		}
+		if _, failures := endpoint.state(); failures == s.failoverThreshold {
+			log.Printf("RPC endpoint %s failed %d times in a row, skipping it for %s: %s", redactURL(endpoint.url), failures, s.failoverCooldown, redactError(endpoint.url, err))
+		}
+		if i+1 < len(endpoints) {
+			s.retryLog.debugf(ctx, "%s failed on %s, failing over to %s: %s", method, redactURL(endpoint.url), redactURL(endpoints[i+1].url), redactError(endpoint.url, err))
+		}
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWritesDoNotFailOver(t *testing.T) {
+	tests := []struct {
+		name         string
+		call         func(ctx context.Context, client *SolanaRPCClient) error
+		wantErr      bool
+		wantFailover bool
+	}{
+		{
+			name: "sendTransaction",
+			call: func(ctx context.Context, client *SolanaRPCClient) error {
+				_, err := client.SendTransaction(ctx, "AQAB", "base64", false)
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "getBalance",
+			call: func(ctx context.Context, client *SolanaRPCClient) error {
+				_, err := client.GetBalance(ctx, "Account1111111111111111111111111111111111111")
+				return err
+			},
+			wantFailover: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var primaryCalls atomic.Int32
+			primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var call testRPCCall
+				if json.NewDecoder(r.Body).Decode(&call) == nil && call.Method == tt.name {
+					primaryCalls.Add(1)
+				}
+				http.Error(w, "bad gateway", http.StatusBadGateway)
+			}))
+			t.Cleanup(primary.Close)
+
+			backup := &testRPCNode{respond: func(method string, _ []interface{}) interface{} {
+				switch method {
+				case "sendTransaction":
+					return "5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW"
+				case "getBalance":
+					return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": 1e9}
+				}
+				return nil
+			}}
+			backupServer := httptest.NewServer(http.HandlerFunc(backup.serveHTTP))
+			t.Cleanup(backupServer.Close)
+
+			client := NewSolanaClient(primary.URL, backupServer.URL)
+			t.Cleanup(client.Close)
+
+			err := tt.call(context.Background(), client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if calls := len(backup.callsTo(tt.name)); (calls > 0) != tt.wantFailover {
+				t.Errorf("backup received %d %s calls, want failover %v", calls, tt.name, tt.wantFailover)
+			}
+			if calls := primaryCalls.Load(); !tt.wantFailover && calls != 1 {
+				t.Errorf("primary received %d %s calls, want 1", calls, tt.name)
+			}
+		})
+	}
+}
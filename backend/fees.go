@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rpcMethodNotFound is the JSON-RPC code returned for methods a node does
+// not implement, e.g. getFeeRateGovernor on clusters that removed it.
+const rpcMethodNotFound = -32601
+
+// systemProgramID doubles as the fee payer of the probe message used to ask
+// the node for its per-signature fee.
+const systemProgramID = "11111111111111111111111111111111"
+
+var errFeesUnsupported = errors.New("fee methods not supported by RPC node")
+
+type FeeRateGovernor struct {
+	LamportsPerSignature       uint64 `json:"lamportsPerSignature"`
+	BurnPercent                *uint8 `json:"burnPercent"`
+	MinLamportsPerSignature    uint64 `json:"minLamportsPerSignature,omitempty"`
+	MaxLamportsPerSignature    uint64 `json:"maxLamportsPerSignature,omitempty"`
+	TargetLamportsPerSignature uint64 `json:"targetLamportsPerSignature,omitempty"`
+	TargetSignaturesPerSlot    uint64 `json:"targetSignaturesPerSlot,omitempty"`
+	Source                     string `json:"source"`
+}
+
+func isMethodNotFound(rpcErr interface{}) bool {
+	errorMap, ok := rpcErr.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	code, _ := errorMap["code"].(float64)
+	return code == rpcMethodNotFound
+}
+
+// GetFeeRateGovernor returns the network's base fee parameters. The
+// per-signature fee comes from getFeeForMessage, which every current node
+// supports; the burn percentage and governor bounds come from the deprecated
+// getFeeRateGovernor and are left empty when the node no longer serves it.
+func (s *SolanaRPCClient) GetFeeRateGovernor(ctx context.Context) (*FeeRateGovernor, error) {
+	cacheKey := s.cacheKey("fee_governor")
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if governor, ok := cached.(*FeeRateGovernor); ok {
+			return governor, nil
+		}
+	}
+
+	governor, err := s.getLegacyFeeRateGovernor(ctx)
+	if err != nil && !errors.Is(err, errFeesUnsupported) {
+		return nil, err
+	}
+
+	lamportsPerSignature, feeErr := s.getFeePerSignature(ctx)
+	switch {
+	case feeErr == nil:
+		if governor == nil {
+			governor = &FeeRateGovernor{Source: "getFeeForMessage"}
+		}
+		governor.LamportsPerSignature = lamportsPerSignature
+	case governor == nil:
+		return nil, feeErr
+	case !errors.Is(feeErr, errFeesUnsupported):
+		return nil, feeErr
+	}
+
+	s.setCache(ctx, cacheKey, governor, 1*time.Minute)
+
+	return governor, nil
+}
+
+func (s *SolanaRPCClient) getLegacyFeeRateGovernor(ctx context.Context) (*FeeRateGovernor, error) {
+	resp, err := s.makeRPCCallWithRetry(ctx, "getFeeRateGovernor", []interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		if isMethodNotFound(resp.Error) {
+			return nil, errFeesUnsupported
+		}
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, _, err := unwrapContext("getFeeRateGovernor", resp.Result)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getFeeRateGovernor", Detail: "value is not an object"}
+	}
+	params, ok := value["feeRateGovernor"].(map[string]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getFeeRateGovernor", Detail: "feeRateGovernor is not an object"}
+	}
+
+	burnPercent, ok := params["burnPercent"].(float64)
+	if !ok {
+		return nil, &ParseError{Method: "getFeeRateGovernor", Detail: "burnPercent is not a number"}
+	}
+	minLamports, _ := params["minLamportsPerSignature"].(float64)
+	maxLamports, _ := params["maxLamportsPerSignature"].(float64)
+	targetLamports, _ := params["targetLamportsPerSignature"].(float64)
+	targetSignatures, _ := params["targetSignaturesPerSlot"].(float64)
+
+	burn := uint8(burnPercent)
+	return &FeeRateGovernor{
+		// Overwritten by the node's actual fee when getFeeForMessage works.
+		LamportsPerSignature:       uint64(minLamports),
+		BurnPercent:                &burn,
+		MinLamportsPerSignature:    uint64(minLamports),
+		MaxLamportsPerSignature:    uint64(maxLamports),
+		TargetLamportsPerSignature: uint64(targetLamports),
+		TargetSignaturesPerSlot:    uint64(targetSignatures),
+		Source:                     "getFeeRateGovernor",
+	}, nil
+}
+
+// getFeePerSignature prices a message with a single signer and no
+// instructions, which costs exactly one signature's base fee.
+func (s *SolanaRPCClient) getFeePerSignature(ctx context.Context) (uint64, error) {
+	blockhash, err := s.getLatestBlockhash(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	payer, err := decodePublicKey(systemProgramID)
+	if err != nil {
+		return 0, err
+	}
+	recentBlockhash, err := decodePublicKey(blockhash)
+	if err != nil {
+		return 0, &ParseError{Method: "getLatestBlockhash", Detail: "blockhash is not a valid hash"}
+	}
+
+	// Legacy message: header (1 signer, 0 read-only), one account key, the
+	// recent blockhash and an empty instruction list.
+	message := []byte{1, 0, 0, 1}
+	message = append(message, payer...)
+	message = append(message, recentBlockhash...)
+	message = append(message, 0)
+
+	params := []interface{}{base64.StdEncoding.EncodeToString(message)}
+	resp, err := s.makeRPCCallWithRetry(ctx, "getFeeForMessage", params)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.Error != nil {
+		if isMethodNotFound(resp.Error) {
+			return 0, errFeesUnsupported
+		}
+		return 0, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, _, err := unwrapContext("getFeeForMessage", resp.Result)
+	if err != nil {
+		return 0, err
+	}
+	fee, ok := parseUint64Result(result)
+	if !ok {
+		return 0, &ParseError{Method: "getFeeForMessage", Detail: "value is not a number"}
+	}
+
+	return fee, nil
+}
+
+func (s *SolanaRPCClient) getLatestBlockhash(ctx context.Context) (string, error) {
+	resp, err := s.makeRPCCallWithRetry(ctx, "getLatestBlockhash", []interface{}{})
+	if err != nil {
+		return "", err
+	}
+
+	if resp.Error != nil {
+		if isMethodNotFound(resp.Error) {
+			return "", errFeesUnsupported
+		}
+		return "", fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, _, err := unwrapContext("getLatestBlockhash", resp.Result)
+	if err != nil {
+		return "", err
+	}
+	value, ok := result.(map[string]interface{})
+	if !ok {
+		return "", &ParseError{Method: "getLatestBlockhash", Detail: "value is not an object"}
+	}
+	blockhash, ok := value["blockhash"].(string)
+	if !ok {
+		return "", &ParseError{Method: "getLatestBlockhash", Detail: "blockhash is not a string"}
+	}
+
+	return blockhash, nil
+}
+
+func handleFeeGovernor(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		governor, err := client.GetFeeRateGovernor(c.Request.Context())
+		if errors.Is(err, errFeesUnsupported) {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "The RPC node does not support fee rate queries"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get fee rate governor"})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, governor)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultFlagReloadInterval = 10 * time.Minute
+	flagFetchTimeout          = 10 * time.Second
+	maxFlagListBytes          = 16 * 1024 * 1024
+)
+
+// flagEntry is one flagged address in a FLAGGED_ADDRESSES_SOURCE list, which
+// is a JSON array of them.
+type flagEntry struct {
+	Address string `json:"address"`
+	Reason  string `json:"reason"`
+}
+
+// flagList is a best-effort denylist of scam and otherwise flagged
+// addresses, loaded from a local file or an http(s) URL and reloaded
+// periodically. A failed reload keeps the previous list. A nil *flagList
+// flags nothing, so callers need not check whether the feature is enabled.
+type flagList struct {
+	source string
+
+	mutex     sync.RWMutex
+	reasons   map[string]string
+	loadedAt  time.Time
+	lastError string
+}
+
+func newFlagList(source string) *flagList {
+	return &flagList{source: source, reasons: map[string]string{}}
+}
+
+func (f *flagList) read() ([]byte, error) {
+	if !strings.HasPrefix(f.source, "http://") && !strings.HasPrefix(f.source, "https://") {
+		return os.ReadFile(f.source)
+	}
+
+	httpClient := &http.Client{Timeout: flagFetchTimeout}
+	resp, err := httpClient.Get(f.source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("flag list returned HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxFlagListBytes))
+}
+
+func (f *flagList) load() error {
+	data, err := f.read()
+	if err == nil {
+		var entries []flagEntry
+		if err = json.Unmarshal(data, &entries); err == nil {
+			reasons := make(map[string]string, len(entries))
+			for _, entry := range entries {
+				if entry.Address != "" {
+					reasons[entry.Address] = entry.Reason
+				}
+			}
+
+			f.mutex.Lock()
+			f.reasons = reasons
+			f.loadedAt = time.Now()
+			f.lastError = ""
+			f.mutex.Unlock()
+			return nil
+		}
+		err = fmt.Errorf("invalid flag list: %w", err)
+	}
+
+	f.mutex.Lock()
+	f.lastError = err.Error()
+	f.mutex.Unlock()
+	return err
+}
+
+func (f *flagList) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := f.load(); err != nil {
+			log.Printf("Failed to reload flagged addresses, keeping the previous list: %v", err)
+		}
+	}
+}
+
+// lookup returns the reason address is flagged, if it is.
+func (f *flagList) lookup(address string) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	reason, ok := f.reasons[address]
+	return reason, ok
+}
+
+type FlagStatus struct {
+	Address  string     `json:"address"`
+	Flagged  bool       `json:"flagged"`
+	Reason   string     `json:"reason,omitempty"`
+	LoadedAt *time.Time `json:"listLoadedAt,omitempty"`
+	// ListError is the last load failure; the previous list, if any, is
+	// still in use.
+	ListError string `json:"listError,omitempty"`
+}
+
+func handleFlagStatus(flags *flagList) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if flags == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No flagged address list is configured"})
+			return
+		}
+
+		address := c.Param("address")
+		if _, err := decodePublicKey(address); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+			return
+		}
+
+		status := FlagStatus{Address: address}
+		status.Reason, status.Flagged = flags.lookup(address)
+
+		flags.mutex.RLock()
+		if !flags.loadedAt.IsZero() {
+			loadedAt := flags.loadedAt
+			status.LoadedAt = &loadedAt
+		}
+		status.ListError = flags.lastError
+		flags.mutex.RUnlock()
+
+		respondJSON(c, http.StatusOK, status)
+	}
+}
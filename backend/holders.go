@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// largestAccountsLimit is the number of accounts getTokenLargestAccounts returns.
+const largestAccountsLimit = 20
+
+// defaultHolderLimit is the number of holders the holders endpoint returns
+// when no limit is given.
+const defaultHolderLimit = 10
+
+// firstN returns at most the first n holders. The result's capacity is
+// capped so appending to it cannot overwrite a cached slice.
+func firstN(holders []map[string]interface{}, n int) []map[string]interface{} {
+	if n < 0 {
+		n = 0
+	}
+	if n > len(holders) {
+		n = len(holders)
+	}
+	return holders[:n:n]
+}
+
+func parseCommaList(raw string) []string {
+	var addresses []string
+	for _, part := range strings.Split(raw, ",") {
+		if address := strings.TrimSpace(part); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// GetTokenHolderDistribution returns the largest holders of a mint with the
+// excluded addresses removed and each holder's percentage of the remaining
+// supply. Exclusions are matched against the token account addresses returned
+// by getTokenLargestAccounts, so they are best-effort: an excluded account
+// outside the top 20 cannot be subtracted from the supply. The distribution
+// is cached without the limit, which only truncates it.
+func (s *SolanaRPCClient) GetTokenHolderDistribution(ctx context.Context, mintAddress string, limit int, exclude []string) ([]map[string]interface{}, error) {
+	excluded := make(map[string]bool, len(exclude)+len(s.holderDenylist))
+	for _, address := range s.holderDenylist {
+		excluded[address] = true
+	}
+	for _, address := range exclude {
+		excluded[address] = true
+	}
+
+	exclusionKey := make([]string, 0, len(excluded))
+	for address := range excluded {
+		exclusionKey = append(exclusionKey, address)
+	}
+	sort.Strings(exclusionKey)
+
+	cacheKey := s.cacheKey("token_distribution", mintAddress, strings.Join(exclusionKey, ","))
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if holders, ok := cached.([]map[string]interface{}); ok {
+			return firstN(holders, limit), nil
+		}
+	}
+
+	largest, err := s.GetTokenAccountsByMint(ctx, mintAddress, largestAccountsLimit)
+	if err != nil {
+		return nil, err
+	}
+	if len(largest) == 0 {
+		return largest, nil
+	}
+
+	tokenInfo, err := s.GetTokenSupply(ctx, mintAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	remainingSupply := tokenInfo.ActualSupply
+	var kept []map[string]interface{}
+	for _, holder := range largest {
+		address, _ := holder["address"].(string)
+		if excluded[address] {
+			remainingSupply -= holderUIAmount(holder)
+			continue
+		}
+		kept = append(kept, holder)
+	}
+
+	holders := []map[string]interface{}{}
+	for _, holder := range kept {
+		percentage := safeDivide(holderUIAmount(holder), remainingSupply) * 100
+
+		entry := make(map[string]interface{}, len(holder)+1)
+		for k, v := range holder {
+			entry[k] = v
+		}
+		entry["percentage"] = percentage
+		holders = append(holders, entry)
+	}
+
+	if len(excluded) > 0 {
+		log.Printf("Excluded %d of %d largest holders for %s", len(largest)-len(kept), len(largest), mintAddress)
+	}
+
+	s.setCache(ctx, cacheKey, holders, 5*time.Minute)
+
+	return firstN(holders, limit), nil
+}
+
+func holderUIAmount(holder map[string]interface{}) float64 {
+	balance, ok := holder["balance"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	uiAmount, _ := balance["uiAmount"].(float64)
+	return uiAmount
+}
+
+type HolderOverlapRequest struct {
+	Mints []string `json:"mints" binding:"required"`
+}
+
+type mintHolderOwners struct {
+	Owners []string `json:"-"`
+	Count  int      `json:"holderCount"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// GetTokenAccountOwners resolves token account addresses to the wallets that
+// own them with a single getMultipleAccounts call.
+func (s *SolanaRPCClient) GetTokenAccountOwners(ctx context.Context, tokenAccounts []string) (map[string]string, error) {
+	owners := make(map[string]string, len(tokenAccounts))
+	if len(tokenAccounts) == 0 {
+		return owners, nil
+	}
+
+	params := []interface{}{tokenAccounts, map[string]interface{}{"encoding": "jsonParsed"}}
+	resp, err := s.makeRPCCallWithRetry(ctx, "getMultipleAccounts", params)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, _, err := unwrapContext("getMultipleAccounts", resp.Result)
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := result.([]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getMultipleAccounts", Detail: "value is not an array"}
+	}
+
+	for i, value := range values {
+		if i >= len(tokenAccounts) {
+			break
+		}
+		account, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		data, _ := account["data"].(map[string]interface{})
+		parsed, _ := data["parsed"].(map[string]interface{})
+		info, _ := parsed["info"].(map[string]interface{})
+		if owner, ok := info["owner"].(string); ok {
+			owners[tokenAccounts[i]] = owner
+		}
+	}
+
+	return owners, nil
+}
+
+// GetTokenHolderOwners returns the distinct wallets owning the largest token
+// accounts of a mint. Holder depth is capped at the 20 accounts returned by
+// getTokenLargestAccounts.
+func (s *SolanaRPCClient) GetTokenHolderOwners(ctx context.Context, mintAddress string) ([]string, error) {
+	cacheKey := s.cacheKey("token_holder_owners", mintAddress)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if owners, ok := cached.([]string); ok {
+			return owners, nil
+		}
+	}
+
+	holders, err := s.GetTokenAccountsByMint(ctx, mintAddress, largestAccountsLimit)
+	if err != nil {
+		return nil, err
+	}
+	if len(holders) == 0 {
+		return nil, fmt.Errorf("no holders found")
+	}
+
+	tokenAccounts := make([]string, 0, len(holders))
+	for _, holder := range holders {
+		if address, ok := holder["address"].(string); ok {
+			tokenAccounts = append(tokenAccounts, address)
+		}
+	}
+
+	ownerByAccount, err := s.GetTokenAccountOwners(ctx, tokenAccounts)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(ownerByAccount))
+	owners := []string{}
+	for _, account := range tokenAccounts {
+		owner, ok := ownerByAccount[account]
+		if !ok || seen[owner] {
+			continue
+		}
+		seen[owner] = true
+		owners = append(owners, owner)
+	}
+
+	s.setCache(ctx, cacheKey, owners, 5*time.Minute)
+
+	return owners, nil
+}
+
+// handleHolderOverlap returns the wallets found among the largest holders of
+// both mints. Each mint costs a getTokenLargestAccounts and a
+// getMultipleAccounts call on a cold cache.
+func handleHolderOverlap(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req HolderOverlapRequest
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.Mints) != 2 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly two mint addresses are required"})
+			return
+		}
+		if req.Mints[0] == req.Mints[1] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Mint addresses must be different"})
+			return
+		}
+
+		results := make([]*mintHolderOwners, len(req.Mints))
+		var wg sync.WaitGroup
+		for i, mint := range req.Mints {
+			wg.Add(1)
+			go func(i int, mint string) {
+				defer wg.Done()
+				owners, err := client.GetTokenHolderOwners(c.Request.Context(), mint)
+				if err != nil {
+					results[i] = &mintHolderOwners{Error: err.Error()}
+					return
+				}
+				results[i] = &mintHolderOwners{Owners: owners, Count: len(owners)}
+			}(i, mint)
+		}
+		wg.Wait()
+
+		mints := make(map[string]*mintHolderOwners, len(req.Mints))
+		for i, mint := range req.Mints {
+			mints[mint] = results[i]
+		}
+
+		overlap := []string{}
+		if results[0].Error == "" && results[1].Error == "" {
+			inFirst := make(map[string]bool, len(results[0].Owners))
+			for _, owner := range results[0].Owners {
+				inFirst[owner] = true
+			}
+			for _, owner := range results[1].Owners {
+				if inFirst[owner] {
+					overlap = append(overlap, owner)
+				}
+			}
+		}
+
+		respondJSON(c, http.StatusOK, gin.H{
+			"mints":        mints,
+			"overlap":      overlap,
+			"overlapCount": len(overlap),
+			"holderDepth":  largestAccountsLimit,
+		})
+	}
+}
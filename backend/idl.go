@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	anchorDiscriminatorSize = 8
+
+	// maxIDLBytes caps an IDL uploaded with a decode request.
+	maxIDLBytes = 1 << 20
+
+	// maxIDLTypeDepth stops self-referencing or absurdly nested types.
+	maxIDLTypeDepth = 32
+)
+
+// anchorIDL holds the parts of an Anchor IDL needed to decode accounts. Both
+// the legacy layout (account types inline, "publicKey", "defined": "Name")
+// and the 0.30+ layout (explicit discriminators, types listed separately,
+// "pubkey", "defined": {"name": "Name"}) are accepted.
+type anchorIDL struct {
+	Address  string       `json:"address"`
+	Name     string       `json:"name"`
+	Accounts []idlAccount `json:"accounts"`
+	Types    []idlTypeDef `json:"types"`
+	Metadata idlMetadata  `json:"metadata"`
+	types    map[string]*idlTypeDefTy
+}
+
+type idlMetadata struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+type idlAccount struct {
+	Name          string        `json:"name"`
+	Discriminator []int         `json:"discriminator"`
+	Type          *idlTypeDefTy `json:"type"`
+}
+
+type idlTypeDef struct {
+	Name string       `json:"name"`
+	Type idlTypeDefTy `json:"type"`
+}
+
+type idlTypeDefTy struct {
+	Kind     string        `json:"kind"`
+	Fields   []interface{} `json:"fields"`
+	Variants []idlVariant  `json:"variants"`
+	Alias    interface{}   `json:"alias"`
+}
+
+type idlVariant struct {
+	Name   string        `json:"name"`
+	Fields []interface{} `json:"fields"`
+}
+
+// programID returns the program the IDL describes, if it says.
+func (idl *anchorIDL) programID() string {
+	if idl.Address != "" {
+		return idl.Address
+	}
+	return idl.Metadata.Address
+}
+
+func parseAnchorIDL(raw []byte) (*anchorIDL, error) {
+	var idl anchorIDL
+	if err := json.Unmarshal(raw, &idl); err != nil {
+		return nil, fmt.Errorf("invalid IDL: %w", err)
+	}
+	if len(idl.Accounts) == 0 {
+		return nil, fmt.Errorf("IDL defines no accounts")
+	}
+	idl.types = make(map[string]*idlTypeDefTy, len(idl.Types)+len(idl.Accounts))
+	for i := range idl.Types {
+		idl.types[idl.Types[i].Name] = &idl.Types[i].Type
+	}
+	for _, account := range idl.Accounts {
+		if account.Type != nil {
+			idl.types[account.Name] = account.Type
+		}
+	}
+	return &idl, nil
+}
+
+// loadIDLDir reads every *.json file in dir as an IDL, keyed by the program
+// address it declares or, failing that, its file name.
+func loadIDLDir(dir string) (map[string]*anchorIDL, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	idls := make(map[string]*anchorIDL, len(paths))
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		idl, err := parseAnchorIDL(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		key := idl.programID()
+		if key == "" {
+			key = strings.TrimSuffix(filepath.Base(path), ".json")
+		}
+		idls[key] = idl
+		log.Printf("Registered IDL for %s", key)
+	}
+	return idls, nil
+}
+
+// accountDiscriminator returns the account's 8-byte prefix: the IDL's own
+// value when it has one, otherwise sha256("account:<Name>").
+func accountDiscriminator(account idlAccount) []byte {
+	if len(account.Discriminator) == anchorDiscriminatorSize {
+		discriminator := make([]byte, anchorDiscriminatorSize)
+		for i, b := range account.Discriminator {
+			discriminator[i] = byte(b)
+		}
+		return discriminator
+	}
+	sum := sha256.Sum256([]byte("account:" + account.Name))
+	return sum[:anchorDiscriminatorSize]
+}
+
+// DecodedAccount is an account's data decoded with an Anchor IDL.
+type DecodedAccount struct {
+	Address     string      `json:"address"`
+	Owner       string      `json:"owner"`
+	AccountType string      `json:"accountType"`
+	Data        interface{} `json:"data"`
+	UnusedBytes int         `json:"unusedBytes"`
+}
+
+// decodeAnchorAccount matches data's discriminator against the IDL accounts
+// and decodes the rest with the matching layout. Bytes left over after the
+// last field are counted, not treated as an error, since accounts are often
+// allocated larger than their struct.
+func decodeAnchorAccount(idl *anchorIDL, data []byte) (string, interface{}, int, error) {
+	if len(data) < anchorDiscriminatorSize {
+		return "", nil, 0, fmt.Errorf("account data is shorter than a discriminator")
+	}
+	for _, account := range idl.Accounts {
+		if !bytes.Equal(accountDiscriminator(account), data[:anchorDiscriminatorSize]) {
+			continue
+		}
+		typeDef, ok := idl.types[account.Name]
+		if !ok {
+			return "", nil, 0, fmt.Errorf("IDL has no type definition for account %s", account.Name)
+		}
+		r := &borshReader{data: data, pos: anchorDiscriminatorSize}
+		decoded, err := idl.decodeTypeDef(r, typeDef, 0)
+		if err != nil {
+			return "", nil, 0, fmt.Errorf("decoding %s: %w", account.Name, err)
+		}
+		return account.Name, decoded, len(data) - r.pos, nil
+	}
+	return "", nil, 0, fmt.Errorf("discriminator matches no account in the IDL")
+}
+
+func (idl *anchorIDL) decodeTypeDef(r *borshReader, def *idlTypeDefTy, depth int) (interface{}, error) {
+	switch def.Kind {
+	case "struct":
+		return idl.decodeFields(r, def.Fields, depth)
+	case "enum":
+		index, err := r.u8()
+		if err != nil {
+			return nil, err
+		}
+		if int(index) >= len(def.Variants) {
+			return nil, fmt.Errorf("enum variant %d out of range", index)
+		}
+		variant := def.Variants[index]
+		if len(variant.Fields) == 0 {
+			return variant.Name, nil
+		}
+		fields, err := idl.decodeFields(r, variant.Fields, depth)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{variant.Name: fields}, nil
+	case "type":
+		return idl.decodeType(r, def.Alias, depth+1)
+	default:
+		return nil, fmt.Errorf("unsupported type kind %q", def.Kind)
+	}
+}
+
+// decodeFields decodes named fields into an object and tuple fields into an
+// array.
+func (idl *anchorIDL) decodeFields(r *borshReader, fields []interface{}, depth int) (interface{}, error) {
+	named := map[string]interface{}{}
+	var tuple []interface{}
+	for _, field := range fields {
+		if spec, ok := field.(map[string]interface{}); ok {
+			if name, ok := spec["name"].(string); ok {
+				value, err := idl.decodeType(r, spec["type"], depth+1)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				named[name] = value
+				continue
+			}
+		}
+		value, err := idl.decodeType(r, field, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		tuple = append(tuple, value)
+	}
+	if tuple != nil {
+		return tuple, nil
+	}
+	return named, nil
+}
+
+func (idl *anchorIDL) decodeType(r *borshReader, typ interface{}, depth int) (interface{}, error) {
+	if depth > maxIDLTypeDepth {
+		return nil, fmt.Errorf("type nesting exceeds %d levels", maxIDLTypeDepth)
+	}
+
+	switch typ := typ.(type) {
+	case string:
+		return decodePrimitive(r, typ)
+	case map[string]interface{}:
+		if inner, ok := typ["vec"]; ok {
+			n, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			// Every element takes at least a byte, which bounds n by what is
+			// left and keeps a corrupt length from allocating gigabytes.
+			if int(n) > len(r.data)-r.pos {
+				return nil, fmt.Errorf("vec length %d exceeds remaining data", n)
+			}
+			return idl.decodeSequence(r, inner, int(n), depth)
+		}
+		if inner, ok := typ["option"]; ok {
+			tag, err := r.u8()
+			if err != nil {
+				return nil, err
+			}
+			return idl.decodeOptional(r, inner, tag != 0, depth)
+		}
+		if inner, ok := typ["coption"]; ok {
+			tag, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			return idl.decodeOptional(r, inner, tag != 0, depth)
+		}
+		if array, ok := typ["array"].([]interface{}); ok && len(array) == 2 {
+			n, ok := array[1].(float64)
+			if !ok || n < 0 {
+				return nil, fmt.Errorf("unsupported array length %v", array[1])
+			}
+			if int(n) > len(r.data)-r.pos {
+				return nil, fmt.Errorf("array length %v exceeds remaining data", n)
+			}
+			return idl.decodeSequence(r, array[0], int(n), depth)
+		}
+		if defined, ok := typ["defined"]; ok {
+			name, _ := defined.(string)
+			if spec, ok := defined.(map[string]interface{}); ok {
+				if _, generic := spec["generics"]; generic {
+					return nil, fmt.Errorf("generic types are not supported")
+				}
+				name, _ = spec["name"].(string)
+			}
+			def, ok := idl.types[name]
+			if !ok {
+				return nil, fmt.Errorf("undefined type %q", name)
+			}
+			return idl.decodeTypeDef(r, def, depth+1)
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %v", typ)
+}
+
+func (idl *anchorIDL) decodeSequence(r *borshReader, inner interface{}, n, depth int) (interface{}, error) {
+	// Byte arrays and vecs read better as base64 than as lists of numbers.
+	if inner == "u8" {
+		b, err := r.bytes(n)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+	}
+	values := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		value, err := idl.decodeType(r, inner, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func (idl *anchorIDL) decodeOptional(r *borshReader, inner interface{}, present bool, depth int) (interface{}, error) {
+	if !present {
+		return nil, nil
+	}
+	return idl.decodeType(r, inner, depth+1)
+}
+
+// decodePrimitive decodes a scalar IDL type. 64- and 128-bit integers are
+// returned as decimal strings so JavaScript clients do not lose precision.
+func decodePrimitive(r *borshReader, typ string) (interface{}, error) {
+	switch typ {
+	case "bool":
+		b, err := r.u8()
+		return b != 0, err
+	case "u8":
+		return r.u8()
+	case "i8":
+		b, err := r.u8()
+		return int8(b), err
+	case "u16":
+		return r.u16()
+	case "i16":
+		v, err := r.u16()
+		return int16(v), err
+	case "u32":
+		return r.u32()
+	case "i32":
+		v, err := r.u32()
+		return int32(v), err
+	case "f32":
+		v, err := r.u32()
+		return jsonSafeFloat(float64(math.Float32frombits(v))), err
+	case "u64":
+		v, err := r.u64()
+		return strconv.FormatUint(v, 10), err
+	case "i64":
+		v, err := r.u64()
+		return strconv.FormatInt(int64(v), 10), err
+	case "f64":
+		v, err := r.u64()
+		return jsonSafeFloat(math.Float64frombits(v)), err
+	case "u128", "i128":
+		b, err := r.bytes(16)
+		if err != nil {
+			return nil, err
+		}
+		bigEndian := make([]byte, 16)
+		for i := range b {
+			bigEndian[15-i] = b[i]
+		}
+		value := new(big.Int).SetBytes(bigEndian)
+		if typ == "i128" && b[15]&0x80 != 0 {
+			value.Sub(value, new(big.Int).Lsh(big.NewInt(1), 128))
+		}
+		return value.String(), nil
+	case "string":
+		return r.string()
+	case "bytes":
+		n, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.bytes(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+	case "publicKey", "pubkey":
+		return r.pubkey()
+	default:
+		return nil, fmt.Errorf("unsupported primitive type %q", typ)
+	}
+}
+
+type DecodeAccountRequest struct {
+	// IDL is an Anchor IDL to decode with. When omitted, the IDL registered
+	// for Program, or else for the account's owner, is used.
+	IDL     json.RawMessage `json:"idl"`
+	Program string          `json:"program"`
+}
+
+func handleDecodeAccount(client *SolanaRPCClient, registered map[string]*anchorIDL) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		address := c.Param("address")
+		if _, err := decodePublicKey(address); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account address"})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxIDLBytes)
+		var req DecodeAccountRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+				return
+			}
+		}
+
+		var idl *anchorIDL
+		if len(req.IDL) > 0 {
+			parsed, err := parseAnchorIDL(req.IDL)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IDL", "details": err.Error()})
+				return
+			}
+			idl = parsed
+		} else if req.Program != "" {
+			if idl = registered[req.Program]; idl == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "No IDL is registered for this program"})
+				return
+			}
+		}
+
+		account, err := client.GetAccountInfoWithOptions(c.Request.Context(), address, AccountInfoOptions{DataEncoding: "base64"})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get account info"})
+			return
+		}
+		if !account.IsValid {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+		if account.DataTruncated {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Account data exceeds MAX_ACCOUNT_DATA_BYTES"})
+			return
+		}
+
+		if idl == nil {
+			if idl = registered[account.Owner]; idl == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "No IDL is registered for the account's owner program"})
+				return
+			}
+		}
+		if program := idl.programID(); program != "" && program != account.Owner {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Account is not owned by the IDL's program", "owner": account.Owner})
+			return
+		}
+
+		data, err := base64.StdEncoding.DecodeString(account.Data)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read account data"})
+			return
+		}
+		accountType, decoded, unused, err := decodeAnchorAccount(idl, data)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to decode account", "details": err.Error()})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, DecodedAccount{
+			Address:     address,
+			Owner:       account.Owner,
+			AccountType: accountType,
+			Data:        decoded,
+			UnusedBytes: unused,
+		})
+	}
+}
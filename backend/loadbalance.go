@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Load-balancing strategies for LB_STRATEGY, which decide the order the
+// configured endpoints are tried in.
+const (
+	// lbPrimary always tries the endpoints in SOLANA_RPC_URLS order, so the
+	// backups only see traffic while the primary fails.
+	lbPrimary = "primary"
+	// lbRoundRobin starts each call at the next endpoint in turn.
+	lbRoundRobin = "round-robin"
+	// lbLeastLatency prefers the endpoint with the lowest latencyEWMA.
+	lbLeastLatency = "least-latency"
+)
+
+var validLBStrategies = map[string]bool{
+	lbPrimary:      true,
+	lbRoundRobin:   true,
+	lbLeastLatency: true,
+}
+
+// latencyEWMAWeight is the weight of each new call in an endpoint's latency
+// average; about the last ten calls dominate it.
+const latencyEWMAWeight = 0.2
+
+// observeLatency folds the duration of a successful call into the
+// endpoint's latency average.
+func (e *rpcEndpoint) observeLatency(elapsed time.Duration) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.latencyEWMA == 0 {
+		e.latencyEWMA = elapsed
+		return
+	}
+	e.latencyEWMA = time.Duration(latencyEWMAWeight*float64(elapsed) + (1-latencyEWMAWeight)*float64(e.latencyEWMA))
+}
+
+func (e *rpcEndpoint) averageLatency() time.Duration {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.latencyEWMA
+}
+
+// balanceEndpoints orders the configured endpoints by s.lbStrategy. The
+// result is a copy; s.endpoints keeps the primary first.
+func (s *SolanaRPCClient) balanceEndpoints() []*rpcEndpoint {
+	endpoints := make([]*rpcEndpoint, len(s.endpoints))
+	copy(endpoints, s.endpoints)
+	if len(endpoints) < 2 {
+		return endpoints
+	}
+
+	switch s.lbStrategy {
+	case lbRoundRobin:
+		s.mutex.Lock()
+		start := s.lbNext % len(endpoints)
+		s.lbNext++
+		s.mutex.Unlock()
+		endpoints = append(endpoints[start:], endpoints[:start]...)
+	case lbLeastLatency:
+		// Endpoints without a measurement yet sort first, so each one is
+		// tried and gets one.
+		latencies := make(map[*rpcEndpoint]time.Duration, len(endpoints))
+		for _, endpoint := range endpoints {
+			latencies[endpoint] = endpoint.averageLatency()
+		}
+		sort.SliceStable(endpoints, func(i, j int) bool {
+			return latencies[endpoints[i]] < latencies[endpoints[j]]
+		})
+	}
+	return endpoints
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/mr-tron/base58"
+)
+
+// lookupTableMetaSize is the size of the AddressLookupTable header
+// (LOOKUP_TABLE_META_SIZE); the stored addresses follow it back to back.
+const lookupTableMetaSize = 56
+
+type LoadedAddresses struct {
+	Writable []string `json:"writable"`
+	Readonly []string `json:"readonly"`
+}
+
+type addressTableLookup struct {
+	AccountKey      string
+	WritableIndexes []int
+	ReadonlyIndexes []int
+}
+
+func parseIndexList(raw interface{}) []int {
+	values, _ := raw.([]interface{})
+	indexes := make([]int, 0, len(values))
+	for _, value := range values {
+		if index, ok := value.(float64); ok {
+			indexes = append(indexes, int(index))
+		}
+	}
+	return indexes
+}
+
+func parseAddressTableLookups(raw interface{}) []addressTableLookup {
+	entries, _ := raw.([]interface{})
+	var lookups []addressTableLookup
+	for _, entry := range entries {
+		lookup, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		accountKey, ok := lookup["accountKey"].(string)
+		if !ok {
+			continue
+		}
+		lookups = append(lookups, addressTableLookup{
+			AccountKey:      accountKey,
+			WritableIndexes: parseIndexList(lookup["writableIndexes"]),
+			ReadonlyIndexes: parseIndexList(lookup["readonlyIndexes"]),
+		})
+	}
+	return lookups
+}
+
+func parseStringList(raw interface{}) []string {
+	values, _ := raw.([]interface{})
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			list = append(list, str)
+		}
+	}
+	return list
+}
+
+// parseLoadedAddresses reads meta.loadedAddresses from a getTransaction result.
+func parseLoadedAddresses(raw interface{}) *LoadedAddresses {
+	loaded, _ := raw.(map[string]interface{})
+	return &LoadedAddresses{
+		Writable: parseStringList(loaded["writable"]),
+		Readonly: parseStringList(loaded["readonly"]),
+	}
+}
+
+// getLookupTables returns the addresses stored in each lookup table. Tables
+// are append-only, so a cached copy stays valid for every index it covers;
+// only tables missing from the cache are fetched, with one getMultipleAccounts
+// call.
+func (s *SolanaRPCClient) getLookupTables(ctx context.Context, tableAddresses []string) (map[string][]string, error) {
+	tables := make(map[string][]string, len(tableAddresses))
+	var missing []string
+	for _, address := range tableAddresses {
+		if cached, found := s.getFromCache(ctx, s.cacheKey("lookup_table", address)); found {
+			if addresses, ok := cached.([]string); ok {
+				tables[address] = addresses
+				continue
+			}
+		}
+		missing = append(missing, address)
+	}
+	if len(missing) == 0 {
+		return tables, nil
+	}
+
+	params := []interface{}{missing, map[string]interface{}{"encoding": "base64"}}
+	resp, err := s.makeRPCCallWithRetry(ctx, "getMultipleAccounts", params)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, _, err := unwrapContext("getMultipleAccounts", resp.Result)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != len(missing) {
+		return nil, &ParseError{Method: "getMultipleAccounts", Detail: "value is not an array of the requested length"}
+	}
+
+	for i, value := range values {
+		account, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("lookup table %s not found", missing[i])
+		}
+		data, _ := account["data"].([]interface{})
+		if len(data) == 0 {
+			return nil, &ParseError{Method: "getMultipleAccounts", Detail: "account data is missing"}
+		}
+		encoded, _ := data[0].(string)
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, &ParseError{Method: "getMultipleAccounts", Detail: "account data is not base64"}
+		}
+		if len(raw) < lookupTableMetaSize || (len(raw)-lookupTableMetaSize)%32 != 0 {
+			return nil, fmt.Errorf("account %s is not an address lookup table", missing[i])
+		}
+
+		var addresses []string
+		for offset := lookupTableMetaSize; offset < len(raw); offset += 32 {
+			addresses = append(addresses, base58.Encode(raw[offset:offset+32]))
+		}
+		tables[missing[i]] = addresses
+		s.setCache(ctx, s.cacheKey("lookup_table", missing[i]), addresses, 1*time.Hour)
+	}
+
+	return tables, nil
+}
+
+// resolveLoadedAddresses expands a v0 message's table lookups into the
+// loaded addresses, in the order the runtime appends them to the account
+// keys: all writable addresses, then all read-only ones.
+func (s *SolanaRPCClient) resolveLoadedAddresses(ctx context.Context, lookups []addressTableLookup) (*LoadedAddresses, error) {
+	tableAddresses := make([]string, 0, len(lookups))
+	for _, lookup := range lookups {
+		tableAddresses = append(tableAddresses, lookup.AccountKey)
+	}
+	tables, err := s.getLookupTables(ctx, tableAddresses)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := &LoadedAddresses{Writable: []string{}, Readonly: []string{}}
+	resolve := func(table string, indexes []int, into *[]string) error {
+		addresses := tables[table]
+		for _, index := range indexes {
+			if index >= len(addresses) {
+				return fmt.Errorf("index %d out of range for lookup table %s", index, table)
+			}
+			*into = append(*into, addresses[index])
+		}
+		return nil
+	}
+	for _, lookup := range lookups {
+		if err := resolve(lookup.AccountKey, lookup.WritableIndexes, &loaded.Writable); err != nil {
+			return nil, err
+		}
+	}
+	for _, lookup := range lookups {
+		if err := resolve(lookup.AccountKey, lookup.ReadonlyIndexes, &loaded.Readonly); err != nil {
+			return nil, err
+		}
+	}
+
+	return loaded, nil
+}
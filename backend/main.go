@@ -2,39 +2,157 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"golang.org/x/time/rate"
 )
 
 type SolanaRPCClient struct {
 	URL                string
-	rateLimiter        map[string]time.Time
+	endpoints          []*rpcEndpoint
+	rateLimiter        *adaptiveLimiter
 	mutex              sync.RWMutex
 	cache              map[string]CacheEntry
+	cacheLRU           *cacheLRU
+	cacheMaxEntries    int
+	cacheEvictions     uint64
 	lastBlockTime      float64
 	lastBlockTimeCheck time.Time
+	holderDenylist     []string
+	maxAccountData     int
+	metadataHTTPClient *http.Client
+	maxCacheStaleness  time.Duration
+	httpClient         *http.Client
+
+	// zstdAccountData fetches account data as base64+zstd; see
+	// accountDataEncoding.
+	zstdAccountData bool
+
+	// neverCacheKinds are refused by storeCache; see defaultNeverCacheKinds.
+	neverCacheKinds map[string]bool
+
+	accountsChunkConcurrency int
+	metricsSampleCount       int
+
+	// slotCacheBlocks is how many block times a slot stays cached; see
+	// slotCacheTTL.
+	slotCacheBlocks float64
+
+	// maxProgramAccounts caps /api/program/:programId/accounts responses.
+	maxProgramAccounts int
+
+	// epochSchedule is loaded once; see EpochSchedule.
+	epochSchedule *EpochSchedule
+
+	// heavyEndpoint serves heavyMethods when set; see endpointsFor.
+	heavyEndpoint *rpcEndpoint
+	heavyMethods  map[string]bool
+
+	// tpsCrossCheck enables TransactionCountTPS; lastTransactionCount holds
+	// the reading it is computed against.
+	tpsCrossCheck        bool
+	lastTransactionCount *transactionCountSample
+
+	// consensusMetrics polls every configured endpoint for the slot and
+	// health in GetMetrics; see MetricsConsensus. consensusDisagreement is
+	// the outcome of the last round.
+	consensusMetrics       bool
+	consensusSlotTolerance uint64
+	consensusDisagreement  bool
+
+	// metricsFetch is the GetMetrics call shared by concurrent requests;
+	// metricsWait bounds how long a request waits for it. See sharedMetrics.
+	metricsFetch *metricsFetch
+	metricsWait  time.Duration
+
+	// retryPolicies overrides defaultRetryPolicies; see retryPolicyFor.
+	retryPolicies map[string]retryPolicy
+	retryLog      *retryLog
+	maxTotalWait  time.Duration
+	minRetryAfter time.Duration
+
+	// failoverThreshold failures in a row take an endpoint out of the
+	// failover order for failoverCooldown; see endpointsFor.
+	failoverThreshold int
+	failoverCooldown  time.Duration
+
+	// lbStrategy orders the configured endpoints; see balanceEndpoints.
+	// lbNext is the round-robin position.
+	lbStrategy string
+	lbNext     int
+
+	// Token buckets from RPC_RATE and RPC_METHOD_RATES; see tokenBucketFor.
+	globalRateLimiter  *rate.Limiter
+	methodRateLimiters map[string]*rate.Limiter
+
+	// priceSource selects the SOL/USD oracle; empty disables pricing.
+	priceSource      string
+	priceCacheTTL    time.Duration
+	pythPriceAccount string
+	tokenPriceSource string
+	tokenPriceURL    string
+	portfolioDustUSD float64
+
+	// network and commitment namespace every cache key; see cacheKey.
+	network    string
+	commitment string
+
+	// Addresses whose cache entries are kept warm; see pinCache.
+	pinnedMints     []string
+	pinnedAccounts  []string
+	pinnedAddresses map[string]bool
+
+	// Endpoints found by AUTO_DISCOVER_RPC; discovery is nil when it is off.
+	discoveredMutex sync.RWMutex
+	discovered      []*rpcEndpoint
+	discovery       *rpcDiscovery
+
+	// done stops the cache janitor; see Close.
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
+// defaultCommitment is the level nodes apply when a request does not set one.
+const defaultCommitment = "finalized"
+
+// defaultMaxCacheStaleness caps how old mutable cached data may be when it is
+// served, including last-known-good fallbacks. Override with
+// MAX_CACHE_STALENESS; 0 disables the cap.
+const defaultMaxCacheStaleness = 15 * time.Minute
+
 type CacheEntry struct {
 	Data      interface{}
+	CreatedAt time.Time
 	ExpiresAt time.Time
+	// Immutable entries hold data that can never change (finalized history)
+	// and are exempt from the maximum staleness cap.
+	Immutable bool
+	// Pinned entries belong to a PINNED_MINTS or PINNED_ACCOUNTS address and
+	// are kept warm by refreshPinned.
+	Pinned bool
 }
 
 type SolanaMetrics struct {
 	TPS              float64   `json:"tps"`
+	// NonVoteTPS is TPS without vote transactions; see calculateNonVoteTPS.
+	NonVoteTPS       float64   `json:"nonVoteTps"`
 	AverageBlockTime float64   `json:"averageBlockTime"`
-	CurrentSlot      uint64    `json:"currentSlot"`
+	CurrentSlot      BigUint   `json:"currentSlot"`
 	Epoch            uint64    `json:"epoch"`
 	ValidatorCount   int       `json:"validatorCount"`
 	Timestamp        time.Time `json:"timestamp"`
@@ -43,6 +161,18 @@ type SolanaMetrics struct {
 	SlotIndex        uint64    `json:"slotIndex"`
 	NetworkHealth    string    `json:"networkHealth"`
 	ConnectionStatus string    `json:"connectionStatus"`
+	StaleFields      []string  `json:"staleFields"`
+	AgeSeconds       float64   `json:"ageSeconds"`
+	// Partial is set when the validator count or TPS could not be fetched
+	// at all; ValidatorCount is then -1 and TPS 0.
+	Partial bool `json:"partial,omitempty"`
+	// TransactionCountTPS cross-checks TPS against the getTransactionCount
+	// delta since the previous metrics request; see transactionCountTPS.
+	TransactionCountTPS *float64 `json:"transactionCountTps,omitempty"`
+
+	// Consensus lists every endpoint's slot and health when
+	// CONSENSUS_METRICS is on; CurrentSlot is then its slot.
+	Consensus *MetricsConsensus `json:"consensus,omitempty"`
 }
 
 type AccountInfo struct {
@@ -50,21 +180,63 @@ type AccountInfo struct {
 	Balance     float64 `json:"balance"`
 	Executable  bool    `json:"executable"`
 	Owner       string  `json:"owner"`
-	RentEpoch   uint64  `json:"rentEpoch"`
-	Lamports    uint64  `json:"lamports"`
+	Lamports    BigUint `json:"lamports"`
 	DataLength  int     `json:"dataLength"`
 	IsValid     bool    `json:"isValid"`
+
+	// RentEpoch is left out for rent-exempt accounts unless
+	// RENT_EPOCH_SENTINEL asks for zero or u64::MAX; see parseRentEpoch.
+	RentEpoch  *BigUint `json:"rentEpoch,omitempty"`
+	RentExempt bool     `json:"rentExempt"`
+
+	Data          string `json:"data,omitempty"`
+	DataEncoding  string `json:"dataEncoding,omitempty"`
+	DataTruncated bool   `json:"dataTruncated,omitempty"`
+
+	// ParsedData is the node's jsonParsed view of the account, set with
+	// ?encoding=jsonParsed for programs the node can parse.
+	ParsedData interface{} `json:"parsedData,omitempty"`
+
+	// ContextSlot is the slot the node read the account at; see
+	// unwrapContext.
+	ContextSlot BigUint `json:"contextSlot,omitempty"`
+
+	USDValue *float64 `json:"usdValue,omitempty"`
+	USDError string   `json:"usdError,omitempty"`
+
+	Flagged    bool   `json:"flagged,omitempty"`
+	FlagReason string `json:"flagReason,omitempty"`
+
+	// Raw holds the upstream RPC results, keyed by method, for ?raw=true.
+	Raw        map[string]interface{} `json:"raw,omitempty"`
+	rpcResults map[string]interface{}
 }
 
 type TokenInfo struct {
 	MintAddress    string  `json:"mintAddress"`
-	Supply         uint64  `json:"supply"`
+	Supply         BigUint `json:"supply"`
 	Decimals       int     `json:"decimals"`
 	IsInitialized  bool    `json:"isInitialized"`
 	FreezeAuthority *string `json:"freezeAuthority"`
 	MintAuthority   *string `json:"mintAuthority"`
 	IsValid        bool    `json:"isValid"`
 	ActualSupply   float64 `json:"actualSupply"`
+
+	// Reason says why IsValid is false when the node said so explicitly;
+	// notAMintReason is the only one so far.
+	Reason string `json:"reason,omitempty"`
+
+	// ContextSlot is the slot the supply was read at.
+	ContextSlot BigUint `json:"contextSlot,omitempty"`
+
+	PriceUSD  *float64 `json:"priceUsd,omitempty"`
+	SupplyUSD *float64 `json:"supplyUsd,omitempty"`
+
+	Flagged    bool   `json:"flagged,omitempty"`
+	FlagReason string `json:"flagReason,omitempty"`
+
+	Raw        map[string]interface{} `json:"raw,omitempty"`
+	rpcResults map[string]interface{}
 }
 
 type RPCResponse struct {
@@ -72,75 +244,230 @@ type RPCResponse struct {
 	Error  interface{} `json:"error"`
 }
 
-func NewSolanaClient(url string) *SolanaRPCClient {
+// ParseError reports an RPC result whose shape does not match what the
+// calling method expects, e.g. from a buggy or misbehaving node.
+type ParseError struct {
+	Method string
+	Detail string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid %s response: %s", e.Method, e.Detail)
+}
+
+// RateLimitError is returned when a call is still being throttled after all
+// retries. RetryAfter is the server's hint, or zero when it sent none.
+type RateLimitError struct {
+	Method     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: rate limited", e.Method)
+}
+
+// rpcError turns the error member of a JSON-RPC response into an error. A
+// 429 becomes a RateLimitError carrying the node's Retry-After hint.
+func (s *SolanaRPCClient) rpcError(method string, rpcErr interface{}) error {
+	if errorMap, ok := rpcErr.(map[string]interface{}); ok && errorMap["code"] == float64(429) {
+		rateLimitErr := &RateLimitError{Method: method}
+		if retryAfter, ok := errorMap["retryAfter"].(string); ok {
+			rateLimitErr.RetryAfter, _ = parseRetryAfter(retryAfter, s.minRetryAfter)
+		}
+		return rateLimitErr
+	}
+	return fmt.Errorf("RPC error: %v", rpcErr)
+}
+
+// respondRPCFailure answers a request whose lookup failed upstream: 503 with
+// the Retry-After hint when the node is rate limiting, 502 otherwise.
+func respondRPCFailure(c *gin.Context, message string, err error) {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		if rateLimitErr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))))
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": message, "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadGateway, gin.H{"error": message, "details": err.Error()})
+}
+
+// NewSolanaClient returns a client for the RPC endpoints in urls, primary
+// first; the others take over when it fails. At least one URL is required.
+func NewSolanaClient(urls ...string) *SolanaRPCClient {
+	url := urls[0]
+	endpoints := make([]*rpcEndpoint, len(urls))
+	for i, endpointURL := range urls {
+		endpoints[i] = newRPCEndpoint(endpointURL)
+	}
+
 	client := &SolanaRPCClient{
 		URL:                url,
-		rateLimiter:        make(map[string]time.Time),
+		endpoints:          endpoints,
+		rateLimiter:        newAdaptiveLimiter(),
+		globalRateLimiter:  newTokenBucket(defaultRPCRate, defaultRPCBurst),
+		retryLog:           newRetryLog(),
 		cache:              make(map[string]CacheEntry),
+		cacheLRU:           newCacheLRU(),
+		cacheMaxEntries:    defaultCacheMaxEntries,
 		lastBlockTime:      0.4, // Start with typical Solana block time
 		lastBlockTimeCheck: time.Time{}, // Zero time to trigger initial calculation
+		maxAccountData:     defaultMaxAccountData,
+		metadataHTTPClient: newMetadataHTTPClient(defaultMetadataFetchTimeout),
+		maxCacheStaleness:  defaultMaxCacheStaleness,
+		httpClient:         newRPCHTTPClient(defaultRPCTimeout),
+		neverCacheKinds:    newNeverCacheKinds(nil),
+
+		accountsChunkConcurrency: defaultAccountsChunkConcurrency,
+		metricsSampleCount:       defaultMetricsSampleCount,
+		slotCacheBlocks:          defaultSlotCacheBlocks,
+		consensusSlotTolerance:   defaultConsensusSlotTolerance,
+		maxTotalWait:             defaultMaxTotalRetryWait,
+		failoverThreshold:        defaultFailoverThreshold,
+		failoverCooldown:         defaultFailoverCooldown,
+		lbStrategy:               lbPrimary,
+		minRetryAfter:            defaultMinRetryAfter,
+
+		priceCacheTTL:    defaultPriceCacheTTL,
+		pythPriceAccount: defaultPythSOLUSDAccount,
+		tokenPriceURL:    defaultJupiterPriceURL,
+		portfolioDustUSD: defaultPortfolioDustUSD,
+
+		maxProgramAccounts: defaultMaxProgramAccounts,
+
+		network:    networkNamespace(url),
+		commitment: defaultCommitment,
+
+		done: make(chan struct{}),
 	}
 
 	// Start initial block time calculation in background
 	go client.updateBlockTimeInBackground()
+	go client.startCacheJanitor()
 
 	return client
 }
 
-func (s *SolanaRPCClient) checkRateLimit(method string) bool {
-	s.mutex.RLock()
-	lastCall, exists := s.rateLimiter[method]
-	s.mutex.RUnlock()
+func (s *SolanaRPCClient) getFromCache(ctx context.Context, key string) (interface{}, bool) {
+	data, _, found := s.getFromCacheWithAge(ctx, key)
+	return data, found
+}
 
-	if exists && time.Since(lastCall) < 2*time.Second {
-		return false
+// getFromCacheWithAge returns a cached value and how long ago it was stored.
+// Entries older than maxCacheStaleness are never served, whatever their
+// expiry; for those found is false but the age is still reported so callers
+// can tell "too old" apart from "missing". A hit makes the entry the most
+// recently used, which takes the write lock. A request pinned with ?rpc=
+// always misses, since the cache does not know which endpoint answered.
+func (s *SolanaRPCClient) getFromCacheWithAge(ctx context.Context, key string) (interface{}, time.Duration, bool) {
+	if _, overridden := endpointOverrideFrom(ctx); overridden {
+		return nil, 0, false
 	}
-	return true
-}
 
-func (s *SolanaRPCClient) updateRateLimit(method string) {
 	s.mutex.Lock()
-	s.rateLimiter[method] = time.Now()
-	s.mutex.Unlock()
-}
-
-func (s *SolanaRPCClient) getFromCache(key string) (interface{}, bool) {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
+	defer s.mutex.Unlock()
 
 	entry, exists := s.cache[key]
 	if !exists || time.Now().After(entry.ExpiresAt) {
-		return nil, false
+		observeCacheLookup(key, false)
+		return nil, 0, false
 	}
-	return entry.Data, true
+
+	age := time.Since(entry.CreatedAt)
+	if !entry.Immutable && s.maxCacheStaleness > 0 && age > s.maxCacheStaleness {
+		observeCacheLookup(key, false)
+		return nil, age, false
+	}
+	observeCacheLookup(key, true)
+	s.cacheLRU.touch(key)
+	return entry.Data, age, true
+}
+
+func (s *SolanaRPCClient) setCache(ctx context.Context, key string, data interface{}, duration time.Duration) {
+	s.storeCache(ctx, key, data, duration, false)
+}
+
+// setImmutableCache caches data that can never change, such as finalized
+// history, without subjecting it to the staleness cap.
+func (s *SolanaRPCClient) setImmutableCache(ctx context.Context, key string, data interface{}, duration time.Duration) {
+	s.storeCache(ctx, key, data, duration, true)
 }
 
-func (s *SolanaRPCClient) setCache(key string, data interface{}, duration time.Duration) {
+// storeCache is where every cached response is written, so it enforces
+// neverCacheKinds for all of them. Results of a request pinned with ?rpc=
+// are never stored: they come from an endpoint the operator chose to
+// compare, not from the one regular traffic would use.
+func (s *SolanaRPCClient) storeCache(ctx context.Context, key string, data interface{}, duration time.Duration, immutable bool) {
+	if _, overridden := endpointOverrideFrom(ctx); overridden || s.neverCached(key) {
+		return
+	}
+	now := time.Now()
 	s.mutex.Lock()
-	s.cache[key] = CacheEntry{
+	s.putCacheEntry(key, CacheEntry{
 		Data:      data,
-		ExpiresAt: time.Now().Add(duration),
-	}
+		CreatedAt: now,
+		ExpiresAt: now.Add(duration),
+		Immutable: immutable,
+		Pinned:    s.isPinnedKey(key),
+	})
 	s.mutex.Unlock()
 }
 
-func parseRetryAfter(retryAfter string) (time.Duration, error) {
-	if seconds, err := strconv.Atoi(retryAfter); err == nil {
-		duration := time.Duration(seconds) * time.Second
-		if duration > 5*time.Minute {
-			return 5 * time.Minute, nil
-		}
-		return duration, nil
+// networkNamespace identifies the cluster behind an RPC URL for cache keys.
+// The host is used rather than the full URL so API keys stay out of them.
+func networkNamespace(rpcURL string) string {
+	if parsed, err := url.Parse(rpcURL); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return rpcURL
+}
+
+// cacheKey composes the key for cached data of the given kind read at the
+// client's commitment level. Every cache entry must be keyed through it so
+// that data from different networks or commitment levels never collides.
+func (s *SolanaRPCClient) cacheKey(kind string, args ...interface{}) string {
+	return s.cacheKeyAt(s.commitment, kind, args...)
+}
+
+// cacheKeyAt is cacheKey for data read at an explicit commitment level.
+func (s *SolanaRPCClient) cacheKeyAt(commitment, kind string, args ...interface{}) string {
+	parts := make([]string, 0, len(args)+3)
+	parts = append(parts, s.network, commitment, kind)
+	for _, arg := range args {
+		parts = append(parts, fmt.Sprint(arg))
 	}
+	return strings.Join(parts, "|")
+}
 
-	if retryTime, err := time.Parse(time.RFC1123, retryAfter); err == nil {
-		duration := time.Until(retryTime)
-		if duration > 0 && duration <= 5*time.Minute {
-			return duration, nil
+// maxRetryAfter is the longest delay a Retry-After header is honoured for.
+const maxRetryAfter = 5 * time.Minute
+
+// defaultMinRetryAfter is the shortest delay a Retry-After header can ask
+// for. Without it "0", or a date that a clock slightly ahead of ours puts
+// just into the future, would send the next attempt straight back into the
+// limit. Override with RETRY_AFTER_MIN; 0 disables the floor.
+const defaultMinRetryAfter = 1 * time.Second
+
+// parseRetryAfter reads a Retry-After header in either its delta-seconds or
+// HTTP-date form, raising the delay to at least floor. A date in the past
+// says nothing about how long to wait, since it usually means the clocks
+// disagree, so like an unparsable header it is an error and the caller
+// falls back to exponential backoff.
+func parseRetryAfter(retryAfter string, floor time.Duration) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("negative Retry-After header: %s", retryAfter)
+		}
+		duration := time.Duration(seconds) * time.Second
+		if duration > maxRetryAfter {
+			return maxRetryAfter, nil
 		}
+		return max(duration, floor), nil
 	}
 
 	formats := []string{
+		time.RFC1123,
 		time.RFC822,
 		time.RFC822Z,
 		time.RFC850,
@@ -150,16 +477,54 @@ func parseRetryAfter(retryAfter string) (time.Duration, error) {
 	for _, format := range formats {
 		if retryTime, err := time.Parse(format, retryAfter); err == nil {
 			duration := time.Until(retryTime)
-			if duration > 0 && duration <= 5*time.Minute {
-				return duration, nil
+			if duration <= 0 {
+				return 0, fmt.Errorf("Retry-After date is in the past: %s", retryAfter)
+			}
+			if duration > maxRetryAfter {
+				return 0, fmt.Errorf("Retry-After date is more than %v away: %s", maxRetryAfter, retryAfter)
 			}
+			return max(duration, floor), nil
 		}
 	}
 
 	return 0, fmt.Errorf("unable to parse Retry-After header: %s", retryAfter)
 }
 
-func (s *SolanaRPCClient) makeRPCCall(method string, params []interface{}) (*RPCResponse, error) {
+// defaultRPCTimeout bounds a single RPC attempt, so a node that accepts the
+// connection and never answers cannot hold a goroutine forever. Override with
+// SOLANA_RPC_TIMEOUT.
+const defaultRPCTimeout = 10 * time.Second
+
+// rpcMaxIdleConnsPerHost keeps enough connections open to reuse them under
+// the concurrent lookups of batch and portfolio requests; the default
+// transport keeps only two per host.
+const rpcMaxIdleConnsPerHost = 32
+
+func newRPCHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = rpcMaxIdleConnsPerHost
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func (s *SolanaRPCClient) makeRPCCall(ctx context.Context, method string, params []interface{}) (*RPCResponse, error) {
+	if err := s.waitTokenBucket(ctx, method, 1); err != nil {
+		return nil, err
+	}
+
+	var resp *RPCResponse
+	err := s.withFailover(ctx, method, func(endpoint *rpcEndpoint) error {
+		started := time.Now()
+		var err error
+		resp, err = postRPC(ctx, s.httpClient, endpoint.url, method, params)
+		observeRPC(method, started, resp, err)
+		return err
+	})
+	return resp, err
+}
+
+// postRPC sends a single JSON-RPC request. Transport errors and 5xx responses
+// are returned as errors; a 429 is returned as an RPC error with code 429.
+func postRPC(ctx context.Context, httpClient *http.Client, endpointURL, method string, params []interface{}) (*RPCResponse, error) {
 	payload := map[string]interface{}{
 		"jsonrpc": "2.0",
 		"id":      1,
@@ -172,60 +537,114 @@ func (s *SolanaRPCClient) makeRPCCall(method string, params []interface{}) (*RPC
 		return nil, err
 	}
 
-	resp, err := http.Post(s.URL, "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 500 {
+		return nil, fmt.Errorf("%s: RPC endpoint returned HTTP %d", method, resp.StatusCode)
+	}
+
 	if resp.StatusCode == 429 {
+		// The Retry-After value is passed on in the error and logged by the
+		// retry path.
 		retryAfter := resp.Header.Get("Retry-After")
-		if retryAfter != "" {
-			if duration, err := parseRetryAfter(retryAfter); err == nil {
-				log.Printf("Rate limited by server. Retry-After: %s (parsed as %v)", retryAfter, duration)
-			} else {
-				log.Printf("Rate limited by server. Retry-After: %s (parse failed: %v)", retryAfter, err)
-			}
-		} else {
-			log.Printf("Rate limited by server. No Retry-After header provided")
-		}
-
 		var rpcResp RPCResponse
-		if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err == nil {
-			if errorMap, ok := rpcResp.Error.(map[string]interface{}); ok && retryAfter != "" {
-				errorMap["retryAfter"] = retryAfter
-			}
+		_ = json.NewDecoder(resp.Body).Decode(&rpcResp)
+		errorMap, ok := rpcResp.Error.(map[string]interface{})
+		if !ok {
+			errorMap = map[string]interface{}{"code": float64(429), "message": "Too Many Requests"}
+			rpcResp.Error = errorMap
+		}
+		if retryAfter != "" {
+			errorMap["retryAfter"] = retryAfter
 		}
 		return &rpcResp, nil
 	}
 
 	var rpcResp RPCResponse
-	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+	if err := decodeRPCResponse(resp.Body, &rpcResp); err != nil {
 		return nil, err
 	}
 
 	return &rpcResp, nil
 }
 
-func (s *SolanaRPCClient) makeRPCCallWithRetry(method string, params []interface{}) (*RPCResponse, error) {
-	maxRetries := 3
-	baseDelay := 1 * time.Second
+// makeRPCCallWithRetry sends every attempt through makeRPCCall, which waits
+// on ctx for a token from the global RPC_RATE bucket, or the method's own
+// RPC_METHOD_RATES bucket, so the configured budget is what paces calls; the
+// adaptive limiter only adds spacing to methods the provider has throttled.
+// It gives up as soon as ctx is done, including while it waits for the rate
+// limiter or between attempts, and returns ctx.Err().
+// The waits of one call add up to at most maxTotalWait: a call that would
+// wait longer fails as rate limited, or with its last error when it was
+// backing off from one, instead of holding its request for minutes.
+func (s *SolanaRPCClient) makeRPCCallWithRetry(ctx context.Context, method string, params []interface{}) (*RPCResponse, error) {
+	policy := s.retryPolicyFor(method)
+	maxRetries := 1
+	if policy.Retryable {
+		maxRetries += policy.MaxRetries
+	}
+	baseDelay := policy.BaseDelay
+
+	var slept time.Duration
+	defer func() {
+		if slept > 0 {
+			addRequestWait(ctx, slept)
+			s.retryLog.record(method, func(c *retryCounts) { c.Slept += slept })
+			s.retryLog.debugf(ctx, "%s waited %v in total", method, slept)
+		}
+	}()
+	withinBudget := func(d time.Duration) bool {
+		if s.maxTotalWait > 0 && slept+d > s.maxTotalWait {
+			s.retryLog.record(method, func(c *retryCounts) { c.WaitExceeded++ })
+			s.retryLog.debugf(ctx, "%s would wait %v more after %v, giving up", method, d, slept)
+			return false
+		}
+		return true
+	}
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
-		if !s.checkRateLimit(method) {
-			time.Sleep(2 * time.Second)
-			continue
+		// A throttled method's slot is only booked if it fits what is left
+		// of the wait budget; otherwise the caller waits for it on ctx.
+		maxWait := time.Duration(-1)
+		if s.maxTotalWait > 0 {
+			maxWait = max(s.maxTotalWait-slept, 0)
+		}
+		wait, ok := s.rateLimiter.reserve(method, maxWait)
+		if !ok || !withinBudget(wait) {
+			return nil, &RateLimitError{Method: method, RetryAfter: wait}
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
 		}
+		slept += wait
 
-		s.updateRateLimit(method)
-		resp, err := s.makeRPCCall(method, params)
+		resp, err := s.makeRPCCall(ctx, method, params)
 
 		if err != nil {
-			if attempt == maxRetries-1 {
-				return nil, err
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
 			}
 			delay := time.Duration(float64(baseDelay) * math.Pow(2, float64(attempt)))
-			time.Sleep(delay)
+			if attempt == maxRetries-1 || !withinBudget(delay) {
+				s.retryLog.record(method, func(c *retryCounts) { c.Errors++; c.Exhausted++ })
+				return nil, err
+			}
+			s.retryLog.record(method, func(c *retryCounts) { c.Errors++; c.Retries++ })
+			s.retryLog.debugf(ctx, "%s failed, retrying in %v (attempt %d/%d): %v", method, delay, attempt+1, maxRetries, err)
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			slept += delay
 			continue
 		}
 
@@ -233,95 +652,101 @@ func (s *SolanaRPCClient) makeRPCCallWithRetry(method string, params []interface
 			if errorMap, ok := resp.Error.(map[string]interface{}); ok {
 				if code, exists := errorMap["code"]; exists && code == float64(429) {
 					if attempt == maxRetries-1 {
-						return resp, nil
+						s.retryLog.record(method, func(c *retryCounts) { c.RateLimited++; c.Exhausted++ })
+						s.rateLimiter.throttled(method, 0)
+						return nil, s.rpcError(method, resp.Error)
 					}
 
 					var delay time.Duration
 					if retryAfter, hasRetryAfter := errorMap["retryAfter"].(string); hasRetryAfter {
-						if parsedDelay, err := parseRetryAfter(retryAfter); err == nil {
+						if parsedDelay, err := parseRetryAfter(retryAfter, s.minRetryAfter); err == nil {
 							delay = parsedDelay
-							log.Printf("Using server-specified Retry-After: %v (attempt %d/%d)", delay, attempt+1, maxRetries)
+							s.retryLog.debugf(ctx, "Using server-specified Retry-After: %v (attempt %d/%d)", delay, attempt+1, maxRetries)
 						} else {
 							delay = time.Duration(float64(baseDelay) * math.Pow(2, float64(attempt+1)))
-							log.Printf("Failed to parse Retry-After header (%v), using exponential backoff: %v (attempt %d/%d)", err, delay, attempt+1, maxRetries)
+							s.retryLog.debugf(ctx, "Failed to parse Retry-After header (%v), using exponential backoff: %v (attempt %d/%d)", err, delay, attempt+1, maxRetries)
 						}
 					} else {
 						delay = time.Duration(float64(baseDelay) * math.Pow(2, float64(attempt+1)))
-						log.Printf("No Retry-After header, using exponential backoff: %v (attempt %d/%d)", delay, attempt+1, maxRetries)
+						s.retryLog.debugf(ctx, "No Retry-After header, using exponential backoff: %v (attempt %d/%d)", delay, attempt+1, maxRetries)
 					}
 
-					time.Sleep(delay)
+					// The limiter holds back the next attempt, and every other
+					// caller of this method, until the delay has passed.
+					s.rateLimiter.throttled(method, delay)
+					s.retryLog.record(method, func(c *retryCounts) { c.RateLimited++; c.Retries++ })
 					continue
 				}
 			}
 		}
 
+		s.rateLimiter.succeeded(method)
 		return resp, nil
 	}
 
-	return nil, fmt.Errorf("max retries exceeded")
+	return nil, &RateLimitError{Method: method}
 }
 
-func (s *SolanaRPCClient) GetSlot() (uint64, error) {
-	resp, err := s.makeRPCCall("getSlot", []interface{}{})
+func (s *SolanaRPCClient) GetSlot(ctx context.Context) (uint64, error) {
+	resp, err := s.makeRPCCallWithRetry(ctx, "getSlot", []interface{}{})
 	if err != nil {
 		return 0, err
 	}
 
-	slot, ok := resp.Result.(float64)
+	slot, ok := parseUint64Result(resp.Result)
 	if !ok {
-		return 0, fmt.Errorf("invalid slot response")
+		return 0, &ParseError{Method: "getSlot", Detail: "result is not a number"}
 	}
 
-	return uint64(slot), nil
+	return slot, nil
 }
 
-func (s *SolanaRPCClient) GetEpochInfo() (map[string]interface{}, error) {
-	resp, err := s.makeRPCCall("getEpochInfo", []interface{}{})
+func (s *SolanaRPCClient) GetEpochInfo(ctx context.Context) (map[string]interface{}, error) {
+	resp, err := s.makeRPCCallWithRetry(ctx, "getEpochInfo", []interface{}{})
 	if err != nil {
 		return nil, err
 	}
 
 	epochInfo, ok := resp.Result.(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("invalid epoch info response")
+		return nil, &ParseError{Method: "getEpochInfo", Detail: "result is not an object"}
 	}
 
 	return epochInfo, nil
 }
 
-func (s *SolanaRPCClient) GetValidatorCount() (int, error) {
-	resp, err := s.makeRPCCall("getVoteAccounts", []interface{}{})
+func (s *SolanaRPCClient) GetValidatorCount(ctx context.Context) (int, error) {
+	resp, err := s.makeRPCCallWithRetry(ctx, "getVoteAccounts", []interface{}{})
 	if err != nil {
 		return 0, err
 	}
 
 	voteAccounts, ok := resp.Result.(map[string]interface{})
 	if !ok {
-		return 0, fmt.Errorf("invalid vote accounts response")
+		return 0, &ParseError{Method: "getVoteAccounts", Detail: "result is not an object"}
 	}
 
 	current, ok := voteAccounts["current"].([]interface{})
 	if !ok {
-		return 0, fmt.Errorf("invalid current validators")
+		return 0, &ParseError{Method: "getVoteAccounts", Detail: "current is not an array"}
 	}
 
 	return len(current), nil
 }
 
-func (s *SolanaRPCClient) GetPerformanceSamples(limit int) ([]map[string]interface{}, error) {
+func (s *SolanaRPCClient) GetPerformanceSamples(ctx context.Context, limit int) ([]map[string]interface{}, error) {
 	params := []interface{}{limit}
-	resp, err := s.makeRPCCall("getRecentPerformanceSamples", params)
+	resp, err := s.makeRPCCallWithRetry(ctx, "getRecentPerformanceSamples", params)
 	if err != nil {
 		return nil, err
 	}
 
 	samples, ok := resp.Result.([]interface{})
 	if !ok {
-		return nil, fmt.Errorf("invalid performance samples response")
+		return nil, &ParseError{Method: "getRecentPerformanceSamples", Detail: "result is not an array"}
 	}
 
-	var result []map[string]interface{}
+	result := []map[string]interface{}{}
 	for _, sample := range samples {
 		if s, ok := sample.(map[string]interface{}); ok {
 			result = append(result, s)
@@ -331,21 +756,86 @@ func (s *SolanaRPCClient) GetPerformanceSamples(limit int) ([]map[string]interfa
 	return result, nil
 }
 
+// calculateTPS weights every sample by its period: total transactions over
+// total seconds. Samples with no period are skipped, and no usable samples
+// yield 0 rather than NaN.
 func calculateTPS(samples []map[string]interface{}) float64 {
-	if len(samples) == 0 {
-		return 0
+	var totalTransactions, totalSeconds float64
+	for _, sample := range samples {
+		numTransactions, ok := sample["numTransactions"].(float64)
+		if !ok {
+			continue
+		}
+		if samplePeriodSecs, ok := sample["samplePeriodSecs"].(float64); ok && samplePeriodSecs > 0 {
+			totalTransactions += numTransactions
+			totalSeconds += samplePeriodSecs
+		}
 	}
 
-	var totalTPS float64
+	return safeDivide(totalTransactions, totalSeconds)
+}
+
+// calculateNonVoteTPS is calculateTPS over numNonVoteTransactions, leaving
+// out the vote transactions that make up most of the total. Samples from
+// nodes too old to report it count their total instead.
+func calculateNonVoteTPS(samples []map[string]interface{}) float64 {
+	var totalTransactions, totalSeconds float64
 	for _, sample := range samples {
-		if numTransactions, ok := sample["numTransactions"].(float64); ok {
-			if samplePeriodSecs, ok := sample["samplePeriodSecs"].(float64); ok && samplePeriodSecs > 0 {
-				totalTPS += numTransactions / samplePeriodSecs
+		numTransactions, ok := sample["numNonVoteTransactions"].(float64)
+		if !ok {
+			if numTransactions, ok = sample["numTransactions"].(float64); !ok {
+				continue
 			}
 		}
+		if samplePeriodSecs, ok := sample["samplePeriodSecs"].(float64); ok && samplePeriodSecs > 0 {
+			totalTransactions += numTransactions
+			totalSeconds += samplePeriodSecs
+		}
+	}
+
+	return safeDivide(totalTransactions, totalSeconds)
+}
+
+// jsonSafeFloat replaces NaN and ±Inf, which encoding/json refuses to
+// marshal, with 0. Every ratio derived from RPC data goes through it so one
+// malformed response cannot make a whole payload unserializable.
+func jsonSafeFloat(value float64) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0
 	}
+	return value
+}
 
-	return totalTPS / float64(len(samples))
+// safeDivide returns numerator/denominator, or 0 when the denominator is not
+// positive or the result is not a finite number.
+func safeDivide(numerator, denominator float64) float64 {
+	if !(denominator > 0) {
+		return 0
+	}
+	return jsonSafeFloat(numerator / denominator)
+}
+
+// sampleRange returns the slots and wall-clock time covered by performance
+// samples. Each sample's slot is the last of the numSlots slots it spans.
+func sampleRange(samples []map[string]interface{}) (firstSlot, lastSlot uint64, coveredSeconds float64) {
+	for i, sample := range samples {
+		slot, _ := sample["slot"].(float64)
+		numSlots, _ := sample["numSlots"].(float64)
+		samplePeriodSecs, _ := sample["samplePeriodSecs"].(float64)
+
+		start := uint64(slot)
+		if numSlots >= 1 && uint64(numSlots) <= start {
+			start = start - uint64(numSlots) + 1
+		}
+		if i == 0 || start < firstSlot {
+			firstSlot = start
+		}
+		if uint64(slot) > lastSlot {
+			lastSlot = uint64(slot)
+		}
+		coveredSeconds += samplePeriodSecs
+	}
+	return firstSlot, lastSlot, jsonSafeFloat(coveredSeconds)
 }
 
 func (s *SolanaRPCClient) GetCachedBlockTime() float64 {
@@ -371,15 +861,25 @@ func (s *SolanaRPCClient) GetCachedBlockTime() float64 {
 	return 0.4
 }
 
+// updateBlockTimeInBackground refreshes lastBlockTime from block timestamps,
+// falling back to counting the slots produced in three seconds when the node
+// has no timestamps for the blocks, e.g. because one of the slots was
+// skipped.
 func (s *SolanaRPCClient) updateBlockTimeInBackground() {
-	currentSlot, err := s.GetSlot()
+	ctx := context.Background()
+	if blockTime, ok, _ := s.blockTimeFromTimestamps(ctx); ok {
+		s.setBlockTime(blockTime)
+		return
+	}
+
+	currentSlot, err := s.GetSlot(ctx)
 	if err != nil {
 		return
 	}
 
 	time.Sleep(3 * time.Second)
 
-	laterSlot, err := s.GetSlot()
+	laterSlot, err := s.GetSlot(ctx)
 	if err != nil {
 		return
 	}
@@ -393,6 +893,11 @@ func (s *SolanaRPCClient) updateBlockTimeInBackground() {
 
 	blockTime := timeDifference / slotDifference
 
+	s.setBlockTime(blockTime)
+}
+
+// setBlockTime stores a block time measurement, discarding implausible ones.
+func (s *SolanaRPCClient) setBlockTime(blockTime float64) {
 	if blockTime >= 0.1 && blockTime <= 2.0 {
 		s.mutex.Lock()
 		s.lastBlockTime = blockTime
@@ -402,54 +907,87 @@ func (s *SolanaRPCClient) updateBlockTimeInBackground() {
 	}
 }
 
-func (s *SolanaRPCClient) GetAccountInfo(address string) (*AccountInfo, error) {
+func (s *SolanaRPCClient) GetAccountInfo(ctx context.Context, address string) (*AccountInfo, error) {
+	return s.GetAccountInfoWithOptions(ctx, address, AccountInfoOptions{})
+}
+
+func (s *SolanaRPCClient) GetAccountInfoWithOptions(ctx context.Context, address string, opts AccountInfoOptions) (*AccountInfo, error) {
+	// Only pinned accounts are cached, and only without data options.
+	cacheKey := s.cacheKey("account_info", address)
+	summaryOnly := opts.DataEncoding == "" && !opts.JSONParsed
+	if summaryOnly {
+		if cached, found := s.getFromCache(ctx, cacheKey); found {
+			if info, ok := cached.(*AccountInfo); ok {
+				// Handlers decorate the result; hand out a copy.
+				copied := *info
+				return &copied, nil
+			}
+		}
+	}
+
 	params := []interface{}{address}
-	resp, err := s.makeRPCCall("getAccountInfo", params)
+	if opts.DataEncoding != "" {
+		params = append(params, s.accountDataConfig(opts))
+	} else if opts.JSONParsed {
+		params = append(params, map[string]interface{}{"encoding": "jsonParsed"})
+	}
+	resp, err := s.makeRPCCallWithRetry(ctx, "getAccountInfo", params)
 	if err != nil {
 		return nil, err
 	}
 
+	// Only a null value means the account does not exist; a failed or
+	// malformed lookup is an error, not a missing account.
 	if resp.Error != nil {
-		return &AccountInfo{
-			Address: address,
-			IsValid: false,
-		}, nil
+		return nil, s.rpcError("getAccountInfo", resp.Error)
 	}
 
-	if resp.Result == nil {
+	result, contextSlot, err := unwrapContext("getAccountInfo", resp.Result)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
 		return &AccountInfo{
 			Address: address,
 			IsValid: false,
 		}, nil
 	}
 
-	result, ok := resp.Result.(map[string]interface{})
+	value, ok := result.(map[string]interface{})
 	if !ok {
-		return &AccountInfo{
-			Address: address,
-			IsValid: false,
-		}, nil
+		return nil, &ParseError{Method: "getAccountInfo", Detail: "value is not an object"}
 	}
 
-	value, ok := result["value"].(map[string]interface{})
-	if !ok {
-		return &AccountInfo{
-			Address: address,
-			IsValid: false,
-		}, nil
+	accountInfo := parseAccountValue(address, value)
+	accountInfo.ContextSlot = BigUint(contextSlot)
+	accountInfo.rpcResults = map[string]interface{}{"getAccountInfo": resp.Result}
+
+	switch {
+	case opts.DataEncoding != "":
+		if err := s.attachAccountData(accountInfo, value["data"], opts); err != nil {
+			return nil, err
+		}
+	case opts.JSONParsed:
+		// Accounts the node cannot parse come back as base64 instead and
+		// get no ParsedData.
+		if data, ok := value["data"].(map[string]interface{}); ok {
+			accountInfo.ParsedData = data["parsed"]
+		}
+	default:
+		copied := *accountInfo
+		s.setPinnedCache(ctx, cacheKey, &copied)
 	}
 
+	return accountInfo, nil
+}
+
+// parseAccountValue converts an account object from getAccountInfo or
+// getMultipleAccounts into an AccountInfo.
+func parseAccountValue(address string, value map[string]interface{}) *AccountInfo {
 	lamports, _ := value["lamports"].(float64)
 	executable, _ := value["executable"].(bool)
 	owner, _ := value["owner"].(string)
-	rentEpoch, _ := value["rentEpoch"].(float64)
-
-	var dataLength int
-	if data, ok := value["data"].([]interface{}); ok && len(data) > 0 {
-		if dataStr, ok := data[0].(string); ok {
-			dataLength = len(dataStr)
-		}
-	}
+	rentEpoch, rentExempt := parseRentEpoch(value["rentEpoch"])
 
 	balance := lamports / 1e9
 
@@ -458,60 +996,107 @@ func (s *SolanaRPCClient) GetAccountInfo(address string) (*AccountInfo, error) {
 		Balance:    balance,
 		Executable: executable,
 		Owner:      owner,
-		RentEpoch:  uint64(rentEpoch),
-		Lamports:   uint64(lamports),
-		DataLength: dataLength,
+		RentEpoch:  rentEpoch,
+		RentExempt: rentExempt,
+		Lamports:   BigUint(lamports),
+		DataLength: accountDataLength(value),
 		IsValid:    true,
-	}, nil
+	}
+}
+
+// solBalance is a getBalance result: the balance in SOL and the slot it was
+// read at.
+type solBalance struct {
+	sol         float64
+	contextSlot uint64
+}
+
+func (s *SolanaRPCClient) GetBalance(ctx context.Context, address string) (float64, error) {
+	balance, _, err := s.GetBalanceWithSlot(ctx, address)
+	return balance, err
 }
 
-func (s *SolanaRPCClient) GetBalance(address string) (float64, error) {
+// GetBalanceWithSlot is GetBalance that also returns the slot the balance
+// was read at, or 0 if the node did not report one.
+func (s *SolanaRPCClient) GetBalanceWithSlot(ctx context.Context, address string) (float64, uint64, error) {
+	cacheKey := s.cacheKey("balance", address)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if balance, ok := cached.(solBalance); ok {
+			return balance.sol, balance.contextSlot, nil
+		}
+	}
+
 	params := []interface{}{address}
-	resp, err := s.makeRPCCall("getBalance", params)
+	resp, err := s.makeRPCCallWithRetry(ctx, "getBalance", params)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
+	}
+
+	balance, err := parseBalanceResponse(resp)
+	if err != nil {
+		return 0, 0, err
 	}
 
+	s.setPinnedCache(ctx, cacheKey, balance)
+
+	return balance.sol, balance.contextSlot, nil
+}
+
+// parseBalanceResponse returns the SOL balance in a getBalance response.
+func parseBalanceResponse(resp *RPCResponse) (solBalance, error) {
 	if resp.Error != nil {
-		return 0, fmt.Errorf("RPC error: %v", resp.Error)
+		return solBalance{}, fmt.Errorf("RPC error: %v", resp.Error)
 	}
 
-	result, ok := resp.Result.(map[string]interface{})
-	if !ok {
-		return 0, fmt.Errorf("invalid balance response")
+	value, contextSlot, err := unwrapContext("getBalance", resp.Result)
+	if err != nil {
+		return solBalance{}, err
 	}
 
-	value, ok := result["value"].(float64)
+	lamports, ok := parseUint64Result(value)
 	if !ok {
-		return 0, fmt.Errorf("invalid balance value")
+		return solBalance{}, &ParseError{Method: "getBalance", Detail: "value is not a number"}
 	}
 
-	return value / 1e9, nil
+	return solBalance{sol: float64(lamports) / 1e9, contextSlot: contextSlot}, nil
 }
 
-func (s *SolanaRPCClient) GetTokenSupply(mintAddress string) (*TokenInfo, error) {
+func (s *SolanaRPCClient) GetTokenSupply(ctx context.Context, mintAddress string) (*TokenInfo, error) {
+	cacheKey := s.cacheKey("token_supply", mintAddress)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if tokenInfo, ok := cached.(*TokenInfo); ok {
+			return tokenInfo, nil
+		}
+	}
+
 	params := []interface{}{mintAddress}
-	resp, err := s.makeRPCCall("getTokenSupply", params)
+	resp, err := s.makeRPCCallWithRetry(ctx, "getTokenSupply", params)
 	if err != nil {
 		return nil, err
 	}
 
+	// The node rejects wallets, token accounts and missing accounts alike
+	// with invalid params; any other error leaves the reason unknown.
 	if resp.Error != nil {
-		return &TokenInfo{
+		tokenInfo := &TokenInfo{
 			MintAddress: mintAddress,
 			IsValid:     false,
-		}, nil
+		}
+		if isInvalidParams(resp.Error) {
+			tokenInfo.Reason = notAMintReason
+		}
+		return tokenInfo, nil
 	}
 
-	result, ok := resp.Result.(map[string]interface{})
-	if !ok {
+	result, contextSlot, err := unwrapContext("getTokenSupply", resp.Result)
+	if err != nil {
 		return &TokenInfo{
 			MintAddress: mintAddress,
 			IsValid:     false,
 		}, nil
 	}
 
-	value, ok := result["value"].(map[string]interface{})
+	value, ok := result.(map[string]interface{})
 	if !ok {
 		return &TokenInfo{
 			MintAddress: mintAddress,
@@ -527,66 +1112,65 @@ func (s *SolanaRPCClient) GetTokenSupply(mintAddress string) (*TokenInfo, error)
 		supply = 0
 	}
 
-	actualSupply := float64(supply) / math.Pow(10, decimals)
+	actualSupply := safeDivide(float64(supply), math.Pow(10, decimals))
 
 	tokenInfo := &TokenInfo{
 		MintAddress:  mintAddress,
-		Supply:       supply,
+		Supply:       BigUint(supply),
 		Decimals:     int(decimals),
 		ActualSupply: actualSupply,
 		IsValid:      true,
+		ContextSlot:  BigUint(contextSlot),
+		rpcResults:   map[string]interface{}{"getTokenSupply": resp.Result},
 	}
 
-	mintAccountInfo, err := s.GetAccountInfo(mintAddress)
+	mintAccountInfo, err := s.GetAccountInfo(ctx, mintAddress)
 	if err == nil && mintAccountInfo.IsValid {
 		tokenInfo.IsInitialized = true
+		tokenInfo.rpcResults["getAccountInfo"] = mintAccountInfo.rpcResults["getAccountInfo"]
 	}
 
+	s.setPinnedCache(ctx, cacheKey, tokenInfo)
+
 	return tokenInfo, nil
 }
 
-func (s *SolanaRPCClient) GetTokenAccountsByMint(mintAddress string, limit int) ([]map[string]interface{}, error) {
+// GetTokenAccountsByMint returns up to limit of the mint's largest token
+// accounts. getTokenLargestAccounts always returns the top 20, so they are
+// cached once per mint and every limit is served from that entry.
+func (s *SolanaRPCClient) GetTokenAccountsByMint(ctx context.Context, mintAddress string, limit int) ([]map[string]interface{}, error) {
 	// Check cache first
-	cacheKey := fmt.Sprintf("token_holders_%s_%d", mintAddress, limit)
-	if cached, found := s.getFromCache(cacheKey); found {
+	cacheKey := s.cacheKey("token_holders", mintAddress)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
 		if holders, ok := cached.([]map[string]interface{}); ok {
 			log.Printf("Returning cached token holders for %s", mintAddress)
-			return holders, nil
+			return firstN(holders, limit), nil
 		}
 	}
 
-	if !s.checkRateLimit("getTokenLargestAccounts") {
-		log.Printf("Rate limited, returning empty holders list for %s", mintAddress)
-		return []map[string]interface{}{}, nil
-	}
-
 	params := []interface{}{mintAddress}
-	resp, err := s.makeRPCCallWithRetry("getTokenLargestAccounts", params)
+	resp, err := s.makeRPCCallWithRetry(ctx, "getTokenLargestAccounts", params)
 	if err != nil {
-		log.Printf("Failed to get token holders after retries: %v", err)
-		return []map[string]interface{}{}, nil
+		return nil, err
 	}
 
 	if resp.Error != nil {
-		log.Printf("RPC error getting token holders: %v", resp.Error)
-		return []map[string]interface{}{}, nil
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
 	}
 
-	result, ok := resp.Result.(map[string]interface{})
-	if !ok {
-		log.Printf("Invalid response format for token holders")
-		return []map[string]interface{}{}, nil
+	result, _, err := unwrapContext("getTokenLargestAccounts", resp.Result)
+	if err != nil {
+		return nil, err
 	}
 
-	value, ok := result["value"].([]interface{})
+	value, ok := result.([]interface{})
 	if !ok {
-		log.Printf("Invalid value format for token holders")
-		return []map[string]interface{}{}, nil
+		return nil, &ParseError{Method: "getTokenLargestAccounts", Detail: "value is not an array"}
 	}
 
-	var tokenHolders []map[string]interface{}
+	tokenHolders := []map[string]interface{}{}
 	for i, account := range value {
-		if i >= limit {
+		if i >= largestAccountsLimit {
 			break
 		}
 		if accountMap, ok := account.(map[string]interface{}); ok {
@@ -603,9 +1187,20 @@ func (s *SolanaRPCClient) GetTokenAccountsByMint(mintAddress string, limit int)
 		}
 	}
 
-	s.setCache(cacheKey, tokenHolders, 5*time.Minute)
+	s.setCache(ctx, cacheKey, tokenHolders, 5*time.Minute)
+
+	return firstN(tokenHolders, limit), nil
+}
 
-	return tokenHolders, nil
+// newRouter returns a Gin engine with the route matching options. Trailing
+// slashes are redirected away by default. Case-insensitive matching
+// redirects to the registered route's casing; Gin only folds the static path
+// segments, so base58 parameters keep their case.
+func newRouter(redirectTrailingSlash, caseInsensitiveRoutes bool) *gin.Engine {
+	r := gin.New()
+	r.RedirectTrailingSlash = redirectTrailingSlash
+	r.RedirectFixedPath = caseInsensitiveRoutes
+	return r
 }
 
 func main() {
@@ -618,92 +1213,363 @@ func main() {
 	if solanaURL == "" {
 		solanaURL = "https://api.mainnet-beta.solana.com"
 	}
+	// SOLANA_RPC_URLS lists every configured endpoint, primary first; the
+	// others take over in order when it fails. It replaces SOLANA_RPC_URL.
+	rpcURLs := parseCommaList(os.Getenv("SOLANA_RPC_URLS"))
+	if len(rpcURLs) == 0 {
+		rpcURLs = []string{solanaURL}
+	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	client := NewSolanaClient(solanaURL)
-	r := gin.Default()
+	client := NewSolanaClient(rpcURLs...)
+	if threshold, err := strconv.Atoi(os.Getenv("RPC_FAILOVER_THRESHOLD")); err == nil && threshold > 0 {
+		client.failoverThreshold = threshold
+	}
+	if cooldown, err := time.ParseDuration(os.Getenv("RPC_FAILOVER_COOLDOWN")); err == nil && cooldown >= 0 {
+		client.failoverCooldown = cooldown
+	}
+	if strategy := os.Getenv("LB_STRATEGY"); strategy != "" {
+		if validLBStrategies[strategy] {
+			client.lbStrategy = strategy
+		} else {
+			log.Printf("Invalid LB_STRATEGY %q, using %q", strategy, client.lbStrategy)
+		}
+	}
+	if heavyURL := os.Getenv("SOLANA_RPC_URL_HEAVY"); heavyURL != "" {
+		client.heavyEndpoint = newRPCEndpoint(heavyURL)
+		heavyMethods := defaultHeavyMethods
+		if raw, ok := os.LookupEnv("RPC_HEAVY_METHODS"); ok {
+			heavyMethods = parseCommaList(raw)
+		}
+		client.heavyMethods = make(map[string]bool, len(heavyMethods))
+		for _, method := range heavyMethods {
+			client.heavyMethods[method] = true
+		}
+	}
+	if raw := os.Getenv("RPC_MAX_CONCURRENCY"); raw != "" {
+		var limits []int
+		for _, entry := range parseCommaList(raw) {
+			limit, err := strconv.Atoi(entry)
+			if err != nil || limit < 0 {
+				log.Printf("Ignoring invalid RPC_MAX_CONCURRENCY entry %q, leaving that endpoint uncapped", entry)
+				limit = 0
+			}
+			limits = append(limits, limit)
+		}
+		if len(limits) > 0 {
+			client.setEndpointConcurrency(limits)
+		}
+	}
+	if os.Getenv("AUTO_DISCOVER_RPC") == "true" {
+		maxPool := defaultDiscoveryMaxPool
+		if size, err := strconv.Atoi(os.Getenv("RPC_DISCOVERY_MAX")); err == nil && size > 0 {
+			maxPool = size
+		}
+		interval := defaultDiscoveryInterval
+		if parsed, err := time.ParseDuration(os.Getenv("RPC_DISCOVERY_INTERVAL")); err == nil && parsed > 0 {
+			interval = parsed
+		}
+		client.discovery = newRPCDiscovery(client, maxPool, interval, os.Getenv("RPC_DISCOVERY_ALLOW_PRIVATE") == "true")
+		go client.discovery.run()
+	}
+	client.holderDenylist = parseCommaList(os.Getenv("HOLDER_EXCLUDE_ADDRESSES"))
+	if maxData, err := strconv.Atoi(os.Getenv("MAX_ACCOUNT_DATA_BYTES")); err == nil && maxData > 0 {
+		client.maxAccountData = maxData
+	}
+	strictRPCResults = os.Getenv("STRICT_RPC_RESULTS") == "true"
+	if mode := os.Getenv("RENT_EPOCH_SENTINEL"); mode != "" {
+		if validRentEpochModes[mode] {
+			rentEpochSentinel = mode
+		} else {
+			log.Printf("Invalid RENT_EPOCH_SENTINEL %q, using %q", mode, rentEpochSentinel)
+		}
+	}
+	if staleness, err := time.ParseDuration(os.Getenv("MAX_CACHE_STALENESS")); err == nil && staleness >= 0 {
+		client.maxCacheStaleness = staleness
+	}
+	if maxEntries, err := strconv.Atoi(os.Getenv("CACHE_MAX_ENTRIES")); err == nil && maxEntries >= 0 {
+		client.cacheMaxEntries = maxEntries
+	}
+	switch source := os.Getenv("SOL_PRICE_SOURCE"); source {
+	case "", priceSourceCoinGecko, priceSourcePyth:
+		client.priceSource = source
+	default:
+		log.Printf("Unknown SOL_PRICE_SOURCE %q, SOL pricing disabled", source)
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("SOL_PRICE_CACHE_TTL")); err == nil && ttl > 0 {
+		client.priceCacheTTL = ttl
+	}
+	if account := os.Getenv("PYTH_SOL_USD_ACCOUNT"); account != "" {
+		client.pythPriceAccount = account
+	}
+	switch source := os.Getenv("TOKEN_PRICE_SOURCE"); source {
+	case "", tokenPriceSourceJupiter:
+		client.tokenPriceSource = source
+	default:
+		log.Printf("Unknown TOKEN_PRICE_SOURCE %q, token pricing disabled", source)
+	}
+	if priceURL := os.Getenv("TOKEN_PRICE_URL"); priceURL != "" {
+		client.tokenPriceURL = priceURL
+	}
+	if concurrency, err := strconv.Atoi(os.Getenv("ACCOUNTS_CHUNK_CONCURRENCY")); err == nil && concurrency > 0 {
+		client.accountsChunkConcurrency = concurrency
+	}
+	if samples, err := strconv.Atoi(os.Getenv("METRICS_TPS_SAMPLES")); err == nil && samples > 0 && samples <= maxPerformanceSamples {
+		client.metricsSampleCount = samples
+	}
+	if limit, err := strconv.Atoi(os.Getenv("PROGRAM_ACCOUNTS_MAX")); err == nil && limit > 0 {
+		client.maxProgramAccounts = limit
+	}
+	if blocks, err := strconv.ParseFloat(os.Getenv("SLOT_CACHE_BLOCKS"), 64); err == nil && blocks > 0 {
+		client.slotCacheBlocks = blocks
+	}
+	client.tpsCrossCheck = os.Getenv("METRICS_TPS_CROSS_CHECK") == "true"
+	client.consensusMetrics = os.Getenv("CONSENSUS_METRICS") == "true"
+	if tolerance, err := strconv.ParseUint(os.Getenv("CONSENSUS_SLOT_TOLERANCE"), 10, 64); err == nil {
+		client.consensusSlotTolerance = tolerance
+	}
+	if client.consensusMetrics && len(client.endpoints) == 1 && client.heavyEndpoint == nil {
+		log.Printf("CONSENSUS_METRICS is on but only one RPC endpoint is configured; list more in SOLANA_RPC_URLS")
+	}
+	if wait, err := time.ParseDuration(os.Getenv("METRICS_WAIT_TIMEOUT")); err == nil && wait >= 0 {
+		client.metricsWait = wait
+	}
+	if timeout, err := time.ParseDuration(os.Getenv("TOKEN_METADATA_TIMEOUT")); err == nil && timeout > 0 {
+		client.metadataHTTPClient = newMetadataHTTPClient(timeout)
+	}
+	if timeout, err := time.ParseDuration(os.Getenv("SOLANA_RPC_TIMEOUT")); err == nil && timeout > 0 {
+		client.httpClient = newRPCHTTPClient(timeout)
+	}
+	client.zstdAccountData = os.Getenv("RPC_ZSTD_ACCOUNT_DATA") == "true"
+	client.neverCacheKinds = newNeverCacheKinds(parseCommaList(os.Getenv("NEVER_CACHE")))
+	if raw := os.Getenv("RPC_RETRY_POLICY"); raw != "" {
+		if policies, err := parseRetryPolicies(raw); err != nil {
+			log.Printf("Invalid RPC_RETRY_POLICY, using default retry policies: %v", err)
+		} else {
+			client.retryPolicies = policies
+		}
+	}
+	if wait, err := time.ParseDuration(os.Getenv("RPC_MAX_TOTAL_WAIT")); err == nil && wait >= 0 {
+		client.maxTotalWait = wait
+	}
+	if floor, err := time.ParseDuration(os.Getenv("RETRY_AFTER_MIN")); err == nil && floor >= 0 {
+		client.minRetryAfter = floor
+	}
+	// RPC_RATE=0 turns the global bucket off; a rate without RPC_BURST gets
+	// the burst newTokenBucket derives from it.
+	perSecond, burst := float64(defaultRPCRate), defaultRPCBurst
+	if parsed, err := strconv.ParseFloat(os.Getenv("RPC_RATE"), 64); err == nil && parsed >= 0 {
+		perSecond, burst = parsed, 0
+	}
+	if parsed, err := strconv.Atoi(os.Getenv("RPC_BURST")); err == nil && parsed > 0 {
+		burst = parsed
+	}
+	client.globalRateLimiter = nil
+	if perSecond > 0 {
+		client.globalRateLimiter = newTokenBucket(perSecond, burst)
+	}
+	if raw := os.Getenv("RPC_METHOD_RATES"); raw != "" {
+		if limiters, err := parseMethodRates(raw); err != nil {
+			log.Printf("Ignoring RPC_METHOD_RATES: %v", err)
+		} else {
+			client.methodRateLimiters = limiters
+		}
+	}
+	if dust, err := strconv.ParseFloat(os.Getenv("PORTFOLIO_DUST_USD"), 64); err == nil && dust >= 0 {
+		client.portfolioDustUSD = dust
+	}
+	idls := map[string]*anchorIDL{}
+	if dir := os.Getenv("ANCHOR_IDL_DIR"); dir != "" {
+		if loaded, err := loadIDLDir(dir); err != nil {
+			log.Printf("Failed to load IDLs from %s: %v", dir, err)
+		} else {
+			idls = loaded
+		}
+	}
+	client.pinCache(parseCommaList(os.Getenv("PINNED_MINTS")), parseCommaList(os.Getenv("PINNED_ACCOUNTS")))
+	go client.loadEpochSchedule()
+
+	maxWatches := defaultMaxWatches
+	if max, err := strconv.Atoi(os.Getenv("WATCH_MAX")); err == nil && max > 0 {
+		maxWatches = max
+	}
+	watchPollInterval := defaultWatchPollInterval
+	if interval, err := time.ParseDuration(os.Getenv("WATCH_POLL_INTERVAL")); err == nil && interval > 0 {
+		watchPollInterval = interval
+	}
+	// The flag list is optional; a nil list flags nothing.
+	var flags *flagList
+	if source := os.Getenv("FLAGGED_ADDRESSES_SOURCE"); source != "" {
+		flags = newFlagList(source)
+		if err := flags.load(); err != nil {
+			log.Printf("Failed to load flagged addresses: %v", err)
+		}
+		flagReload := defaultFlagReloadInterval
+		if interval, err := time.ParseDuration(os.Getenv("FLAGGED_ADDRESSES_RELOAD")); err == nil && interval > 0 {
+			flagReload = interval
+		}
+		go flags.run(flagReload)
+	}
+
+	watches := newWatchRegistry(client, maxWatches, watchPollInterval, os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true")
+	go watches.run()
+
+	logSkipPaths := []string{"/api/health", "/metrics"}
+	if raw, ok := os.LookupEnv("LOG_SKIP_PATHS"); ok {
+		logSkipPaths = parseCommaList(raw)
+	}
+
+	debugMode := os.Getenv("DEBUG") == "true"
+
+	// Per-attempt retry lines are debug output; otherwise retries are only
+	// reported in the periodic summary. RETRY_LOG_INTERVAL=0 disables it.
+	client.retryLog.verbose = debugMode
+	retryLogInterval := defaultRetryLogInterval
+	if interval, err := time.ParseDuration(os.Getenv("RETRY_LOG_INTERVAL")); err == nil && interval >= 0 {
+		retryLogInterval = interval
+	}
+	if retryLogInterval > 0 {
+		go client.retryLog.run(retryLogInterval)
+	}
 
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+	requestTimeout := defaultRequestTimeout
+	if timeout, err := time.ParseDuration(os.Getenv("REQUEST_TIMEOUT")); err == nil && timeout >= 0 {
+		requestTimeout = timeout
+	}
+
+	r := newRouter(os.Getenv("REDIRECT_TRAILING_SLASH") != "false", os.Getenv("CASE_INSENSITIVE_ROUTES") == "true")
+	// BIG_INTS_AS_STRINGS predates versioning: it makes the string encoding
+	// the default, while clients can still ask for version 1.
+	defaultAPIVersion := stableAPIVersion
+	if os.Getenv("BIG_INTS_AS_STRINGS") == "true" {
+		defaultAPIVersion = bigIntStringsVersion
+	}
+	if raw := os.Getenv("API_DEFAULT_VERSION"); raw != "" {
+		if version, ok := parseAPIVersion(raw); ok {
+			defaultAPIVersion = version
+		} else {
+			log.Printf("Unsupported API_DEFAULT_VERSION %q, using %d", raw, defaultAPIVersion)
+		}
+	}
+	adminKey := os.Getenv("ADMIN_API_KEY")
+	r.Use(
+		requestIDMiddleware(),
+		prometheusMiddleware(),
+		gin.LoggerWithConfig(gin.LoggerConfig{SkipPaths: logSkipPaths, Formatter: accessLogFormatter}),
+		recoveryMiddleware(debugMode),
+		retryWaitMiddleware(debugMode),
+		apiVersionMiddleware(defaultAPIVersion),
+		timeoutMiddleware(requestTimeout),
+		endpointOverrideMiddleware(client, adminKey),
+	)
+
+	defaultOrigins := []string{"http://localhost:3000"}
+	corsConfig := cors.Config{
+		AllowOrigins: defaultOrigins,
+		AllowMethods: []string{"GET", "POST", "PUT", "DELETE"},
+		// Every request header the API reads is allowed so browser clients
+		// can use the admin, idempotency and request ID features.
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Admin-Key", "Idempotency-Key", requestIDHeader, acceptVersionHeader},
+		ExposeHeaders:    []string{"Content-Length", requestIDHeader, "Retry-After", apiVersionHeader, rpcEndpointHeader, serverTimingHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
-	}))
+	}
+	if origins := parseCommaList(os.Getenv("CORS_ALLOWED_ORIGINS")); len(origins) > 0 {
+		corsConfig.AllowOrigins = origins
+		if err := corsConfig.Validate(); err != nil {
+			log.Printf("Invalid CORS_ALLOWED_ORIGINS, using %v: %v", defaultOrigins, err)
+			corsConfig.AllowOrigins = defaultOrigins
+		}
+	}
+	if methods := parseCommaList(os.Getenv("CORS_ALLOWED_METHODS")); len(methods) > 0 {
+		corsConfig.AllowMethods = methods
+	}
+	corsConfig.AllowHeaders = append(corsConfig.AllowHeaders, parseCommaList(os.Getenv("CORS_ALLOWED_HEADERS"))...)
+	if maxAge, err := time.ParseDuration(os.Getenv("CORS_MAX_AGE")); err == nil && maxAge >= 0 {
+		corsConfig.MaxAge = maxAge
+	}
+	allowedOrigins := corsConfig.AllowOrigins
+	r.Use(cors.New(corsConfig))
 
 	r.GET("/api/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok", "timestamp": time.Now()})
+		respondJSON(c, http.StatusOK, gin.H{"status": "ok", "timestamp": time.Now()})
 	})
 
-	r.GET("/api/metrics", func(c *gin.Context) {
-		slot, err := client.GetSlot()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get slot"})
-			return
-		}
+	r.GET("/api/health/endpoints", handleEndpointHealth(client))
 
-		epochInfo, err := client.GetEpochInfo()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get epoch info"})
-			return
-		}
+	r.GET("/api/slot", handleSlot(client))
 
-		validatorCount, err := client.GetValidatorCount()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get validator count"})
-			return
-		}
+	r.GET("/api/cache/stats", handleCacheStats(client))
+
+	r.GET("/metrics", handlePrometheusMetrics())
+
+	capabilities := newCapabilities(client, idls)
+	capabilities.APIVersion = defaultAPIVersion
+	capabilities.Features.BigIntsAsStrings = defaultAPIVersion >= bigIntStringsVersion
+	capabilities.Features.AdminEndpoints = adminKey != ""
+	capabilities.Features.RawResults = debugMode
+	capabilities.Features.FlaggedAddresses = flags != nil
+	capabilities.Limits.RequestTimeoutSeconds = requestTimeout.Seconds()
+	capabilities.Limits.MaxWatches = maxWatches
+	capabilities.Limits.WatchPollIntervalSeconds = watchPollInterval.Seconds()
+	r.GET("/api/capabilities", handleCapabilities(client, capabilities))
 
-		samples, err := client.GetPerformanceSamples(150)
+	admin := r.Group("/api/admin", adminAuthMiddleware(adminKey))
+	admin.GET("/throttle", handleThrottleState(client))
+	admin.POST("/rpc/discover", handleDiscoverRPC(client))
+
+	r.GET("/api/metrics", func(c *gin.Context) {
+		metrics, err := client.sharedMetrics(c.Request.Context(), client.metricsWait)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get performance samples"})
+			if errors.Is(err, errMetricsPending) {
+				c.Header("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(client.metricsWait.Seconds())))))
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Metrics are still being fetched from the RPC node, retry shortly"})
+				return
+			}
+			var metricsErr *MetricsError
+			if errors.As(err, &metricsErr) {
+				if metricsErr.TooStale {
+					c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("Failed to refresh %s and cached data exceeds the maximum staleness", metricsErr.Metric)})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get %s", metricsErr.Metric)})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get metrics"})
 			return
 		}
 
-		tps := calculateTPS(samples)
-		avgBlockTime := client.GetCachedBlockTime()
+		respondJSON(c, http.StatusOK, metrics)
+	})
 
-		epoch, _ := epochInfo["epoch"].(float64)
-		slotIndex, _ := epochInfo["slotIndex"].(float64)
-		slotsInEpoch, _ := epochInfo["slotsInEpoch"].(float64)
+	wsSendBuffer := defaultWSSendBuffer
+	if size, err := strconv.Atoi(os.Getenv("WS_SEND_BUFFER")); err == nil && size > 0 {
+		wsSendBuffer = size
+	}
+	disconnectSlow := false
+	switch policy := os.Getenv("WS_SLOW_CLIENT_POLICY"); policy {
+	case "", "drop":
+	case "disconnect":
+		disconnectSlow = true
+	default:
+		log.Printf("Unknown WS_SLOW_CLIENT_POLICY %q, dropping updates for slow clients", policy)
+	}
+	metricsHub := newMetricsHub(client, wsSendBuffer, disconnectSlow)
+	r.GET("/ws/metrics", handleMetricsStream(metricsHub, allowedOrigins))
+	r.GET("/api/metrics/stream", handleMetricsEvents(metricsHub))
+	r.GET("/api/sse/metrics", handleMetricsEvents(metricsHub))
 
-		var epochProgress float64
-		if slotsInEpoch > 0 {
-			epochProgress = (slotIndex / slotsInEpoch) * 100
-		}
+	r.GET("/api/epoch/:number", handleEpochDetails(client))
 
-		var networkHealth string
-		if tps > 100 && validatorCount > 1000 {
-			networkHealth = "Healthy"
-		} else if tps > 50 && validatorCount > 500 {
-			networkHealth = "Good"
-		} else if tps > 10 {
-			networkHealth = "Fair"
-		} else {
-			networkHealth = "Poor"
-		}
+	r.GET("/api/stake/:address/estimate", handleStakeEstimate(client))
 
-		metrics := SolanaMetrics{
-			TPS:              tps,
-			AverageBlockTime: avgBlockTime,
-			CurrentSlot:      slot,
-			Epoch:            uint64(epoch),
-			ValidatorCount:   validatorCount,
-			Timestamp:        time.Now(),
-			EpochProgress:    epochProgress,
-			SlotsInEpoch:     uint64(slotsInEpoch),
-			SlotIndex:        uint64(slotIndex),
-			NetworkHealth:    networkHealth,
-			ConnectionStatus: "Connected",
-		}
+	r.GET("/api/fee-governor", handleFeeGovernor(client))
 
-		c.JSON(http.StatusOK, metrics)
-	})
+	r.GET("/api/price/sol", handleSOLPrice(client))
 
 	r.GET("/api/performance", func(c *gin.Context) {
 		timeRange := c.DefaultQuery("timeRange", "20m")
@@ -736,21 +1602,28 @@ func main() {
 			limit = 360
 		}
 
-		cacheKey := fmt.Sprintf("performance_%s_%d", timeRange, limit)
+		cacheKey := client.cacheKey("performance", timeRange, limit)
 
-		if cachedData, found := client.getFromCache(cacheKey); found {
+		if cachedData, age, found := client.getFromCacheWithAge(c.Request.Context(), cacheKey); found {
 			if samples, ok := cachedData.([]map[string]interface{}); ok {
-				c.JSON(http.StatusOK, gin.H{
-					"samples":   samples,
-					"timeRange": timeRange,
-					"limit":     limit,
-					"cached":    true,
+				firstSlot, lastSlot, coveredSeconds := sampleRange(samples)
+				respondJSON(c, http.StatusOK, gin.H{
+					"samples":        samples,
+					"timeRange":      timeRange,
+					"limit":          limit,
+					"requestedLimit": limit,
+					"actualCount":    len(samples),
+					"cached":         true,
+					"ageSeconds":     age.Seconds(),
+					"firstSlot":      BigUint(firstSlot),
+					"lastSlot":       BigUint(lastSlot),
+					"coveredSeconds": coveredSeconds,
 				})
 				return
 			}
 		}
 
-		samples, err := client.GetPerformanceSamples(limit)
+		samples, err := client.GetPerformanceSamples(c.Request.Context(), limit)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get performance samples"})
 			return
@@ -770,13 +1643,20 @@ func main() {
 			cacheDuration = 30 * time.Second
 		}
 
-		client.setCache(cacheKey, samples, cacheDuration)
-
-		c.JSON(http.StatusOK, gin.H{
-			"samples":   samples,
-			"timeRange": timeRange,
-			"limit":     limit,
-			"cached":    false,
+		client.setCache(c.Request.Context(), cacheKey, samples, cacheDuration)
+
+		firstSlot, lastSlot, coveredSeconds := sampleRange(samples)
+		respondJSON(c, http.StatusOK, gin.H{
+			"samples":        samples,
+			"timeRange":      timeRange,
+			"limit":          limit,
+			"requestedLimit": limit,
+			"actualCount":    len(samples),
+			"cached":         false,
+			"ageSeconds":     0,
+			"firstSlot":      BigUint(firstSlot),
+			"lastSlot":       BigUint(lastSlot),
+			"coveredSeconds": coveredSeconds,
 		})
 	})
 
@@ -786,30 +1666,113 @@ func main() {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Address parameter is required"})
 			return
 		}
+		if !isValidSolanaAddress(address) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address"})
+			return
+		}
 
-		accountInfo, err := client.GetAccountInfo(address)
+		opts, err := parseAccountInfoOptions(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		raw, ok := rawRequested(c, debugMode)
+		if !ok {
+			return
+		}
+
+		accountInfo, err := client.GetAccountInfoWithOptions(c.Request.Context(), address, opts)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get account info"})
 			return
 		}
+		if raw {
+			accountInfo.Raw = accountInfo.rpcResults
+		}
+
+		if c.Query("usd") == "true" && accountInfo.IsValid {
+			if value, err := client.usdValue(c.Request.Context(), accountInfo.Balance); err != nil {
+				accountInfo.USDError = err.Error()
+			} else {
+				accountInfo.USDValue = value
+			}
+		}
+		accountInfo.FlagReason, accountInfo.Flagged = flags.lookup(address)
 
-		c.JSON(http.StatusOK, accountInfo)
+		respondJSON(c, http.StatusOK, accountInfo)
 	})
 
+	r.POST("/api/accounts", handleMultipleAccounts(client))
+
+	r.POST("/api/balances", handleMultipleBalances(client))
+
+	r.GET("/api/account/:address/creation", handleAccountCreation(client))
+
+	r.GET("/api/account/:address/transactions", handleAccountTransactions(client))
+
+	r.GET("/api/account/:address/domains", handleWalletDomains(client))
+
+	r.GET("/api/account/:address/portfolio", handlePortfolio(client))
+
+	r.GET("/api/account/:address/tokens", handleTokenAccounts(client))
+	r.GET("/api/account/:address/token/:mint", handleTokenBalance(client))
+
+	r.GET("/api/flagged/:address", handleFlagStatus(flags))
+
+	r.POST("/api/account/:address/decode", handleDecodeAccount(client, idls))
+
+	r.POST("/api/program/:programId/accounts", handleProgramAccounts(client))
+	r.GET("/api/program/:programId/info", handleProgramInfo(client))
+
+	r.POST("/api/watch", handleCreateWatch(watches))
+	r.GET("/api/watch/:id", handleGetWatch(watches))
+	r.DELETE("/api/watch/:id", handleDeleteWatch(watches))
+
 	r.GET("/api/balance/:address", func(c *gin.Context) {
 		address := c.Param("address")
 		if address == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Address parameter is required"})
 			return
 		}
+		if !isValidSolanaAddress(address) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address"})
+			return
+		}
 
-		balance, err := client.GetBalance(address)
+		// Asking for a program's balance is usually a wallet tooling bug, so
+		// callers can opt in to rejecting executable accounts.
+		if c.Query("walletsOnly") == "true" {
+			accountInfo, err := client.GetAccountInfo(c.Request.Context(), address)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get account info"})
+				return
+			}
+			if accountInfo.Executable {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Address is a program account, not a wallet"})
+				return
+			}
+		}
+
+		balance, contextSlot, err := client.GetBalanceWithSlot(c.Request.Context(), address)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get balance"})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"address": address, "balance": balance})
+		response := gin.H{"address": address, "balance": balance}
+		if contextSlot > 0 {
+			response["contextSlot"] = BigUint(contextSlot)
+		}
+		if c.Query("usd") == "true" {
+			if value, err := client.usdValue(c.Request.Context(), balance); err != nil {
+				response["usdError"] = err.Error()
+			} else {
+				response["usdValue"] = *value
+			}
+		}
+
+		respondJSON(c, http.StatusOK, response)
 	})
 
 	r.GET("/api/token/:mintAddress", func(c *gin.Context) {
@@ -818,44 +1781,139 @@ func main() {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Mint address parameter is required"})
 			return
 		}
+		if !isValidSolanaAddress(mintAddress) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address"})
+			return
+		}
 
-		tokenInfo, err := client.GetTokenSupply(mintAddress)
+		raw, ok := rawRequested(c, debugMode)
+		if !ok {
+			return
+		}
+
+		tokenInfo, err := client.GetTokenSupply(c.Request.Context(), mintAddress)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get token info"})
 			return
 		}
+		if tokenInfo.Reason == notAMintReason {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Address is not a token mint", "reason": notAMintReason, "mintAddress": mintAddress})
+			return
+		}
+		if raw {
+			withRaw := *tokenInfo
+			withRaw.Raw = tokenInfo.rpcResults
+			tokenInfo = &withRaw
+		}
 
-		c.JSON(http.StatusOK, tokenInfo)
+		if price, ok := client.tokenUSDPrice(c, mintAddress); ok {
+			// GetTokenSupply may return a cached value; price a copy.
+			priced := *tokenInfo
+			supplyUSD := jsonSafeFloat(tokenInfo.ActualSupply * price)
+			priced.PriceUSD = &price
+			priced.SupplyUSD = &supplyUSD
+			tokenInfo = &priced
+		}
+		if reason, ok := flags.lookup(mintAddress); ok {
+			flagged := *tokenInfo
+			flagged.Flagged, flagged.FlagReason = true, reason
+			tokenInfo = &flagged
+		}
+
+		respondJSON(c, http.StatusOK, tokenInfo)
 	})
 
+	r.GET("/api/token/:mintAddress/metadata", handleTokenMetadata(client))
+
 	r.GET("/api/token/:mintAddress/holders", func(c *gin.Context) {
 		mintAddress := c.Param("mintAddress")
 		if mintAddress == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Mint address parameter is required"})
 			return
 		}
+		if !isValidSolanaAddress(mintAddress) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address"})
+			return
+		}
 
-		limitStr := c.DefaultQuery("limit", "10")
+		limitStr := c.DefaultQuery("limit", strconv.Itoa(defaultHolderLimit))
 		limit, err := strconv.Atoi(limitStr)
 		if err != nil {
-			limit = 10
+			limit = defaultHolderLimit
 		}
 
-		log.Printf("Fetching token holders for mint: %s, limit: %d", mintAddress, limit)
+		exclude := parseCommaList(c.Query("exclude"))
 
-		holders, err := client.GetTokenAccountsByMint(mintAddress, limit)
+		log.Printf("Fetching token holders for mint: %s, limit: %d, exclude: %d", mintAddress, limit, len(exclude))
+
+		// getTokenLargestAccounts finds the holders under whichever program
+		// owns the mint; the detection rejects non-mints up front and tells
+		// the client which program it is.
+		tokenProgram, err := client.GetTokenProgram(c.Request.Context(), mintAddress)
+		if errors.Is(err, errNotTokenMint) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Address is not a token mint"})
+			return
+		}
+		if err != nil {
+			log.Printf("Error detecting token program for %s: %v", mintAddress, err)
+			respondRPCFailure(c, "Failed to check the mint account", err)
+			return
+		}
+
+		holders, err := client.GetTokenHolderDistribution(c.Request.Context(), mintAddress, limit, exclude)
 		if err != nil {
 			log.Printf("Error getting token holders: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get token holders"})
+
+			var rateLimitErr *RateLimitError
+			if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))))
+			}
+
+			respondJSON(c, http.StatusOK, gin.H{"mintAddress": mintAddress, "holders": []interface{}{}, "status": "unavailable"})
 			return
 		}
 
 		log.Printf("Found %d token holders", len(holders))
 
-		c.JSON(http.StatusOK, gin.H{"mintAddress": mintAddress, "holders": holders})
+		status := "ok"
+		if len(holders) == 0 {
+			status = "empty"
+		}
+
+		response := gin.H{"mintAddress": mintAddress, "holders": holders, "status": status, "tokenProgram": tokenProgram}
+		if price, ok := client.tokenUSDPrice(c, mintAddress); ok {
+			// The holder maps are shared with the cache, so price copies.
+			priced := make([]map[string]interface{}, 0, len(holders))
+			for _, holder := range holders {
+				entry := make(map[string]interface{}, len(holder)+1)
+				for k, v := range holder {
+					entry[k] = v
+				}
+				entry["usdValue"] = jsonSafeFloat(holderUIAmount(holder) * price)
+				priced = append(priced, entry)
+			}
+			response["holders"] = priced
+			response["priceUsd"] = price
+		}
+
+		respondJSON(c, http.StatusOK, response)
 	})
 
+	r.GET("/api/resolve/:name", handleResolveDomain(client))
+
+	r.POST("/api/tokens/holders/overlap", handleHolderOverlap(client))
+
+	r.GET("/api/block/:slot", handleGetBlock(client))
+
+	r.GET("/api/blocks/recent", handleRecentBlocks(client))
+
+	r.GET("/api/transaction/:signature", handleGetTransaction(client, debugMode))
+
+	r.GET("/api/transaction/status/stream", handleSignatureStatusStream(client))
+
+	r.POST("/api/transaction/send", handleSendTransaction(client))
+
 	log.Printf("Server starting on port %s", port)
-	log.Printf("Using Solana RPC: %s", solanaURL)
+	log.Printf("Using Solana RPC: %s", client.URL)
 	log.Fatal(r.Run(":" + port))
 }
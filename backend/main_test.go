@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testRPCCall is a JSON-RPC call received by a testRPCNode.
+type testRPCCall struct {
+	Method string
+	Params []interface{}
+}
+
+// testRPCError is returned by a testRPCNode's respond to fail a call with a
+// JSON-RPC error.
+type testRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// testRPCNode is a JSON-RPC node backed by httptest. respond returns the
+// result of a call, or a *testRPCError to fail it; nil answers "Method not
+// found". Single calls and batches are both accepted, and every call is
+// recorded.
+type testRPCNode struct {
+	respond func(method string, params []interface{}) interface{}
+
+	mutex sync.Mutex
+	calls []testRPCCall
+}
+
+// newTestRPCNode starts a testRPCNode and returns a client for it. Both are
+// shut down when the test ends.
+func newTestRPCNode(t *testing.T, respond func(method string, params []interface{}) interface{}) (*testRPCNode, *SolanaRPCClient) {
+	t.Helper()
+
+	node := &testRPCNode{respond: respond}
+	server := httptest.NewServer(http.HandlerFunc(node.serveHTTP))
+	t.Cleanup(server.Close)
+
+	client := NewSolanaClient(server.URL)
+	t.Cleanup(client.Close)
+	return node, client
+}
+
+// rawResults answers each method with its raw JSON result.
+func rawResults(results map[string]string) func(string, []interface{}) interface{} {
+	return func(method string, _ []interface{}) interface{} {
+		if raw, ok := results[method]; ok {
+			return json.RawMessage(raw)
+		}
+		return nil
+	}
+}
+
+func (n *testRPCNode) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	type request struct {
+		ID     interface{}   `json:"id"`
+		Method string        `json:"method"`
+		Params []interface{} `json:"params"`
+	}
+	answer := func(req request) map[string]interface{} {
+		n.mutex.Lock()
+		n.calls = append(n.calls, testRPCCall{Method: req.Method, Params: req.Params})
+		n.mutex.Unlock()
+
+		result := n.respond(req.Method, req.Params)
+		if result == nil {
+			result = &testRPCError{Code: -32601, Message: "Method not found"}
+		}
+		if rpcErr, ok := result.(*testRPCError); ok {
+			return map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": rpcErr}
+		}
+		return map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result}
+	}
+
+	var response interface{}
+	var batch []request
+	if err := json.Unmarshal(raw, &batch); err == nil {
+		answers := make([]map[string]interface{}, len(batch))
+		for i, req := range batch {
+			answers[i] = answer(req)
+		}
+		response = answers
+	} else {
+		var single request
+		if err := json.Unmarshal(raw, &single); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		response = answer(single)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// callsTo returns the recorded calls of method.
+func (n *testRPCNode) callsTo(method string) []testRPCCall {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	var calls []testRPCCall
+	for _, call := range n.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func TestMalformedResultsReturnParseError(t *testing.T) {
+	getEpochInfo := func(ctx context.Context, s *SolanaRPCClient) error {
+		_, err := s.GetEpochInfo(ctx)
+		return err
+	}
+	getValidatorCount := func(ctx context.Context, s *SolanaRPCClient) error {
+		_, err := s.GetValidatorCount(ctx)
+		return err
+	}
+	getPerformanceSamples := func(ctx context.Context, s *SolanaRPCClient) error {
+		_, err := s.GetPerformanceSamples(ctx, 5)
+		return err
+	}
+	getTokenAccountsByMint := func(ctx context.Context, s *SolanaRPCClient) error {
+		_, err := s.GetTokenAccountsByMint(ctx, "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v", 10)
+		return err
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		result string
+		call   func(context.Context, *SolanaRPCClient) error
+	}{
+		{"epoch info is an array", "getEpochInfo", `[1, 2]`, getEpochInfo},
+		{"epoch info is null", "getEpochInfo", `null`, getEpochInfo},
+		{"vote accounts is a string", "getVoteAccounts", `"current"`, getValidatorCount},
+		{"current is not an array", "getVoteAccounts", `{"current": {"a": 1}, "delinquent": []}`, getValidatorCount},
+		{"current is missing", "getVoteAccounts", `{"delinquent": []}`, getValidatorCount},
+		{"samples is an object", "getRecentPerformanceSamples", `{"numSlots": 60}`, getPerformanceSamples},
+		{"samples is a number", "getRecentPerformanceSamples", `42`, getPerformanceSamples},
+		{"largest accounts without context", "getTokenLargestAccounts", `[{"address": "a"}]`, getTokenAccountsByMint},
+		{"largest accounts without value", "getTokenLargestAccounts", `{"context": {"slot": 1}}`, getTokenAccountsByMint},
+		{"largest accounts value is an object", "getTokenLargestAccounts", `{"context": {"slot": 1}, "value": {"address": "a"}}`, getTokenAccountsByMint},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, client := newTestRPCNode(t, rawResults(map[string]string{tt.method: tt.result}))
+
+			err := tt.call(context.Background(), client)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("got error %v, want a ParseError", err)
+			}
+			if parseErr.Method != tt.method {
+				t.Errorf("ParseError.Method = %q, want %q", parseErr.Method, tt.method)
+			}
+		})
+	}
+}
+
+func TestGetPerformanceSamplesSkipsMalformedSamples(t *testing.T) {
+	_, client := newTestRPCNode(t, rawResults(map[string]string{
+		"getRecentPerformanceSamples": `[null, "sample", 7, {"numSlots": 60, "numTransactions": 1200, "samplePeriodSecs": 60}]`,
+	}))
+
+	samples, err := client.GetPerformanceSamples(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("GetPerformanceSamples: %v", err)
+	}
+	if len(samples) != 1 {
+		t.Fatalf("got %d samples, want 1", len(samples))
+	}
+}
+
+func TestGetMetricsMalformedResults(t *testing.T) {
+	_, client := newTestRPCNode(t, rawResults(map[string]string{
+		"getSlot":                     `{"unexpected": [true]}`,
+		"getEpochInfo":                `[1, 2]`,
+		"getVoteAccounts":             `{"current": "all"}`,
+		"getRecentPerformanceSamples": `{"numSlots": 60}`,
+	}))
+
+	_, err := client.GetMetrics(context.Background())
+	var metricsErr *MetricsError
+	if !errors.As(err, &metricsErr) {
+		t.Fatalf("got error %v, want a MetricsError", err)
+	}
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("got error %v, want it to wrap a ParseError", err)
+	}
+}
+
+func TestCalculateTPS(t *testing.T) {
+	sample := func(transactions, seconds float64) map[string]interface{} {
+		return map[string]interface{}{"numTransactions": transactions, "samplePeriodSecs": seconds, "numSlots": 150.0}
+	}
+
+	tests := []struct {
+		name    string
+		samples []map[string]interface{}
+		want    float64
+	}{
+		{"no samples", nil, 0},
+		{"empty samples", []map[string]interface{}{}, 0},
+		{"one sample", []map[string]interface{}{sample(6000, 60)}, 100},
+		{"fewer samples than requested", []map[string]interface{}{sample(6000, 60), sample(3000, 60)}, 75},
+		{"weighted by period", []map[string]interface{}{sample(5400, 60), sample(900, 30)}, 70},
+		{"zero period skipped", []map[string]interface{}{sample(6000, 60), sample(500, 0)}, 100},
+		{"only zero periods", []map[string]interface{}{sample(500, 0)}, 0},
+		{"missing transactions skipped", []map[string]interface{}{sample(6000, 60), {"samplePeriodSecs": 60.0}}, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calculateTPS(tt.samples)
+			if math.IsNaN(got) || math.IsInf(got, 0) {
+				t.Fatalf("got %v, want a finite TPS", got)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateNonVoteTPS(t *testing.T) {
+	sample := func(transactions, nonVote, seconds float64) map[string]interface{} {
+		return map[string]interface{}{"numTransactions": transactions, "numNonVoteTransactions": nonVote, "samplePeriodSecs": seconds, "numSlots": 150.0}
+	}
+	legacy := func(transactions, seconds float64) map[string]interface{} {
+		return map[string]interface{}{"numTransactions": transactions, "samplePeriodSecs": seconds, "numSlots": 150.0}
+	}
+
+	tests := []struct {
+		name        string
+		samples     []map[string]interface{}
+		wantTPS     float64
+		wantNonVote float64
+	}{
+		{"no samples", nil, 0, 0},
+		{"both fields", []map[string]interface{}{sample(6000, 1200, 60), sample(3000, 600, 60)}, 75, 15},
+		{"no non-vote transactions", []map[string]interface{}{sample(6000, 0, 60)}, 100, 0},
+		{"non-vote field absent", []map[string]interface{}{legacy(6000, 60), legacy(3000, 60)}, 75, 75},
+		{"mixed nodes", []map[string]interface{}{sample(6000, 1200, 60), legacy(3000, 60)}, 75, 35},
+		{"zero period skipped", []map[string]interface{}{sample(6000, 1200, 60), sample(500, 100, 0)}, 100, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateTPS(tt.samples); got != tt.wantTPS {
+				t.Errorf("tps = %v, want %v", got, tt.wantTPS)
+			}
+			if got := calculateNonVoteTPS(tt.samples); got != tt.wantNonVote {
+				t.Errorf("nonVoteTps = %v, want %v", got, tt.wantNonVote)
+			}
+		})
+	}
+}
+
+func TestGetPerformanceSamplesShortfall(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  string
+		limit   int
+		want    int
+		wantTPS float64
+	}{
+		{"no samples", `[]`, 20, 0, 0},
+		{"fewer than requested", `[
+			{"slot": 1000, "numSlots": 150, "numTransactions": 6000, "samplePeriodSecs": 60},
+			{"slot": 850, "numSlots": 150, "numTransactions": 3000, "samplePeriodSecs": 60}
+		]`, 20, 2, 75},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, client := newTestRPCNode(t, rawResults(map[string]string{"getRecentPerformanceSamples": tt.result}))
+
+			samples, err := client.GetPerformanceSamples(context.Background(), tt.limit)
+			if err != nil {
+				t.Fatalf("GetPerformanceSamples: %v", err)
+			}
+			if len(samples) != tt.want {
+				t.Fatalf("got %d samples, want %d", len(samples), tt.want)
+			}
+			if tps := calculateTPS(samples); tps != tt.wantTPS {
+				t.Errorf("TPS = %v, want %v", tps, tt.wantTPS)
+			}
+
+			calls := node.callsTo("getRecentPerformanceSamples")
+			if len(calls) != 1 || len(calls[0].Params) != 1 || calls[0].Params[0] != float64(tt.limit) {
+				t.Errorf("got calls %v, want one asking for %d samples", calls, tt.limit)
+			}
+		})
+	}
+}
+
+func TestSampleRange(t *testing.T) {
+	tests := []struct {
+		name        string
+		samples     []map[string]interface{}
+		wantFirst   uint64
+		wantLast    uint64
+		wantSeconds float64
+	}{
+		{"no samples", nil, 0, 0, 0},
+		{"two samples", []map[string]interface{}{
+			{"slot": 1000.0, "numSlots": 150.0, "samplePeriodSecs": 60.0},
+			{"slot": 850.0, "numSlots": 150.0, "samplePeriodSecs": 60.0},
+		}, 701, 1000, 120},
+		{"numSlots beyond genesis", []map[string]interface{}{
+			{"slot": 10.0, "numSlots": 150.0, "samplePeriodSecs": 4.0},
+		}, 10, 10, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last, seconds := sampleRange(tt.samples)
+			if first != tt.wantFirst || last != tt.wantLast || seconds != tt.wantSeconds {
+				t.Errorf("got %d-%d over %vs, want %d-%d over %vs", first, last, seconds, tt.wantFirst, tt.wantLast, tt.wantSeconds)
+			}
+		})
+	}
+}
+
+func TestRouteMatchingKeepsParamCase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const address = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+
+	tests := []struct {
+		name                  string
+		redirectTrailingSlash bool
+		caseInsensitiveRoutes bool
+		path                  string
+		wantStatus            int
+		wantLocation          string
+	}{
+		{"registered path", true, true, "/api/account/" + address, http.StatusOK, ""},
+		{"trailing slash", true, false, "/api/account/" + address + "/", http.StatusMovedPermanently, "/api/account/" + address},
+		{"trailing slash not redirected", false, false, "/api/account/" + address + "/", http.StatusNotFound, ""},
+		{"route in upper case", true, true, "/API/ACCOUNT/" + address, http.StatusMovedPermanently, "/api/account/" + address},
+		{"route in mixed case", true, true, "/Api/Account/" + address, http.StatusMovedPermanently, "/api/account/" + address},
+		{"case folding off", true, false, "/API/ACCOUNT/" + address, http.StatusNotFound, ""},
+		{"static route", true, true, "/API/Metrics", http.StatusMovedPermanently, "/api/metrics"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRouter(tt.redirectTrailingSlash, tt.caseInsensitiveRoutes)
+			r.GET("/api/account/:address", func(c *gin.Context) {
+				c.String(http.StatusOK, c.Param("address"))
+			})
+			r.GET("/api/metrics", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if location := recorder.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("redirected to %q, want %q", location, tt.wantLocation)
+			}
+			if tt.wantStatus == http.StatusOK && tt.path != "/api/metrics" {
+				if body := recorder.Body.String(); body != address {
+					t.Errorf("handler got address %q, want %q", body, address)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	// HTTP dates only carry whole seconds, so dates are checked against a
+	// range rather than an exact duration.
+	httpDate := func(offset time.Duration) string {
+		return time.Now().Add(offset).UTC().Format(http.TimeFormat)
+	}
+
+	tests := []struct {
+		name       string
+		retryAfter string
+		floor      time.Duration
+		min, max   time.Duration
+		wantErr    bool
+	}{
+		{"delta seconds", "5", time.Second, 5 * time.Second, 5 * time.Second, false},
+		{"zero seconds gets the floor", "0", time.Second, time.Second, time.Second, false},
+		{"zero seconds without a floor", "0", 0, 0, 0, false},
+		{"under the floor", "1", 3 * time.Second, 3 * time.Second, 3 * time.Second, false},
+		{"capped seconds", "3600", time.Second, maxRetryAfter, maxRetryAfter, false},
+		{"negative seconds", "-1", time.Second, 0, 0, true},
+		{"future date", httpDate(30 * time.Second), time.Second, 28 * time.Second, 30 * time.Second, false},
+		{"near-future date gets the floor", httpDate(1500 * time.Millisecond), 2 * time.Second, 2 * time.Second, 2 * time.Second, false},
+		{"past date", httpDate(-time.Hour), time.Second, 0, 0, true},
+		{"clock ahead of ours", httpDate(-3 * time.Second), time.Second, 0, 0, true},
+		{"date too far away", httpDate(time.Hour), time.Second, 0, 0, true},
+		{"RFC3339 date", time.Now().Add(time.Minute).UTC().Format(time.RFC3339), time.Second, 58 * time.Second, time.Minute, false},
+		{"garbage", "soon", time.Second, 0, 0, true},
+		{"empty", "", time.Second, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRetryAfter(tt.retryAfter, tt.floor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && (got < tt.min || got > tt.max) {
+				t.Errorf("got %v, want between %v and %v", got, tt.min, tt.max)
+			}
+		})
+	}
+}
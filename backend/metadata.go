@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mr-tron/base58"
+)
+
+const (
+	metadataProgramID = "metaqbxxUerdq28cj1RbAWkYQm3ybzjb6a8bt518x1s"
+
+	defaultMetadataFetchTimeout = 3 * time.Second
+	maxMetadataResponseBytes    = 256 * 1024
+	maxMetadataRedirects        = 3
+)
+
+var errNoMetadata = errors.New("no metadata account for mint")
+
+type TokenMetadata struct {
+	MintAddress          string                 `json:"mintAddress"`
+	MetadataAddress      string                 `json:"metadataAddress"`
+	UpdateAuthority      string                 `json:"updateAuthority"`
+	Name                 string                 `json:"name"`
+	Symbol               string                 `json:"symbol"`
+	URI                  string                 `json:"uri"`
+	SellerFeeBasisPoints uint16                 `json:"sellerFeeBasisPoints"`
+	OffChain             map[string]interface{} `json:"offChain,omitempty"`
+	MetadataFetchError   string                 `json:"metadataFetchError,omitempty"`
+}
+
+// newMetadataHTTPClient returns the client used for off-chain metadata URIs,
+// which are user-controlled. It times out quickly and only follows a few
+// redirects, all of which must stay on http(s).
+func newMetadataHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxMetadataRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxMetadataRedirects)
+			}
+			return checkMetadataURI(req.URL)
+		},
+	}
+}
+
+func checkMetadataURI(uri *url.URL) error {
+	if uri.Scheme != "http" && uri.Scheme != "https" {
+		return fmt.Errorf("unsupported metadata URI scheme %q", uri.Scheme)
+	}
+	if uri.Host == "" {
+		return fmt.Errorf("metadata URI has no host")
+	}
+	return nil
+}
+
+// borshReader reads the little-endian borsh layout used by Metaplex accounts.
+type borshReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *borshReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, fmt.Errorf("unexpected end of data at offset %d", r.pos)
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *borshReader) u8() (uint8, error) {
+	b, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *borshReader) u16() (uint16, error) {
+	b, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+func (r *borshReader) u32() (uint32, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *borshReader) u64() (uint64, error) {
+	b, err := r.bytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func (r *borshReader) pubkey() (string, error) {
+	b, err := r.bytes(32)
+	if err != nil {
+		return "", err
+	}
+	return base58.Encode(b), nil
+}
+
+func (r *borshReader) string() (string, error) {
+	n, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(int(n))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// decodeMetadata decodes the fixed prefix of a Metaplex metadata account. The
+// name, symbol and uri fields are zero-padded on chain.
+func decodeMetadata(data []byte) (*TokenMetadata, error) {
+	r := &borshReader{data: data}
+
+	if _, err := r.u8(); err != nil {
+		return nil, err
+	}
+	updateAuthority, err := r.pubkey()
+	if err != nil {
+		return nil, err
+	}
+	mint, err := r.pubkey()
+	if err != nil {
+		return nil, err
+	}
+	name, err := r.string()
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := r.string()
+	if err != nil {
+		return nil, err
+	}
+	uri, err := r.string()
+	if err != nil {
+		return nil, err
+	}
+	sellerFee, err := r.u16()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenMetadata{
+		MintAddress:          mint,
+		UpdateAuthority:      updateAuthority,
+		Name:                 strings.TrimRight(name, "\x00"),
+		Symbol:               strings.TrimRight(symbol, "\x00"),
+		URI:                  strings.TrimRight(uri, "\x00"),
+		SellerFeeBasisPoints: sellerFee,
+	}, nil
+}
+
+func (s *SolanaRPCClient) GetTokenMetadata(ctx context.Context, mintAddress string) (*TokenMetadata, error) {
+	cacheKey := s.cacheKey("token_metadata", mintAddress)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if metadata, ok := cached.(*TokenMetadata); ok {
+			return metadata, nil
+		}
+	}
+
+	mint, err := decodePublicKey(mintAddress)
+	if err != nil {
+		return nil, err
+	}
+	program, err := decodePublicKey(metadataProgramID)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataAddress, _, err := findProgramAddress([][]byte{[]byte("metadata"), program, mint}, metadataProgramID)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := s.GetAccountInfoWithOptions(ctx, metadataAddress, AccountInfoOptions{DataEncoding: "base64"})
+	if err != nil {
+		return nil, err
+	}
+	if !account.IsValid {
+		return nil, errNoMetadata
+	}
+
+	data, err := base64.StdEncoding.DecodeString(account.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := decodeMetadata(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata account: %w", err)
+	}
+	metadata.MetadataAddress = metadataAddress
+
+	if metadata.URI != "" {
+		offChain, err := s.fetchOffChainMetadata(ctx, metadata.URI)
+		if err != nil && ctx.Err() != nil {
+			// An abandoned request is not a failed fetch; do not cache it.
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			metadata.MetadataFetchError = err.Error()
+		} else {
+			metadata.OffChain = offChain
+		}
+	}
+
+	// Failed off-chain fetches are retried sooner than complete results.
+	cacheDuration := 10 * time.Minute
+	if metadata.MetadataFetchError != "" {
+		cacheDuration = 1 * time.Minute
+	}
+	s.setCache(ctx, cacheKey, metadata, cacheDuration)
+
+	return metadata, nil
+}
+
+func (s *SolanaRPCClient) fetchOffChainMetadata(ctx context.Context, rawURI string) (map[string]interface{}, error) {
+	uri, err := url.Parse(rawURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata URI: %w", err)
+	}
+	if err := checkMetadataURI(uri); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.metadataHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata URI returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataResponseBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxMetadataResponseBytes {
+		return nil, fmt.Errorf("metadata response larger than %d bytes", maxMetadataResponseBytes)
+	}
+
+	var offChain map[string]interface{}
+	if err := json.Unmarshal(body, &offChain); err != nil {
+		return nil, fmt.Errorf("metadata URI did not return a JSON object")
+	}
+
+	return offChain, nil
+}
+
+func handleTokenMetadata(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mintAddress := c.Param("mintAddress")
+		if _, err := decodePublicKey(mintAddress); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mint address"})
+			return
+		}
+
+		metadata, err := client.GetTokenMetadata(c.Request.Context(), mintAddress)
+		if errors.Is(err, errNoMetadata) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No metadata found for mint"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get token metadata"})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, metadata)
+	}
+}
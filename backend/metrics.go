@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// lastGoodTTL bounds how long a sub-metric may be served from the fallback
+// cache after its RPC call started failing. The cache's maximum staleness
+// applies on top of it.
+const lastGoodTTL = 1 * time.Hour
+
+// defaultMetricsSampleCount is how many performance samples (one per minute)
+// the headline TPS is computed from. A short window keeps the hot metrics
+// call cheap and the figure current; /api/performance serves longer history.
+// Override with METRICS_TPS_SAMPLES.
+const defaultMetricsSampleCount = 5
+
+// maxPerformanceSamples is the most getRecentPerformanceSamples returns.
+const maxPerformanceSamples = 720
+
+// minTransactionCountInterval is the shortest gap between two
+// getTransactionCount readings that is turned into a TPS figure; closer
+// readings reuse the previous figure rather than amplify timing noise.
+const minTransactionCountInterval = 1 * time.Second
+
+type transactionCountSample struct {
+	Count uint64
+	At    time.Time
+	// TPS is the estimate computed when this reading was taken, if any.
+	TPS *float64
+}
+
+// MetricsError reports which sub-metric could not be fetched and had no
+// usable last-known-good value to fall back to. TooStale is set when a value
+// was remembered but is older than the maximum cache staleness.
+type MetricsError struct {
+	Metric   string
+	Err      error
+	TooStale bool
+}
+
+func (e *MetricsError) Error() string {
+	return fmt.Sprintf("failed to get %s: %v", e.Metric, e.Err)
+}
+
+func (e *MetricsError) Unwrap() error {
+	return e.Err
+}
+
+func (s *SolanaRPCClient) rememberLastGood(ctx context.Context, metric string, value interface{}) {
+	s.setCache(ctx, s.cacheKey("last_good", metric), value, lastGoodTTL)
+}
+
+// fallback returns the last good value for metric after fetching it failed
+// with err, raising oldest to the age of the value served.
+func (s *SolanaRPCClient) fallback(ctx context.Context, metric string, err error, oldest *time.Duration) (interface{}, error) {
+	// A cancelled or timed-out request has nobody left to serve.
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	value, age, found := s.getFromCacheWithAge(ctx, s.cacheKey("last_good", metric))
+	if !found {
+		return nil, &MetricsError{Metric: metric, Err: err, TooStale: age > 0}
+	}
+	if age > *oldest {
+		*oldest = age
+	}
+	return value, nil
+}
+
+// metricsPart tracks how one of the calls behind GetMetrics fared: whether
+// its fields were served from the last good value, and how old that was.
+type metricsPart struct {
+	fields []string
+	stale  bool
+	age    time.Duration
+}
+
+// withFallback runs fetch and remembers its value as the last good one for
+// metric, or falls back to that value when fetch fails.
+func (s *SolanaRPCClient) withFallback(ctx context.Context, metric string, part *metricsPart, fetch func() (interface{}, error)) (interface{}, error) {
+	value, err := fetch()
+	if err == nil {
+		s.rememberLastGood(ctx, metric, value)
+		return value, nil
+	}
+	cached, err := s.fallback(ctx, metric, err, &part.age)
+	if err != nil {
+		return nil, err
+	}
+	part.stale = true
+	return cached, nil
+}
+
+// GetMetrics fans out to the RPC calls behind the dashboard metrics, all at
+// once. When a call fails, the last successful value for that sub-metric is
+// used instead and its fields are listed in StaleFields. Without one, a
+// failed slot or epoch call fails the whole request, while the validator
+// count and TPS are only marked missing: ValidatorCount is -1, TPS is 0 and
+// Partial is set.
+func (s *SolanaRPCClient) GetMetrics(ctx context.Context) (*SolanaMetrics, error) {
+	slotPart := metricsPart{fields: []string{"currentSlot"}}
+	epochPart := metricsPart{fields: []string{"epoch", "epochProgress", "slotsInEpoch", "slotIndex"}}
+	validatorPart := metricsPart{fields: []string{"validatorCount"}}
+	samplesPart := metricsPart{fields: []string{"tps", "nonVoteTps"}}
+
+	var (
+		consensus           *MetricsConsensus
+		slot                uint64
+		epochInfo           map[string]interface{}
+		validatorCount      int
+		samples             []map[string]interface{}
+		samplesMissing      bool
+		transactionCountTPS *float64
+	)
+
+	// Each call only writes its own results; a critical failure cancels
+	// the others.
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		// The consensus slot is fetched fresh from every endpoint; the
+		// primary's slot, with its fallback, only serves when none of them
+		// answered.
+		if s.consensusMetrics {
+			consensus = s.MetricsConsensus(groupCtx)
+		}
+		if consensus != nil {
+			slot = uint64(consensus.Slot)
+			s.rememberLastGood(ctx, "slot", slot)
+			return nil
+		}
+		value, err := s.withFallback(ctx, "slot", &slotPart, func() (interface{}, error) {
+			return s.GetSlot(groupCtx)
+		})
+		slot, _ = value.(uint64)
+		return err
+	})
+	group.Go(func() error {
+		value, err := s.withFallback(ctx, "epoch info", &epochPart, func() (interface{}, error) {
+			return s.GetEpochInfo(groupCtx)
+		})
+		epochInfo, _ = value.(map[string]interface{})
+		return err
+	})
+	group.Go(func() error {
+		value, err := s.withFallback(ctx, "validator count", &validatorPart, func() (interface{}, error) {
+			return s.GetValidatorCount(groupCtx)
+		})
+		validatorCount = -1
+		if err == nil {
+			validatorCount, _ = value.(int)
+		}
+		return nil
+	})
+	group.Go(func() error {
+		value, err := s.withFallback(ctx, "performance samples", &samplesPart, func() (interface{}, error) {
+			return s.GetPerformanceSamples(groupCtx, s.metricsSampleCount)
+		})
+		samples, _ = value.([]map[string]interface{})
+		samplesMissing = err != nil
+		return nil
+	})
+	if s.tpsCrossCheck {
+		group.Go(func() error {
+			transactionCountTPS = s.transactionCountTPS(groupCtx)
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	staleFields := []string{}
+	var staleAge time.Duration
+	for _, part := range []*metricsPart{&slotPart, &epochPart, &validatorPart, &samplesPart} {
+		if part.stale {
+			staleFields = append(staleFields, part.fields...)
+			staleAge = max(staleAge, part.age)
+		}
+	}
+	partial := validatorCount < 0 || samplesMissing
+
+	tps := calculateTPS(samples)
+	nonVoteTPS := calculateNonVoteTPS(samples)
+	avgBlockTime := s.GetCachedBlockTime()
+
+	epoch, _ := epochInfo["epoch"].(float64)
+	slotIndex, _ := epochInfo["slotIndex"].(float64)
+	slotsInEpoch, _ := epochInfo["slotsInEpoch"].(float64)
+
+	epochProgress := math.Min(safeDivide(slotIndex, slotsInEpoch)*100, 100)
+
+	var networkHealth string
+	if partial {
+		networkHealth = "Unknown"
+	} else if tps > 100 && validatorCount > 1000 {
+		networkHealth = "Healthy"
+	} else if tps > 50 && validatorCount > 500 {
+		networkHealth = "Good"
+	} else if tps > 10 {
+		networkHealth = "Fair"
+	} else {
+		networkHealth = "Poor"
+	}
+
+	connectionStatus := "Connected"
+	if len(staleFields) > 0 || partial {
+		connectionStatus = "Degraded"
+	}
+
+	return &SolanaMetrics{
+		TPS:              tps,
+		NonVoteTPS:       nonVoteTPS,
+		AverageBlockTime: avgBlockTime,
+		CurrentSlot:      BigUint(slot),
+		Epoch:            uint64(epoch),
+		ValidatorCount:   validatorCount,
+		Timestamp:        time.Now(),
+		EpochProgress:    epochProgress,
+		SlotsInEpoch:     uint64(slotsInEpoch),
+		SlotIndex:        uint64(slotIndex),
+		NetworkHealth:    networkHealth,
+		ConnectionStatus: connectionStatus,
+		StaleFields:      staleFields,
+		AgeSeconds:       staleAge.Seconds(),
+		Partial:          partial,
+
+		TransactionCountTPS: transactionCountTPS,
+		Consensus:           consensus,
+	}, nil
+}
+
+func (s *SolanaRPCClient) GetTransactionCount(ctx context.Context) (uint64, error) {
+	resp, err := s.makeRPCCallWithRetry(ctx, "getTransactionCount", []interface{}{})
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.Error != nil {
+		return 0, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	count, ok := parseUint64Result(resp.Result)
+	if !ok {
+		return 0, &ParseError{Method: "getTransactionCount", Detail: "result is not a number"}
+	}
+
+	return count, nil
+}
+
+// transactionCountTPS estimates TPS from the change in the cluster's
+// transaction count since the previous reading. It returns nil on the first
+// reading, when the count cannot be fetched, and when the count went
+// backwards (e.g. the node was switched), which restarts the baseline.
+func (s *SolanaRPCClient) transactionCountTPS(ctx context.Context) *float64 {
+	count, err := s.GetTransactionCount(ctx)
+	if err != nil {
+		return nil
+	}
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous := s.lastTransactionCount
+	if previous != nil && now.Sub(previous.At) < minTransactionCountInterval {
+		return previous.TPS
+	}
+
+	current := &transactionCountSample{Count: count, At: now}
+	if previous != nil && count >= previous.Count {
+		tps := jsonSafeFloat(float64(count-previous.Count) / now.Sub(previous.At).Seconds())
+		current.TPS = &tps
+	}
+	s.lastTransactionCount = current
+
+	return current.TPS
+}
+
+// errMetricsPending is returned to callers that stopped waiting for a shared
+// metrics fetch; see sharedMetrics.
+var errMetricsPending = errors.New("metrics fetch still in progress")
+
+// metricsFetch is a GetMetrics fan-out that concurrent callers wait on
+// together. done is closed once metrics and err are set.
+type metricsFetch struct {
+	done    chan struct{}
+	started time.Time
+	// waiters counts the callers still waiting. When the last one leaves
+	// early, cancel stops the fetch's RPC calls.
+	waiters int
+	cancel  context.CancelFunc
+	// reported is set once a caller gave up on this fetch, so a slow
+	// upstream is logged once per fetch rather than once per request.
+	reported bool
+
+	metrics *SolanaMetrics
+	err     error
+}
+
+// sharedMetrics joins the in-flight GetMetrics fan-out or starts one, so a
+// burst of requests against a cold cache costs one set of RPC calls. The
+// fetch runs on its own context, which is cancelled once every caller
+// waiting on it has gone, so abandoned fetches stop costing RPC calls. With
+// a positive wait, a caller gets errMetricsPending once the fetch has been
+// running that long instead of blocking for the full upstream latency. Such
+// a caller keeps its share of the fetch: it was told to retry, and the
+// retry should find the fetch still running rather than start over. A
+// request pinned with ?rpc= fetches on its own, on its own context, so its
+// metrics come from its endpoint and are not handed to other callers.
+func (s *SolanaRPCClient) sharedMetrics(ctx context.Context, wait time.Duration) (*SolanaMetrics, error) {
+	if _, overridden := endpointOverrideFrom(ctx); overridden {
+		return s.GetMetrics(ctx)
+	}
+
+	s.mutex.Lock()
+	fetch := s.metricsFetch
+	if fetch == nil {
+		fetchCtx, cancel := context.WithCancel(context.Background())
+		fetch = &metricsFetch{done: make(chan struct{}), started: time.Now(), cancel: cancel}
+		s.metricsFetch = fetch
+		go func() {
+			defer cancel()
+			metrics, err := s.GetMetrics(fetchCtx)
+
+			s.mutex.Lock()
+			fetch.metrics, fetch.err = metrics, err
+			if s.metricsFetch == fetch {
+				s.metricsFetch = nil
+			}
+			s.mutex.Unlock()
+			close(fetch.done)
+		}()
+	}
+	fetch.waiters++
+	s.mutex.Unlock()
+
+	var deadline <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(time.Until(fetch.started.Add(wait)))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	select {
+	case <-fetch.done:
+		return fetch.metrics, fetch.err
+	case <-ctx.Done():
+		s.mutex.Lock()
+		fetch.waiters--
+		if fetch.waiters == 0 {
+			fetch.cancel()
+			// The next caller starts a fresh fetch instead of joining
+			// the cancelled one.
+			if s.metricsFetch == fetch {
+				s.metricsFetch = nil
+			}
+		}
+		s.mutex.Unlock()
+		return nil, ctx.Err()
+	case <-deadline:
+		s.mutex.Lock()
+		first := !fetch.reported
+		fetch.reported = true
+		s.mutex.Unlock()
+		if first {
+			log.Printf("Metrics fetch has been running for more than %s, answering waiting requests with 503", wait)
+		}
+		return nil, errMetricsPending
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDKey    = "requestID"
+	// maxRequestIDLength bounds an inbound X-Request-ID.
+	maxRequestIDLength = 128
+)
+
+type requestIDContextKey struct{}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID accepts the IDs clients commonly send, such as UUIDs,
+// hex strings and dotted trace IDs. Anything with spaces, quotes or control
+// characters is refused, so an ID cannot forge or split log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDMiddleware takes the request's correlation ID from X-Request-ID
+// when the client sent a valid one and generates one otherwise. The ID is
+// echoed in the response and carried in the request context, where
+// requestLogPrefix picks it up for log lines written on the request's
+// behalf.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Set(requestIDKey, requestID)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDContextKey{}, requestID))
+		c.Header(requestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// requestLogPrefix returns "request <id>: " for a context carrying a request
+// ID, and nothing for background work.
+func requestLogPrefix(ctx context.Context) string {
+	if requestID, ok := ctx.Value(requestIDContextKey{}).(string); ok {
+		return "request " + requestID + ": "
+	}
+	return ""
+}
+
+// accessLogFormatter is gin's default access log line with the request ID
+// appended.
+func accessLogFormatter(param gin.LogFormatterParams) string {
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	requestID, _ := param.Keys[requestIDKey].(string)
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v | %s\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		param.Path,
+		requestID,
+		param.ErrorMessage,
+	)
+}
+
+const (
+	apiVersionHeader    = "X-API-Version"
+	acceptVersionHeader = "Accept-Version"
+	apiVersionKey       = "apiVersion"
+
+	// currentAPIVersion is the newest response shape. A change that would
+	// break existing clients, such as turning a number into a string, gets a
+	// new version and is only served to requests that ask for it; handlers
+	// check apiVersion. Version 2 encodes BigUint values as strings.
+	currentAPIVersion = 2
+	// stableAPIVersion is served to requests without Accept-Version unless
+	// API_DEFAULT_VERSION or BIG_INTS_AS_STRINGS pick another, so clients
+	// built before a breaking change keep the shape they expect.
+	stableAPIVersion = 1
+)
+
+// supportedAPIVersions are the response shapes that can still be requested.
+var supportedAPIVersions = []int{1, 2}
+
+func parseAPIVersion(raw string) (int, bool) {
+	version, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(raw)), "v"))
+	if err != nil {
+		return 0, false
+	}
+	for _, supported := range supportedAPIVersions {
+		if version == supported {
+			return version, true
+		}
+	}
+	return 0, false
+}
+
+// apiVersionMiddleware resolves the response shape of a request from its
+// Accept-Version header ("2" or "v2"), falling back to defaultVersion, and
+// echoes it in X-API-Version. Unsupported versions are rejected with 400.
+func apiVersionMiddleware(defaultVersion int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		version := defaultVersion
+		if raw := c.GetHeader(acceptVersionHeader); raw != "" {
+			parsed, ok := parseAPIVersion(raw)
+			if !ok {
+				c.Header(apiVersionHeader, strconv.Itoa(defaultVersion))
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Unsupported API version", "supportedVersions": supportedAPIVersions})
+				return
+			}
+			version = parsed
+		}
+
+		c.Set(apiVersionKey, version)
+		c.Header(apiVersionHeader, strconv.Itoa(version))
+		c.Next()
+	}
+}
+
+// apiVersion returns the response shape the request asked for.
+func apiVersion(c *gin.Context) int {
+	if version, ok := c.Get(apiVersionKey); ok {
+		return version.(int)
+	}
+	return stableAPIVersion
+}
+
+// versioned returns obj in the response shape of the request's API version.
+func versioned(c *gin.Context, obj interface{}) interface{} {
+	if apiVersion(c) >= bigIntStringsVersion {
+		return withBigIntStrings(obj)
+	}
+	return obj
+}
+
+// respondJSON is c.JSON for responses carrying data, which are encoded in
+// the shape of the request's API version.
+func respondJSON(c *gin.Context, code int, obj interface{}) {
+	c.JSON(code, versioned(c, obj))
+}
+
+// recoveryMiddleware turns a handler panic into a 500 carrying the request
+// ID. The panic value and stack trace are always logged but only returned to
+// the client in debug mode.
+func recoveryMiddleware(debugMode bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			requestID := c.GetString(requestIDKey)
+			stack := debug.Stack()
+			log.Printf("Panic recovered (request %s) %s %s: %v\n%s", requestID, c.Request.Method, c.Request.URL.Path, recovered, stack)
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+
+			body := gin.H{"error": "Internal server error", "requestId": requestID}
+			if debugMode {
+				body["panic"] = fmt.Sprint(recovered)
+				body["stack"] = string(stack)
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, body)
+		}()
+
+		c.Next()
+	}
+}
+
+// defaultRequestTimeout bounds how long a request may take before it is
+// answered with 504. Override with REQUEST_TIMEOUT; 0 disables it.
+const defaultRequestTimeout = 30 * time.Second
+
+// timeoutWriter discards whatever a handler writes after the request's
+// deadline has passed without a response, leaving the 504 to
+// timeoutMiddleware.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx      context.Context
+	timedOut bool
+}
+
+func (w *timeoutWriter) expired() bool {
+	if !w.timedOut && !w.ResponseWriter.Written() && w.ctx.Err() == context.DeadlineExceeded {
+		w.timedOut = true
+	}
+	return w.timedOut
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if !w.expired() {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	if !w.expired() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.expired() {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(data string) (int, error) {
+	if w.expired() {
+		return len(data), nil
+	}
+	return w.ResponseWriter.WriteString(data)
+}
+
+// streamingRoutes are the long-lived WebSocket and event-stream routes,
+// which timeoutMiddleware exempts whatever headers the client sends.
+var streamingRoutes = map[string]bool{
+	"/ws/metrics":                    true,
+	"/api/metrics/stream":            true,
+	"/api/sse/metrics":               true,
+	"/api/transaction/status/stream": true,
+}
+
+// timeoutMiddleware gives each request a deadline through its context, which
+// the RPC client honours while calling upstream and between retries. A
+// request that has not responded when the deadline passes gets a 504.
+// Requests to streamingRoutes are exempt.
+func timeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 || streamingRoutes[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		writer := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.expired() {
+			c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out", "requestId": c.GetString(requestIDKey)})
+		}
+	}
+}
+
+// rawRequested reports whether the client asked for the upstream RPC results
+// with ?raw=true. They are only served in debug mode; otherwise it responds
+// with 403 and returns ok=false, and the handler must stop.
+func rawRequested(c *gin.Context, debugMode bool) (raw bool, ok bool) {
+	if c.Query("raw") != "true" {
+		return false, true
+	}
+	if !debugMode {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Raw RPC results are only available in debug mode"})
+		return false, false
+	}
+	return true, true
+}
+
+// adminAuthMiddleware guards the /api/admin routes. The key is sent as
+// "Authorization: Bearer <key>" or in X-Admin-Key; without ADMIN_API_KEY the
+// admin routes are disabled entirely.
+func adminAuthMiddleware(adminKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminKey == "" {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Admin endpoints are disabled"})
+			return
+		}
+
+		if !validAdminKey(c, adminKey) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin key"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// validAdminKey reports whether the request carries adminKey, which must not
+// be empty.
+func validAdminKey(c *gin.Context, adminKey string) bool {
+	provided := c.GetHeader("X-Admin-Key")
+	if bearer, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); found {
+		provided = bearer
+	}
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(adminKey)) == 1
+}
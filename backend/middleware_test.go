@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The upstream holds getBalance until its caller gives up, and reports
+	// whether that happened.
+	cancelled := make(chan bool, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var call testRPCCall
+		if err := json.NewDecoder(r.Body).Decode(&call); err != nil || call.Method != "getBalance" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			cancelled <- true
+		case <-time.After(5 * time.Second):
+			cancelled <- false
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	client := NewSolanaClient(upstream.URL)
+	t.Cleanup(client.Close)
+
+	r := gin.New()
+	r.Use(requestIDMiddleware(), timeoutMiddleware(100*time.Millisecond))
+	slow := func(c *gin.Context) {
+		balance, err := client.GetBalance(c.Request.Context(), c.Param("address"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"balance": balance})
+	}
+	r.GET("/api/balance/:address", slow)
+	r.GET("/api/metrics/stream", func(c *gin.Context) {
+		if _, hasDeadline := c.Request.Context().Deadline(); hasDeadline {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+		c.String(http.StatusOK, "data: {}\n\n")
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"slow upstream", "/api/balance/Account1111111111111111111111111111111111111", http.StatusGatewayTimeout},
+		{"streaming route exempt", "/api/metrics/stream", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d (%s), want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusGatewayTimeout {
+				return
+			}
+
+			var body map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding %s: %v", w.Body, err)
+			}
+			if body["error"] != "Request timed out" {
+				t.Errorf("error = %q, want %q", body["error"], "Request timed out")
+			}
+			if requestID := w.Header().Get(requestIDHeader); requestID == "" || body["requestId"] != requestID {
+				t.Errorf("requestId = %q, want the %s header %q", body["requestId"], requestIDHeader, requestID)
+			}
+			if len(body) != 2 {
+				t.Errorf("body %s has more than the error and requestId", w.Body)
+			}
+
+			select {
+			case ok := <-cancelled:
+				if !ok {
+					t.Error("upstream call was not cancelled")
+				}
+			case <-time.After(2 * time.Second):
+				t.Error("upstream call still running after the timeout")
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import "log"
+
+// defaultNeverCacheKinds are cache kinds that must never be stored, whatever
+// a caller asks for, because serving them stale breaks correctness: a cached
+// blockhash expires on chain while it is still being handed out, a cached
+// signature status hides a confirmation, and a cached write result would
+// skip the write. None of them is cached today; listing them means a later
+// change cannot start caching them by accident. NEVER_CACHE adds kinds.
+var defaultNeverCacheKinds = []string{
+	"latest_blockhash",
+	"signature_status",
+	"send_transaction",
+	"simulate_transaction",
+	"airdrop",
+}
+
+func newNeverCacheKinds(extra []string) map[string]bool {
+	kinds := make(map[string]bool, len(defaultNeverCacheKinds)+len(extra))
+	for _, kind := range defaultNeverCacheKinds {
+		kinds[kind] = true
+	}
+	for _, kind := range extra {
+		// Idempotency keys are a record of sends, not cached data; without
+		// them a retried send could land twice.
+		if kind == "idempotency" {
+			log.Printf("Ignoring %q in NEVER_CACHE: idempotency keys are always stored", kind)
+			continue
+		}
+		kinds[kind] = true
+	}
+	return kinds
+}
+
+// neverCached reports whether a cache key is of a kind that must not be
+// stored. The kind is the third part of a key built by cacheKeyAt.
+func (s *SolanaRPCClient) neverCached(key string) bool {
+	return s.neverCacheKinds[cacheKeyKind(key)]
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"filippo.io/edwards25519"
+	"github.com/mr-tron/base58"
+)
+
+const maxSeedLength = 32
+
+// decodePublicKey decodes a base58 address into its 32 raw bytes.
+func decodePublicKey(address string) ([]byte, error) {
+	decoded, err := base58.Decode(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	if len(decoded) != 32 {
+		return nil, fmt.Errorf("invalid address %q: expected 32 bytes, got %d", address, len(decoded))
+	}
+	return decoded, nil
+}
+
+// isValidSolanaAddress reports whether address is a base58 public key, so
+// handlers can reject junk before spending an RPC call on it.
+func isValidSolanaAddress(address string) bool {
+	_, err := decodePublicKey(address)
+	return err == nil
+}
+
+func isOnCurve(key []byte) bool {
+	_, err := new(edwards25519.Point).SetBytes(key)
+	return err == nil
+}
+
+// findProgramAddress mirrors Pubkey::find_program_address: it searches bump
+// seeds from 255 down for the first hash that is not a valid ed25519 point.
+func findProgramAddress(seeds [][]byte, programID string) (string, uint8, error) {
+	program, err := decodePublicKey(programID)
+	if err != nil {
+		return "", 0, err
+	}
+	for _, seed := range seeds {
+		if len(seed) > maxSeedLength {
+			return "", 0, fmt.Errorf("seed longer than %d bytes", maxSeedLength)
+		}
+	}
+
+	for bump := 255; bump >= 0; bump-- {
+		h := sha256.New()
+		for _, seed := range seeds {
+			h.Write(seed)
+		}
+		h.Write([]byte{byte(bump)})
+		h.Write(program)
+		h.Write([]byte("ProgramDerivedAddress"))
+		candidate := h.Sum(nil)
+
+		if !isOnCurve(candidate) {
+			return base58.Encode(candidate), uint8(bump), nil
+		}
+	}
+
+	return "", 0, fmt.Errorf("unable to find a viable program address")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	pinRefreshInterval = 15 * time.Second
+
+	// pinRefreshLead is how close to expiry a pinned entry is refreshed. It
+	// exceeds the refresh interval so entries are renewed before they lapse.
+	pinRefreshLead = pinRefreshInterval + 5*time.Second
+
+	// pinnedCacheTTL applies to data that is only cached because it is
+	// pinned, such as account info and token supply.
+	pinnedCacheTTL = 30 * time.Second
+)
+
+// isPinnedKey reports whether a cache key is about a pinned address, i.e.
+// whether its first argument after the network, commitment and kind is one.
+func (s *SolanaRPCClient) isPinnedKey(key string) bool {
+	if len(s.pinnedAddresses) == 0 {
+		return false
+	}
+	parts := strings.SplitN(key, "|", 5)
+	return len(parts) >= 4 && s.pinnedAddresses[parts[3]]
+}
+
+// setPinnedCache caches data only when it is about a pinned address. It is
+// used for lookups that are otherwise always served fresh.
+func (s *SolanaRPCClient) setPinnedCache(ctx context.Context, key string, data interface{}) {
+	if s.isPinnedKey(key) {
+		s.setCache(ctx, key, data, pinnedCacheTTL)
+	}
+}
+
+// expireSoonPinned drops the pinned entries about address that expire within
+// pinRefreshLead, so the next lookup fetches them again.
+func (s *SolanaRPCClient) expireSoonPinned(address string) {
+	deadline := time.Now().Add(pinRefreshLead)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for key, entry := range s.cache {
+		if entry.Pinned && entry.ExpiresAt.Before(deadline) && strings.SplitN(key, "|", 5)[3] == address {
+			s.deleteCacheEntry(key)
+		}
+	}
+}
+
+// refreshPinned keeps the configured mints and accounts warm regardless of
+// traffic. Each pass re-runs the same lookups the endpoints use, one address
+// at a time, so refreshes go through the usual cache keys and rate limiter.
+func (s *SolanaRPCClient) refreshPinned() {
+	ctx := context.Background()
+	ticker := time.NewTicker(pinRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, mint := range s.pinnedMints {
+			s.expireSoonPinned(mint)
+			if _, err := s.GetTokenSupply(ctx, mint); err != nil {
+				log.Printf("Pinned mint %s: failed to refresh supply: %v", mint, err)
+			}
+			if _, err := s.GetTokenMetadata(ctx, mint); err != nil {
+				log.Printf("Pinned mint %s: failed to refresh metadata: %v", mint, err)
+			}
+			if _, err := s.GetTokenHolderDistribution(ctx, mint, defaultHolderLimit, nil); err != nil {
+				log.Printf("Pinned mint %s: failed to refresh holders: %v", mint, err)
+			}
+		}
+		for _, address := range s.pinnedAccounts {
+			s.expireSoonPinned(address)
+			if _, err := s.GetAccountInfo(ctx, address); err != nil {
+				log.Printf("Pinned account %s: failed to refresh account info: %v", address, err)
+			}
+			if _, err := s.GetBalance(ctx, address); err != nil {
+				log.Printf("Pinned account %s: failed to refresh balance: %v", address, err)
+			}
+			if _, err := s.GetWalletDomains(ctx, address); err != nil {
+				log.Printf("Pinned account %s: failed to refresh domains: %v", address, err)
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// pinCache registers the mints and accounts to keep warm and starts the
+// background refresher. It must be called before the server starts.
+func (s *SolanaRPCClient) pinCache(mints, accounts []string) {
+	if len(mints) == 0 && len(accounts) == 0 {
+		return
+	}
+	s.pinnedMints = mints
+	s.pinnedAccounts = accounts
+	s.pinnedAddresses = make(map[string]bool, len(mints)+len(accounts))
+	for _, address := range append(append([]string{}, mints...), accounts...) {
+		s.pinnedAddresses[address] = true
+	}
+	go s.refreshPinned()
+}
+
+type pinnedEntry struct {
+	Key       string  `json:"key"`
+	ExpiresIn float64 `json:"expiresInSeconds"`
+}
+
+func handleCacheStats(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		expired := 0
+		pinned := []pinnedEntry{}
+
+		client.mutex.RLock()
+		entries := len(client.cache)
+		evictions := client.cacheEvictions
+		for key, entry := range client.cache {
+			if now.After(entry.ExpiresAt) {
+				expired++
+			}
+			if entry.Pinned {
+				pinned = append(pinned, pinnedEntry{Key: key, ExpiresIn: entry.ExpiresAt.Sub(now).Seconds()})
+			}
+		}
+		client.mutex.RUnlock()
+
+		sort.Slice(pinned, func(i, j int) bool { return pinned[i].Key < pinned[j].Key })
+
+		neverCached := make([]string, 0, len(client.neverCacheKinds))
+		for kind := range client.neverCacheKinds {
+			neverCached = append(neverCached, kind)
+		}
+		sort.Strings(neverCached)
+
+		respondJSON(c, http.StatusOK, gin.H{
+			"entries":        entries,
+			"expired":        expired,
+			"maxEntries":     client.cacheMaxEntries,
+			"evictions":      evictions,
+			"pinned":         pinned,
+			"pinnedMints":    client.pinnedMints,
+			"pinnedAccounts": client.pinnedAccounts,
+			"neverCached":    neverCached,
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultPortfolioDustUSD is the USD value below which priced holdings
+	// are left out of a portfolio. Override with PORTFOLIO_DUST_USD.
+	defaultPortfolioDustUSD = 0.01
+	// portfolioLookupConcurrency bounds the metadata and price lookups run
+	// at once for one portfolio.
+	portfolioLookupConcurrency = 4
+)
+
+// TokenAccount is an SPL token account as returned by
+// getTokenAccountsByOwner with jsonParsed encoding.
+type TokenAccount struct {
+	Address      string  `json:"address"`
+	Mint         string  `json:"mint"`
+	Owner        string  `json:"owner"`
+	Amount       string  `json:"amount"`
+	Decimals     int     `json:"decimals"`
+	UIAmount     float64 `json:"uiAmount"`
+	TokenProgram string  `json:"tokenProgram"`
+}
+
+type PortfolioToken struct {
+	Mint          string   `json:"mint"`
+	Name          string   `json:"name,omitempty"`
+	Symbol        string   `json:"symbol,omitempty"`
+	UIAmount      float64  `json:"uiAmount"`
+	Decimals      int      `json:"decimals"`
+	TokenProgram  string   `json:"tokenProgram"`
+	TokenAccounts []string `json:"tokenAccounts"`
+	PriceUSD      *float64 `json:"priceUsd,omitempty"`
+	USDValue      *float64 `json:"usdValue,omitempty"`
+}
+
+// Portfolio is a wallet's SOL balance and token holdings. TotalUSDValue sums
+// the SOL value and every priced holding; it is omitted when nothing could
+// be priced, and UnpricedTokens says how many holdings it leaves out.
+type Portfolio struct {
+	Address        string           `json:"address"`
+	SOLBalance     float64          `json:"solBalance"`
+	SOLUSDValue    *float64         `json:"solUsdValue,omitempty"`
+	Tokens         []PortfolioToken `json:"tokens"`
+	TotalUSDValue  *float64         `json:"totalUsdValue,omitempty"`
+	UnpricedTokens int              `json:"unpricedTokens"`
+	DustExcluded   int              `json:"dustExcluded"`
+}
+
+// GetTokenAccountsByOwner returns the owner's token accounts for mint, or
+// when mint is empty, under both the Token and Token-2022 programs, one call
+// each. A mint that is not a token mint returns errNotTokenMint. Like
+// balances, the accounts are only cached for pinned owners.
+func (s *SolanaRPCClient) GetTokenAccountsByOwner(ctx context.Context, owner, mint string) ([]TokenAccount, error) {
+	cacheKey := s.cacheKey("token_accounts", owner, mint)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if accounts, ok := cached.([]TokenAccount); ok {
+			return accounts, nil
+		}
+	}
+
+	accounts := []TokenAccount{}
+	if mint != "" {
+		resp, err := s.queryTokenAccountsByOwner(ctx, owner, map[string]interface{}{"mint": mint})
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error != nil && isInvalidParams(resp.Error) {
+			return nil, errNotTokenMint
+		}
+		if accounts, _, err = parseTokenAccounts(resp); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, program := range []string{tokenProgramID, token2022ProgramID} {
+			fetched, err := s.getTokenAccountsByProgram(ctx, owner, program)
+			if err != nil {
+				return nil, err
+			}
+			accounts = append(accounts, fetched...)
+		}
+	}
+
+	s.setPinnedCache(ctx, cacheKey, accounts)
+
+	return accounts, nil
+}
+
+func (s *SolanaRPCClient) getTokenAccountsByProgram(ctx context.Context, owner, program string) ([]TokenAccount, error) {
+	resp, err := s.queryTokenAccountsByOwner(ctx, owner, map[string]interface{}{"programId": program})
+	if err != nil {
+		return nil, err
+	}
+	accounts, _, err := parseTokenAccounts(resp)
+	return accounts, err
+}
+
+// queryTokenAccountsByOwner calls getTokenAccountsByOwner with filter, which
+// selects the accounts by programId or by mint. RPC errors are left in the
+// response for the caller.
+func (s *SolanaRPCClient) queryTokenAccountsByOwner(ctx context.Context, owner string, filter map[string]interface{}) (*RPCResponse, error) {
+	params := []interface{}{
+		owner,
+		filter,
+		map[string]interface{}{"encoding": "jsonParsed"},
+	}
+	return s.makeRPCCallWithRetry(ctx, "getTokenAccountsByOwner", params)
+}
+
+// parseTokenAccounts reads a jsonParsed getTokenAccountsByOwner response,
+// returning the accounts and the slot they were read at. Each account's
+// program is the owner the node reports for it.
+func parseTokenAccounts(resp *RPCResponse) ([]TokenAccount, uint64, error) {
+	if resp.Error != nil {
+		return nil, 0, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, contextSlot, err := unwrapContext("getTokenAccountsByOwner", resp.Result)
+	if err != nil {
+		return nil, 0, err
+	}
+	values, ok := result.([]interface{})
+	if !ok {
+		return nil, 0, &ParseError{Method: "getTokenAccountsByOwner", Detail: "value is not an array"}
+	}
+
+	accounts := make([]TokenAccount, 0, len(values))
+	for _, value := range values {
+		entry, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		address, _ := entry["pubkey"].(string)
+		account, _ := entry["account"].(map[string]interface{})
+		data, _ := account["data"].(map[string]interface{})
+		parsed, _ := data["parsed"].(map[string]interface{})
+		info, _ := parsed["info"].(map[string]interface{})
+		tokenAmount, _ := info["tokenAmount"].(map[string]interface{})
+
+		mint, _ := info["mint"].(string)
+		if address == "" || mint == "" {
+			continue
+		}
+		accountOwner, _ := info["owner"].(string)
+		program, _ := account["owner"].(string)
+		amount, _ := tokenAmount["amount"].(string)
+		decimals, _ := tokenAmount["decimals"].(float64)
+		// uiAmount is null for amounts a float cannot hold exactly; the
+		// string form is always present.
+		uiAmountString, _ := tokenAmount["uiAmountString"].(string)
+		uiAmount, err := strconv.ParseFloat(uiAmountString, 64)
+		if err != nil {
+			uiAmount, _ = tokenAmount["uiAmount"].(float64)
+		}
+
+		accounts = append(accounts, TokenAccount{
+			Address:      address,
+			Mint:         mint,
+			Owner:        accountOwner,
+			Amount:       amount,
+			Decimals:     int(decimals),
+			UIAmount:     uiAmount,
+			TokenProgram: program,
+		})
+	}
+
+	return accounts, contextSlot, nil
+}
+
+// GetPortfolio combines the SOL balance, the token accounts, and each
+// mint's metadata and USD price. Accounts of the same mint are merged into
+// one holding and empty accounts are dropped. Metadata and prices are looked
+// up per mint by portfolioLookupConcurrency workers; a failed lookup only
+// leaves its fields empty. Priced holdings worth less than portfolioDustUSD
+// are excluded and counted in DustExcluded.
+func (s *SolanaRPCClient) GetPortfolio(ctx context.Context, address string) (*Portfolio, error) {
+	balance, err := s.GetBalance(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.GetTokenAccountsByOwner(ctx, address, "")
+	if err != nil {
+		return nil, err
+	}
+
+	holdings := []*PortfolioToken{}
+	byMint := make(map[string]*PortfolioToken)
+	for _, account := range accounts {
+		if account.UIAmount <= 0 {
+			continue
+		}
+		holding, ok := byMint[account.Mint]
+		if !ok {
+			holding = &PortfolioToken{
+				Mint:          account.Mint,
+				Decimals:      account.Decimals,
+				TokenProgram:  account.TokenProgram,
+				TokenAccounts: []string{},
+			}
+			byMint[account.Mint] = holding
+			holdings = append(holdings, holding)
+		}
+		holding.UIAmount += account.UIAmount
+		holding.TokenAccounts = append(holding.TokenAccounts, account.Address)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, portfolioLookupConcurrency)
+	for _, holding := range holdings {
+		wg.Add(1)
+		sem <- struct{}{}
+		// Each worker only writes its own holding.
+		go func(holding *PortfolioToken) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if metadata, err := s.GetTokenMetadata(ctx, holding.Mint); err == nil {
+				holding.Name = metadata.Name
+				holding.Symbol = metadata.Symbol
+			} else if !errors.Is(err, errNoMetadata) {
+				log.Printf("Portfolio %s: failed to get metadata for %s: %v", address, holding.Mint, err)
+			}
+
+			price, err := s.GetTokenPrice(ctx, holding.Mint)
+			if err != nil {
+				if !errors.Is(err, errNoTokenPrice) && !errors.Is(err, errPriceDisabled) {
+					log.Printf("Portfolio %s: failed to get price for %s: %v", address, holding.Mint, err)
+				}
+				return
+			}
+			priceUSD := price.Price
+			value := jsonSafeFloat(holding.UIAmount * priceUSD)
+			holding.PriceUSD = &priceUSD
+			holding.USDValue = &value
+		}(holding)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	portfolio := &Portfolio{
+		Address:    address,
+		SOLBalance: balance,
+		Tokens:     []PortfolioToken{},
+	}
+
+	var total float64
+	priced := false
+	if value, err := s.usdValue(ctx, balance); err == nil {
+		portfolio.SOLUSDValue = value
+		total += *value
+		priced = true
+	} else if !errors.Is(err, errPriceDisabled) {
+		log.Printf("Portfolio %s: failed to get SOL price: %v", address, err)
+	}
+
+	for _, holding := range holdings {
+		if holding.USDValue == nil {
+			portfolio.UnpricedTokens++
+		} else if *holding.USDValue < s.portfolioDustUSD {
+			portfolio.DustExcluded++
+			continue
+		} else {
+			total += *holding.USDValue
+			priced = true
+		}
+		portfolio.Tokens = append(portfolio.Tokens, *holding)
+	}
+	if priced {
+		total = jsonSafeFloat(total)
+		portfolio.TotalUSDValue = &total
+	}
+
+	// Most valuable first; unpriced holdings follow, largest amount first.
+	sort.SliceStable(portfolio.Tokens, func(i, j int) bool {
+		a, b := portfolio.Tokens[i], portfolio.Tokens[j]
+		if (a.USDValue == nil) != (b.USDValue == nil) {
+			return a.USDValue != nil
+		}
+		if a.USDValue != nil && *a.USDValue != *b.USDValue {
+			return *a.USDValue > *b.USDValue
+		}
+		return a.UIAmount > b.UIAmount
+	})
+
+	return portfolio, nil
+}
+
+func handlePortfolio(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		address := c.Param("address")
+		if _, err := decodePublicKey(address); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+			return
+		}
+
+		portfolio, err := client.GetPortfolio(c.Request.Context(), address)
+		if err != nil {
+			log.Printf("Error getting portfolio for %s: %v", address, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get portfolio"})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, portfolio)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	priceSourceCoinGecko = "coingecko"
+	priceSourcePyth      = "pyth"
+
+	defaultPriceCacheTTL = 30 * time.Second
+	priceFetchTimeout    = 5 * time.Second
+
+	coinGeckoSOLPriceURL = "https://api.coingecko.com/api/v3/simple/price?ids=solana&vs_currencies=usd"
+
+	// defaultPythSOLUSDAccount is the sponsored SOL/USD PriceUpdateV2
+	// account maintained by the Pyth receiver program.
+	defaultPythSOLUSDAccount = "7UVimffxr9ow1uXYxsr4LHAcV58mLzhmwaeKvJ1pjLiE"
+	pythReceiverProgramID    = "rec5EKMGg6MxZYaMdyBfgwp4d5rB9T1VQH5pJv5LtFJ"
+
+	tokenPriceSourceJupiter = "jupiter"
+	defaultJupiterPriceURL  = "https://lite-api.jup.ag/price/v3"
+)
+
+var (
+	errPriceDisabled = errors.New("price oracle not configured")
+	errNoTokenPrice  = errors.New("no price data for token")
+)
+
+type SOLPrice struct {
+	Price       float64 `json:"price"`
+	Confidence  float64 `json:"confidence,omitempty"`
+	PublishTime *int64  `json:"publishTime,omitempty"`
+	Source      string  `json:"source"`
+}
+
+// GetSOLPrice returns the SOL/USD price from the configured source, cached
+// for priceCacheTTL. It fails with errPriceDisabled when no source is set.
+func (s *SolanaRPCClient) GetSOLPrice(ctx context.Context) (*SOLPrice, error) {
+	if s.priceSource == "" {
+		return nil, errPriceDisabled
+	}
+
+	cacheKey := s.cacheKey("sol_price", s.priceSource)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if price, ok := cached.(*SOLPrice); ok {
+			return price, nil
+		}
+	}
+
+	var price *SOLPrice
+	var err error
+	switch s.priceSource {
+	case priceSourcePyth:
+		price, err = s.fetchPythSOLPrice(ctx)
+	case priceSourceCoinGecko:
+		price, err = fetchCoinGeckoSOLPrice(ctx)
+	default:
+		err = fmt.Errorf("unknown price source %q", s.priceSource)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.setCache(ctx, cacheKey, price, s.priceCacheTTL)
+
+	return price, nil
+}
+
+func fetchCoinGeckoSOLPrice(ctx context.Context) (*SOLPrice, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, coinGeckoSOLPriceURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{Timeout: priceFetchTimeout}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("coingecko returned HTTP %d", resp.StatusCode)
+	}
+
+	var body map[string]map[string]float64
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid coingecko response: %w", err)
+	}
+	usd, ok := body["solana"]["usd"]
+	if !ok || usd <= 0 {
+		return nil, fmt.Errorf("invalid coingecko response: missing solana.usd")
+	}
+
+	return &SOLPrice{Price: usd, Source: priceSourceCoinGecko}, nil
+}
+
+// fetchPythSOLPrice reads a Pyth PriceUpdateV2 account: an 8-byte
+// discriminator, the write authority, a verification level (one byte, plus a
+// signature count for partial verification) and the price message.
+func (s *SolanaRPCClient) fetchPythSOLPrice(ctx context.Context) (*SOLPrice, error) {
+	account, err := s.GetAccountInfoWithOptions(ctx, s.pythPriceAccount, AccountInfoOptions{DataEncoding: "base64"})
+	if err != nil {
+		return nil, err
+	}
+	if !account.IsValid {
+		return nil, fmt.Errorf("pyth price account %s not found", s.pythPriceAccount)
+	}
+	if account.Owner != pythReceiverProgramID {
+		return nil, fmt.Errorf("account %s is not owned by the pyth receiver program", s.pythPriceAccount)
+	}
+	data, err := base64.StdEncoding.DecodeString(account.Data)
+	if err != nil {
+		return nil, &ParseError{Method: "getAccountInfo", Detail: "account data is not base64"}
+	}
+
+	offset := 8 + 32
+	if len(data) <= offset {
+		return nil, fmt.Errorf("pyth price account too short")
+	}
+	if data[offset] == 0 {
+		offset += 2 // Partial { num_signatures: u8 }
+	} else {
+		offset++ // Full
+	}
+	offset += 32 // feed id
+
+	if len(data) < offset+36 {
+		return nil, fmt.Errorf("pyth price account too short")
+	}
+	rawPrice := int64(binary.LittleEndian.Uint64(data[offset:]))
+	rawConf := binary.LittleEndian.Uint64(data[offset+8:])
+	exponent := int32(binary.LittleEndian.Uint32(data[offset+16:]))
+	publishTime := int64(binary.LittleEndian.Uint64(data[offset+20:]))
+
+	scale := math.Pow10(int(exponent))
+	price := jsonSafeFloat(float64(rawPrice) * scale)
+	if price <= 0 {
+		return nil, fmt.Errorf("pyth price account holds no usable price")
+	}
+	return &SOLPrice{
+		Price:       price,
+		Confidence:  jsonSafeFloat(float64(rawConf) * scale),
+		PublishTime: &publishTime,
+		Source:      priceSourcePyth,
+	}, nil
+}
+
+// usdValue prices a SOL amount for responses that asked for ?usd=true. The
+// error is reported to the client instead of failing the request.
+func (s *SolanaRPCClient) usdValue(ctx context.Context, sol float64) (*float64, error) {
+	price, err := s.GetSOLPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	value := jsonSafeFloat(sol * price.Price)
+	return &value, nil
+}
+
+type TokenPrice struct {
+	Mint   string  `json:"mint"`
+	Price  float64 `json:"price"`
+	Source string  `json:"source"`
+}
+
+// GetTokenPrice returns the USD price of an SPL token, cached per mint for
+// priceCacheTTL. Tokens the aggregator has no price for fail with
+// errNoTokenPrice, and that answer is cached too.
+func (s *SolanaRPCClient) GetTokenPrice(ctx context.Context, mint string) (*TokenPrice, error) {
+	if s.tokenPriceSource == "" {
+		return nil, errPriceDisabled
+	}
+
+	cacheKey := s.cacheKey("token_price", s.tokenPriceSource, mint)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if price, ok := cached.(*TokenPrice); ok {
+			if price == nil {
+				return nil, errNoTokenPrice
+			}
+			return price, nil
+		}
+	}
+
+	price, err := s.fetchJupiterTokenPrice(ctx, mint)
+	if errors.Is(err, errNoTokenPrice) {
+		s.setCache(ctx, cacheKey, (*TokenPrice)(nil), s.priceCacheTTL)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.setCache(ctx, cacheKey, price, s.priceCacheTTL)
+
+	return price, nil
+}
+
+// fetchJupiterTokenPrice queries the Jupiter price API, which answers with
+// an object keyed by mint and simply leaves out mints it cannot price.
+func (s *SolanaRPCClient) fetchJupiterTokenPrice(ctx context.Context, mint string) (*TokenPrice, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.tokenPriceURL+"?ids="+url.QueryEscape(mint), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{Timeout: priceFetchTimeout}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jupiter returned HTTP %d", resp.StatusCode)
+	}
+
+	var body map[string]struct {
+		USDPrice float64 `json:"usdPrice"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid jupiter response: %w", err)
+	}
+	entry, ok := body[mint]
+	if !ok || entry.USDPrice <= 0 {
+		return nil, errNoTokenPrice
+	}
+
+	return &TokenPrice{Mint: mint, Price: entry.USDPrice, Source: tokenPriceSourceJupiter}, nil
+}
+
+// tokenUSDPrice returns the mint's price when the request asked for
+// ?usd=true and one is available. Tokens without a price simply get no USD
+// fields.
+func (s *SolanaRPCClient) tokenUSDPrice(c *gin.Context, mint string) (float64, bool) {
+	if c.Query("usd") != "true" {
+		return 0, false
+	}
+	price, err := s.GetTokenPrice(c.Request.Context(), mint)
+	if err != nil {
+		if !errors.Is(err, errNoTokenPrice) && !errors.Is(err, errPriceDisabled) {
+			log.Printf("Failed to get token price for %s: %v", mint, err)
+		}
+		return 0, false
+	}
+	return price.Price, true
+}
+
+func handleSOLPrice(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		price, err := client.GetSOLPrice(c.Request.Context())
+		if errors.Is(err, errPriceDisabled) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No price source is configured"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to get SOL price", "details": err.Error()})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, price)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultMaxProgramAccounts caps the accounts returned by
+	// /api/program/:programId/accounts, since an unfiltered scan of a busy
+	// program can match millions. Override with PROGRAM_ACCOUNTS_MAX.
+	defaultMaxProgramAccounts = 1000
+	// programAccountsCacheTTL is short: the accounts change with every
+	// transaction, but repeated identical scans are expensive.
+	programAccountsCacheTTL = 30 * time.Second
+	// maxProgramAccountsFilters is the most filters the RPC accepts.
+	maxProgramAccountsFilters   = 4
+	maxProgramAccountsBodyBytes = 64 * 1024
+)
+
+type ProgramAccount struct {
+	Pubkey     string  `json:"pubkey"`
+	Lamports   BigUint `json:"lamports"`
+	Balance    float64 `json:"balance"`
+	Owner      string  `json:"owner"`
+	Executable bool    `json:"executable"`
+	Data       string  `json:"data"`
+}
+
+type ProgramAccountsOptions struct {
+	Filters   []interface{}
+	DataSlice *DataSlice
+}
+
+func (s *SolanaRPCClient) GetProgramAccounts(ctx context.Context, programID string, filters []interface{}) ([]ProgramAccount, error) {
+	return s.GetProgramAccountsWithOptions(ctx, programID, ProgramAccountsOptions{Filters: filters})
+}
+
+// GetProgramAccountsWithOptions calls getProgramAccounts with base64 encoding,
+// or base64+zstd with RPC_ZSTD_ACCOUNT_DATA. Data is returned as base64,
+// limited to the requested slice.
+func (s *SolanaRPCClient) GetProgramAccountsWithOptions(ctx context.Context, programID string, opts ProgramAccountsOptions) ([]ProgramAccount, error) {
+	encoding := s.accountDataEncoding("")
+	config := map[string]interface{}{"encoding": encoding}
+	if len(opts.Filters) > 0 {
+		config["filters"] = opts.Filters
+	}
+	if opts.DataSlice != nil {
+		config["dataSlice"] = opts.DataSlice
+	}
+
+	resp, err := s.makeRPCCallWithRetry(ctx, "getProgramAccounts", []interface{}{programID, config})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	entries, ok := resp.Result.([]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getProgramAccounts", Detail: "result is not an array"}
+	}
+
+	accounts := make([]ProgramAccount, 0, len(entries))
+	for _, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		account, ok := fields["account"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		programAccount := ProgramAccount{}
+		programAccount.Pubkey, _ = fields["pubkey"].(string)
+		programAccount.Owner, _ = account["owner"].(string)
+		programAccount.Executable, _ = account["executable"].(bool)
+		if lamports, ok := account["lamports"].(float64); ok {
+			programAccount.Lamports = BigUint(lamports)
+			programAccount.Balance = lamports / 1e9
+		}
+		if encoding == zstdEncoding {
+			decoded, err := decodeAccountBytes(account["data"])
+			if err != nil {
+				return nil, err
+			}
+			programAccount.Data = base64.StdEncoding.EncodeToString(decoded)
+		} else if data, ok := account["data"].([]interface{}); ok && len(data) > 0 {
+			programAccount.Data, _ = data[0].(string)
+		}
+		accounts = append(accounts, programAccount)
+	}
+
+	return accounts, nil
+}
+
+// ProgramAccountsFilter is one getProgramAccounts filter, either dataSize or
+// memcmp.
+type ProgramAccountsFilter struct {
+	DataSize *uint64       `json:"dataSize,omitempty"`
+	Memcmp   *MemcmpFilter `json:"memcmp,omitempty"`
+}
+
+// MemcmpFilter matches accounts whose data holds Bytes at Offset. Bytes are
+// base58 unless Encoding is base64.
+type MemcmpFilter struct {
+	Offset   uint64 `json:"offset"`
+	Bytes    string `json:"bytes"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type ProgramAccountsRequest struct {
+	Filters   []ProgramAccountsFilter `json:"filters"`
+	DataSlice *DataSlice              `json:"dataSlice"`
+}
+
+// ProgramAccounts is a program's matching accounts, cut off at the server's
+// cap. Total is how many matched and Truncated is set when it exceeds the
+// accounts returned.
+type ProgramAccounts struct {
+	ProgramID string           `json:"programId"`
+	Accounts  []ProgramAccount `json:"accounts"`
+	Count     int              `json:"count"`
+	Total     int              `json:"total"`
+	Truncated bool             `json:"truncated"`
+}
+
+func (req *ProgramAccountsRequest) validate() error {
+	if len(req.Filters) > maxProgramAccountsFilters {
+		return fmt.Errorf("at most %d filters are allowed", maxProgramAccountsFilters)
+	}
+	for i, filter := range req.Filters {
+		if (filter.DataSize == nil) == (filter.Memcmp == nil) {
+			return fmt.Errorf("filter %d must set exactly one of dataSize and memcmp", i)
+		}
+		if memcmp := filter.Memcmp; memcmp != nil {
+			if memcmp.Bytes == "" {
+				return fmt.Errorf("filter %d: memcmp bytes are required", i)
+			}
+			if memcmp.Encoding != "" && memcmp.Encoding != "base58" && memcmp.Encoding != "base64" {
+				return fmt.Errorf("filter %d: memcmp encoding must be base58 or base64", i)
+			}
+		}
+	}
+	if req.DataSlice != nil && (req.DataSlice.Offset < 0 || req.DataSlice.Length < 0) {
+		return fmt.Errorf("dataSlice offset and length must not be negative")
+	}
+	return nil
+}
+
+// GetFilteredProgramAccounts runs a getProgramAccounts scan for the request
+// and keeps the first maxProgramAccounts matches. Results are cached briefly
+// under a hash of the filters and data slice.
+func (s *SolanaRPCClient) GetFilteredProgramAccounts(ctx context.Context, programID string, req ProgramAccountsRequest) (*ProgramAccounts, error) {
+	encoded, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(encoded)
+	cacheKey := s.cacheKey("program_accounts", programID, hex.EncodeToString(hash[:]))
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if accounts, ok := cached.(*ProgramAccounts); ok {
+			return accounts, nil
+		}
+	}
+
+	filters := make([]interface{}, len(req.Filters))
+	for i, filter := range req.Filters {
+		filters[i] = filter
+	}
+	accounts, err := s.GetProgramAccountsWithOptions(ctx, programID, ProgramAccountsOptions{Filters: filters, DataSlice: req.DataSlice})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ProgramAccounts{ProgramID: programID, Accounts: accounts, Total: len(accounts)}
+	if len(accounts) > s.maxProgramAccounts {
+		result.Accounts = accounts[:s.maxProgramAccounts:s.maxProgramAccounts]
+		result.Truncated = true
+	}
+	result.Count = len(result.Accounts)
+
+	s.setCache(ctx, cacheKey, result, programAccountsCacheTTL)
+
+	return result, nil
+}
+
+func handleProgramAccounts(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		programID := c.Param("programId")
+		if _, err := decodePublicKey(programID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxProgramAccountsBodyBytes)
+		var req ProgramAccountsRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+				return
+			}
+		}
+		if err := req.validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filters", "details": err.Error()})
+			return
+		}
+
+		accounts, err := client.GetFilteredProgramAccounts(c.Request.Context(), programID, req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get program accounts", "details": err.Error()})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, accounts)
+	}
+}
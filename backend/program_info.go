@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	bpfLoaderDeprecatedID  = "BPFLoader1111111111111111111111111111111111"
+	bpfLoaderID            = "BPFLoader2111111111111111111111111111111111"
+	bpfLoaderUpgradeableID = "BPFLoaderUpgradeab1e11111111111111111111111"
+)
+
+const (
+	// upgradeableProgramTag and upgradeableProgramDataTag are the
+	// UpgradeableLoaderState variants of a program account and of its
+	// ProgramData account.
+	upgradeableProgramTag     = 2
+	upgradeableProgramDataTag = 3
+	// upgradeableProgramSize is a program account's size: the tag and the
+	// ProgramData address.
+	upgradeableProgramSize = 36
+	// programDataHeaderSize is the ProgramData prefix before the program
+	// bytes: the tag, the deploy slot and the optional upgrade authority.
+	programDataHeaderSize = 45
+	programInfoCacheTTL   = 30 * time.Second
+)
+
+var (
+	errProgramNotFound = errors.New("program account not found")
+	errNotProgram      = errors.New("account is not an executable program")
+	errUnknownLoader   = errors.New("program is owned by an unsupported loader")
+)
+
+// ProgramInfo is who can change a program and when it last changed.
+// UpgradeAuthority is null for programs that can no longer be upgraded:
+// upgradeable ones whose authority was removed and every program of the
+// legacy loaders; Upgradeable is set when it is not. LastDeploySlot is only
+// known for programs of the upgradeable loader.
+type ProgramInfo struct {
+	ProgramID          string   `json:"programId"`
+	Loader             string   `json:"loader"`
+	Upgradeable        bool     `json:"upgradeable"`
+	ProgramDataAddress string   `json:"programDataAddress,omitempty"`
+	UpgradeAuthority   *string  `json:"upgradeAuthority"`
+	LastDeploySlot     *BigUint `json:"lastDeploySlot,omitempty"`
+	// ProgramDataLength is the size of the program itself, without the
+	// ProgramData header.
+	ProgramDataLength int `json:"programDataLength"`
+}
+
+// GetProgramInfo reads a program account and, for the upgradeable loader,
+// its ProgramData account, whose address is derived from the program id.
+// Only the account headers are fetched, never the program bytes. Only a
+// null program account is errProgramNotFound; a failed lookup is returned
+// as is.
+func (s *SolanaRPCClient) GetProgramInfo(ctx context.Context, programID string) (*ProgramInfo, error) {
+	cacheKey := s.cacheKey("program_info", programID)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if info, ok := cached.(*ProgramInfo); ok {
+			return info, nil
+		}
+	}
+
+	program, err := s.getAccountHeader(ctx, programID, upgradeableProgramSize)
+	if err != nil {
+		return nil, err
+	}
+	if !program.account.IsValid {
+		return nil, errProgramNotFound
+	}
+	if !program.account.Executable {
+		return nil, errNotProgram
+	}
+
+	info := &ProgramInfo{ProgramID: programID, Loader: program.account.Owner}
+	switch program.account.Owner {
+	case bpfLoaderDeprecatedID, bpfLoaderID:
+		info.ProgramDataLength = program.account.DataLength
+	case bpfLoaderUpgradeableID:
+		if err := s.readProgramData(ctx, info, program.data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errUnknownLoader
+	}
+
+	s.setCache(ctx, cacheKey, info, programInfoCacheTTL)
+
+	return info, nil
+}
+
+// readProgramData fills in the upgradeable-loader fields of info from the
+// program account's data and its ProgramData account.
+func (s *SolanaRPCClient) readProgramData(ctx context.Context, info *ProgramInfo, programData []byte) error {
+	program, err := decodePublicKey(info.ProgramID)
+	if err != nil {
+		return err
+	}
+	programDataAddress, _, err := findProgramAddress([][]byte{program}, bpfLoaderUpgradeableID)
+	if err != nil {
+		return err
+	}
+
+	reader := &borshReader{data: programData}
+	if tag, err := reader.u32(); err != nil || tag != upgradeableProgramTag {
+		return fmt.Errorf("invalid upgradeable program account")
+	}
+	if stored, err := reader.pubkey(); err != nil || stored != programDataAddress {
+		return fmt.Errorf("program account does not point at its ProgramData account %s", programDataAddress)
+	}
+
+	header, err := s.getAccountHeader(ctx, programDataAddress, programDataHeaderSize)
+	if err != nil {
+		return err
+	}
+	if !header.account.IsValid {
+		return fmt.Errorf("ProgramData account %s not found", programDataAddress)
+	}
+
+	reader = &borshReader{data: header.data}
+	tag, err := reader.u32()
+	if err != nil || tag != upgradeableProgramDataTag {
+		return fmt.Errorf("invalid ProgramData account")
+	}
+	slot, err := reader.u64()
+	if err != nil {
+		return fmt.Errorf("invalid ProgramData account: %w", err)
+	}
+	hasAuthority, err := reader.u8()
+	if err != nil {
+		return fmt.Errorf("invalid ProgramData account: %w", err)
+	}
+	if hasAuthority == 1 {
+		authority, err := reader.pubkey()
+		if err != nil {
+			return fmt.Errorf("invalid ProgramData account: %w", err)
+		}
+		info.UpgradeAuthority = &authority
+	}
+
+	lastDeploySlot := BigUint(slot)
+	info.Upgradeable = info.UpgradeAuthority != nil
+	info.ProgramDataAddress = programDataAddress
+	info.LastDeploySlot = &lastDeploySlot
+	if header.account.DataLength > programDataHeaderSize {
+		info.ProgramDataLength = header.account.DataLength - programDataHeaderSize
+	}
+	return nil
+}
+
+type accountHeader struct {
+	account *AccountInfo
+	data    []byte
+}
+
+// getAccountHeader fetches an account with only the first length bytes of
+// its data. The account's DataLength is still its full size.
+func (s *SolanaRPCClient) getAccountHeader(ctx context.Context, address string, length int) (*accountHeader, error) {
+	account, err := s.GetAccountInfoWithOptions(ctx, address, AccountInfoOptions{
+		DataEncoding: "base64",
+		DataSlice:    &DataSlice{Offset: 0, Length: length},
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(account.Data)
+	if err != nil {
+		return nil, &ParseError{Method: "getAccountInfo", Detail: "account data is not base64"}
+	}
+	return &accountHeader{account: account, data: data}, nil
+}
+
+func handleProgramInfo(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		programID := c.Param("programId")
+		if _, err := decodePublicKey(programID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+			return
+		}
+
+		info, err := client.GetProgramInfo(c.Request.Context(), programID)
+		switch {
+		case errors.Is(err, errProgramNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+			return
+		case errors.Is(err, errNotProgram):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Account is not a program"})
+			return
+		case errors.Is(err, errUnknownLoader):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Program loader is not supported"})
+			return
+		case err != nil:
+			log.Printf("Error getting program info for %s: %v", programID, err)
+			respondRPCFailure(c, "Failed to get program info", err)
+			return
+		}
+
+		respondJSON(c, http.StatusOK, info)
+	}
+}
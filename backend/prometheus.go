@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus metrics served at /metrics. Labels are bounded: RPC methods and
+// cache kinds come from the code, and HTTP routes are the registered
+// patterns rather than the request paths.
+var (
+	rpcCallsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solgogo_rpc_calls_total",
+		Help: "RPC calls sent upstream, by method.",
+	}, []string{"method"})
+	rpcErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solgogo_rpc_errors_total",
+		Help: "Failed RPC calls by method and type: transport for network and HTTP failures, rpc for JSON-RPC errors.",
+	}, []string{"method", "type"})
+	rpcLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "solgogo_rpc_duration_seconds",
+		Help:    "Time taken by RPC calls sent upstream, by method; JSON-RPC batches are recorded as method batch.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+	cacheHitsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solgogo_cache_hits_total",
+		Help: "Cache lookups that were served from the cache, by kind.",
+	}, []string{"kind"})
+	cacheMissesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solgogo_cache_misses_total",
+		Help: "Cache lookups that found nothing usable, by kind.",
+	}, []string{"kind"})
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "solgogo_http_request_duration_seconds",
+		Help:    "Time taken to answer HTTP requests, by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// observeRPC records one upstream call. A nil resp with a nil err is a call
+// whose outcome is counted elsewhere, such as a batch.
+func observeRPC(method string, started time.Time, resp *RPCResponse, err error) {
+	rpcCallsTotal.WithLabelValues(method).Inc()
+	rpcLatency.WithLabelValues(method).Observe(time.Since(started).Seconds())
+	switch {
+	case err != nil:
+		rpcErrorsTotal.WithLabelValues(method, "transport").Inc()
+	case resp != nil && resp.Error != nil:
+		rpcErrorsTotal.WithLabelValues(method, "rpc").Inc()
+	}
+}
+
+func observeCacheLookup(key string, hit bool) {
+	if hit {
+		cacheHitsTotal.WithLabelValues(cacheKeyKind(key)).Inc()
+	} else {
+		cacheMissesTotal.WithLabelValues(cacheKeyKind(key)).Inc()
+	}
+}
+
+// prometheusMiddleware times every request. Requests matching no route are
+// grouped under "unmatched" so that scanners cannot create label values.
+func prometheusMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Observe(time.Since(started).Seconds())
+	}
+}
+
+func handlePrometheusMetrics() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
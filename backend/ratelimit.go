@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// defaultRPCRate and defaultRPCBurst size the global token bucket every
+// RPC call draws from unless RPC_RATE and RPC_BURST say otherwise.
+const (
+	defaultRPCRate  = 10
+	defaultRPCBurst = 20
+)
+
+const (
+	// minCallInterval is the spacing a method gets on its first 429, and the
+	// shortest it can loosen to before it is unthrottled again.
+	minCallInterval = 100 * time.Millisecond
+	maxCallInterval = 30 * time.Second
+	// loosenAfter is how long a method must go without a 429 before its
+	// interval is shortened again.
+	loosenAfter = 30 * time.Second
+	// throttleWindow is how far back 429s are counted for reporting.
+	throttleWindow = 5 * time.Minute
+)
+
+type methodLimit struct {
+	interval   time.Duration
+	next       time.Time
+	lastChange time.Time
+	lastCall   time.Time
+	throttles  []time.Time
+}
+
+// adaptiveLimiter spaces calls to each RPC method once the provider has
+// throttled it. Methods start unthrottled; the first upstream 429 spaces
+// calls minCallInterval apart, every further one doubles the spacing, and
+// each loosenAfter period without one shrinks it by a quarter until the
+// method is unthrottled again, so it settles just under the provider's
+// actual limit.
+type adaptiveLimiter struct {
+	mutex   sync.Mutex
+	methods map[string]*methodLimit
+}
+
+func newAdaptiveLimiter() *adaptiveLimiter {
+	return &adaptiveLimiter{methods: make(map[string]*methodLimit)}
+}
+
+func (l *adaptiveLimiter) limit(method string) *methodLimit {
+	m, exists := l.methods[method]
+	if !exists {
+		m = &methodLimit{lastChange: time.Now()}
+		l.methods[method] = m
+	}
+	return m
+}
+
+// reserve books the next call slot for method and returns how long the
+// caller must wait before making the call. When the wait would exceed
+// maxWait nothing is booked and ok is false; a negative maxWait books any
+// slot, leaving the caller's context to bound the wait.
+func (l *adaptiveLimiter) reserve(method string, maxWait time.Duration) (wait time.Duration, ok bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	m := l.limit(method)
+	now := time.Now()
+	start := m.next
+	if start.Before(now) {
+		start = now
+	}
+	wait = start.Sub(now)
+	if maxWait >= 0 && wait > maxWait {
+		return wait, false
+	}
+	m.next = start.Add(m.interval)
+	m.lastCall = start
+	return wait, true
+}
+
+// throttled tightens method after an upstream 429. No call is allowed
+// before retryAfter has elapsed, when the server provided one.
+func (l *adaptiveLimiter) throttled(method string, retryAfter time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	m := l.limit(method)
+	now := time.Now()
+	m.interval *= 2
+	if m.interval < minCallInterval {
+		m.interval = minCallInterval
+	}
+	if m.interval > maxCallInterval {
+		m.interval = maxCallInterval
+	}
+	m.lastChange = now
+	if resume := now.Add(retryAfter); m.next.Before(resume) {
+		m.next = resume
+	}
+	m.throttles = append(pruneBefore(m.throttles, now.Add(-throttleWindow)), now)
+}
+
+// pruneBefore drops the times before cutoff from a chronological list.
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := sort.Search(len(times), func(i int) bool { return !times[i].Before(cutoff) })
+	return times[i:]
+}
+
+// succeeded loosens method once it has gone loosenAfter without a 429, and
+// unthrottles it when the spacing would drop below minCallInterval.
+func (l *adaptiveLimiter) succeeded(method string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	m := l.limit(method)
+	now := time.Now()
+	if m.interval == 0 || now.Sub(m.lastChange) < loosenAfter {
+		return
+	}
+	m.interval = m.interval * 3 / 4
+	if m.interval < minCallInterval {
+		m.interval = 0
+	}
+	m.lastChange = now
+}
+
+// MethodThrottle is one method's limiter state. IntervalMs and
+// CallsPerSecond are 0 while the method is unthrottled.
+type MethodThrottle struct {
+	Method          string     `json:"method"`
+	LastCall        *time.Time `json:"lastCall"`
+	CoolingDown     bool       `json:"coolingDown"`
+	NextSlotIn      float64    `json:"nextSlotInSeconds"`
+	IntervalMs      int64      `json:"intervalMs"`
+	CallsPerSecond  float64    `json:"callsPerSecond"`
+	RecentThrottles int        `json:"recent429s"`
+}
+
+// snapshot reports every method's limiter state, sorted by method. A method
+// is cooling down while its next call slot is in the future.
+func (l *adaptiveLimiter) snapshot() []MethodThrottle {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	throttles := make([]MethodThrottle, 0, len(l.methods))
+	for method, m := range l.methods {
+		m.throttles = pruneBefore(m.throttles, now.Add(-throttleWindow))
+		entry := MethodThrottle{
+			Method:          method,
+			CoolingDown:     m.next.After(now),
+			IntervalMs:      m.interval.Milliseconds(),
+			CallsPerSecond:  safeDivide(1, m.interval.Seconds()),
+			RecentThrottles: len(m.throttles),
+		}
+		if entry.CoolingDown {
+			entry.NextSlotIn = m.next.Sub(now).Seconds()
+		}
+		if !m.lastCall.IsZero() {
+			lastCall := m.lastCall
+			entry.LastCall = &lastCall
+		}
+		throttles = append(throttles, entry)
+	}
+	sort.Slice(throttles, func(i, j int) bool { return throttles[i].Method < throttles[j].Method })
+	return throttles
+}
+
+// newTokenBucket returns a limiter refilling at perSecond calls per second
+// and holding up to burst of them. A burst below 1 defaults to the rate
+// rounded up, so a fractional rate still lets single calls through.
+func newTokenBucket(perSecond float64, burst int) *rate.Limiter {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(perSecond)))
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), burst)
+}
+
+// parseMethodRates reads RPC_METHOD_RATES, a comma-separated list of
+// method=rate or method=rate:burst entries.
+func parseMethodRates(raw string) (map[string]*rate.Limiter, error) {
+	limiters := map[string]*rate.Limiter{}
+	for _, entry := range parseCommaList(raw) {
+		method, spec, found := strings.Cut(entry, "=")
+		if !found || method == "" {
+			return nil, fmt.Errorf("entry %q is not method=rate[:burst]", entry)
+		}
+		rateSpec, burstSpec, hasBurst := strings.Cut(spec, ":")
+		perSecond, err := strconv.ParseFloat(rateSpec, 64)
+		if err != nil || perSecond <= 0 {
+			return nil, fmt.Errorf("entry %q has an invalid rate", entry)
+		}
+		burst := 0
+		if hasBurst {
+			if burst, err = strconv.Atoi(burstSpec); err != nil || burst < 1 {
+				return nil, fmt.Errorf("entry %q has an invalid burst", entry)
+			}
+		}
+		limiters[method] = newTokenBucket(perSecond, burst)
+	}
+	return limiters, nil
+}
+
+// tokenBucketFor returns the bucket calls to method draw from: the method's
+// own RPC_METHOD_RATES bucket when it has one, else the global RPC_RATE
+// bucket. It returns nil when RPC_RATE=0 turned the global bucket off and
+// the method has no bucket of its own.
+func (s *SolanaRPCClient) tokenBucketFor(method string) *rate.Limiter {
+	if limiter, ok := s.methodRateLimiters[method]; ok {
+		return limiter
+	}
+	return s.globalRateLimiter
+}
+
+// waitTokenBucket takes n tokens from method's bucket, waiting for them as
+// long as ctx allows. Providers count every call of a batch, so a batch takes
+// one token per call, capped at the burst so that it can ever be served. A
+// wait that would outlast ctx's deadline fails at once as rate limited.
+func (s *SolanaRPCClient) waitTokenBucket(ctx context.Context, method string, n int) error {
+	bucket := s.tokenBucketFor(method)
+	if bucket == nil {
+		return nil
+	}
+	if n > bucket.Burst() {
+		n = bucket.Burst()
+	}
+	if err := bucket.WaitN(ctx, n); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return &RateLimitError{Method: method}
+	}
+	return nil
+}
+
+type TokenBucketState struct {
+	Rate   float64 `json:"rate"`
+	Burst  int     `json:"burst"`
+	Tokens float64 `json:"tokens"`
+}
+
+func tokenBucketState(limiter *rate.Limiter) *TokenBucketState {
+	if limiter == nil {
+		return nil
+	}
+	return &TokenBucketState{Rate: float64(limiter.Limit()), Burst: limiter.Burst(), Tokens: limiter.Tokens()}
+}
+
+func handleThrottleState(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		methodBuckets := make(map[string]*TokenBucketState, len(client.methodRateLimiters))
+		for method, limiter := range client.methodRateLimiters {
+			methodBuckets[method] = tokenBucketState(limiter)
+		}
+
+		respondJSON(c, http.StatusOK, gin.H{
+			"methods":       client.rateLimiter.snapshot(),
+			"windowSeconds": throttleWindow.Seconds(),
+			"globalBucket":  tokenBucketState(client.globalRateLimiter),
+			"methodBuckets": methodBuckets,
+			"timestamp":     time.Now(),
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiterStartsUnthrottled(t *testing.T) {
+	tests := []struct {
+		name         string
+		throttles    int
+		loosen       int
+		wantInterval time.Duration
+	}{
+		{"fresh", 0, 0, 0},
+		{"first 429", 1, 0, minCallInterval},
+		{"second 429", 2, 0, 2 * minCallInterval},
+		{"capped", 20, 0, maxCallInterval},
+		{"loosened", 2, 1, 2 * minCallInterval * 3 / 4},
+		{"unthrottled again", 1, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newAdaptiveLimiter()
+			for i := 0; i < tt.throttles; i++ {
+				limiter.throttled("getBalance", 0)
+			}
+			for i := 0; i < tt.loosen; i++ {
+				limiter.mutex.Lock()
+				limiter.limit("getBalance").lastChange = time.Now().Add(-loosenAfter)
+				limiter.mutex.Unlock()
+				limiter.succeeded("getBalance")
+			}
+
+			limiter.mutex.Lock()
+			interval := limiter.limit("getBalance").interval
+			limiter.mutex.Unlock()
+			if interval != tt.wantInterval {
+				t.Errorf("interval = %v, want %v", interval, tt.wantInterval)
+			}
+		})
+	}
+}
+
+func TestConcurrentReadsAreNotSpaced(t *testing.T) {
+	_, client := newTestRPCNode(t, func(method string, _ []interface{}) interface{} {
+		if method != "getBalance" {
+			return nil
+		}
+		return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": 1e9}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	calls := defaultRPCBurst + 5
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetBalance(ctx, "Account1111111111111111111111111111111111111"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("GetBalance: %v", err)
+	}
+}
+
+func TestAdaptiveLimiterReserve(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxWait  time.Duration
+		wantWait bool
+		wantOK   bool
+	}{
+		{"no budget waits", -1, true, true},
+		{"slot within budget", time.Minute, true, true},
+		{"slot past budget", 10 * time.Millisecond, true, false},
+		{"budget spent", 0, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newAdaptiveLimiter()
+			limiter.throttled("getBalance", 30*time.Second)
+
+			wait, ok := limiter.reserve("getBalance", tt.maxWait)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, tt.wantOK)
+			}
+			if (wait > 0) != tt.wantWait {
+				t.Errorf("got wait %v", wait)
+			}
+		})
+	}
+}
+
+func TestGlobalTokenBucketWaits(t *testing.T) {
+	_, client := newTestRPCNode(t, func(method string, _ []interface{}) interface{} {
+		if method != "getBalance" {
+			return nil
+		}
+		return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": 1e9}
+	})
+	if client.globalRateLimiter == nil {
+		t.Fatal("no global token bucket by default")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	started := time.Now()
+	calls := defaultRPCBurst + 5
+	for i := 0; i < calls; i++ {
+		if _, err := client.GetBalance(ctx, "Account1111111111111111111111111111111111111"); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	// The calls past the burst wait for tokens at defaultRPCRate instead of
+	// failing.
+	if elapsed := time.Since(started); elapsed < 5*time.Second/defaultRPCRate-100*time.Millisecond {
+		t.Errorf("%d calls took %v, want them paced by the bucket", calls, elapsed)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import "math"
+
+// Ways of reporting the rentEpoch of rent-exempt accounts, chosen with
+// RENT_EPOCH_SENTINEL.
+const (
+	rentEpochOmit = "omit"
+	rentEpochZero = "zero"
+	rentEpochMax  = "max"
+)
+
+var validRentEpochModes = map[string]bool{
+	rentEpochOmit: true,
+	rentEpochZero: true,
+	rentEpochMax:  true,
+}
+
+// rentEpochSentinel is how parseRentEpoch reports the u64::MAX sentinel. It
+// is set once from RENT_EPOCH_SENTINEL before the server starts. The default
+// leaves the field out, since clients that decode JSON numbers as float64
+// cannot hold u64::MAX; returning it exactly is opt-in.
+var rentEpochSentinel = rentEpochOmit
+
+// parseRentEpoch reads an account's rentEpoch. Current nodes return u64::MAX
+// for rent-exempt accounts, which a float64 cannot hold, so postRPC decodes
+// rentEpoch as a json.Number and the sentinel is matched exactly. The
+// sentinel reports exempt and, depending on rentEpochSentinel, u64::MAX,
+// zero or no epoch. A missing or non-numeric rentEpoch reports no epoch and
+// not exempt, rather than an epoch of zero.
+func parseRentEpoch(value interface{}) (epoch *BigUint, exempt bool) {
+	n, ok := parseUint64Result(value)
+	if !ok {
+		return nil, false
+	}
+
+	if n != math.MaxUint64 {
+		rentEpoch := BigUint(n)
+		return &rentEpoch, false
+	}
+
+	switch rentEpochSentinel {
+	case rentEpochZero:
+		rentEpoch := BigUint(0)
+		return &rentEpoch, true
+	case rentEpochMax:
+		rentEpoch := BigUint(math.MaxUint64)
+		return &rentEpoch, true
+	default:
+		return nil, true
+	}
+}
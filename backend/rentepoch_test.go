@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseRentEpoch(t *testing.T) {
+	defer func(mode string) { rentEpochSentinel = mode }(rentEpochSentinel)
+
+	epoch := func(n uint64) *BigUint {
+		e := BigUint(n)
+		return &e
+	}
+
+	tests := []struct {
+		name       string
+		mode       string
+		value      interface{}
+		wantEpoch  *BigUint
+		wantExempt bool
+	}{
+		{"sentinel omitted by default", rentEpochOmit, json.Number("18446744073709551615"), nil, true},
+		{"sentinel as zero", rentEpochZero, json.Number("18446744073709551615"), epoch(0), true},
+		{"sentinel kept exactly", rentEpochMax, json.Number("18446744073709551615"), epoch(math.MaxUint64), true},
+		{"normal epoch", rentEpochOmit, json.Number("361"), epoch(361), false},
+		{"normal epoch as float", rentEpochMax, float64(361), epoch(361), false},
+		{"non-numeric", rentEpochMax, json.Number("soon"), nil, false},
+		{"missing", rentEpochZero, nil, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rentEpochSentinel = tt.mode
+			got, exempt := parseRentEpoch(tt.value)
+			if exempt != tt.wantExempt {
+				t.Errorf("exempt = %v, want %v", exempt, tt.wantExempt)
+			}
+			switch {
+			case got == nil && tt.wantEpoch == nil:
+			case got == nil || tt.wantEpoch == nil || *got != *tt.wantEpoch:
+				t.Errorf("epoch = %v, want %v", got, tt.wantEpoch)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// strictRPCResults restricts scalar results to the shapes the reference
+// implementation returns. It is set once from STRICT_RPC_RESULTS before the
+// server starts.
+var strictRPCResults bool
+
+// exactNumberFields are result fields that can hold u64 values a float64
+// cannot, such as the u64::MAX rentEpoch of rent-exempt accounts.
+// decodeRPCResponse leaves them as json.Number.
+var exactNumberFields = map[string]bool{
+	"rentEpoch": true,
+}
+
+// decodeRPCResponse decodes a JSON-RPC response with UseNumber, then turns
+// every number back into the float64 the rest of the client expects, except
+// under exactNumberFields.
+func decodeRPCResponse(r io.Reader, rpcResp *RPCResponse) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(rpcResp); err != nil {
+		return err
+	}
+	rpcResp.Result = floatNumbers(rpcResp.Result, false)
+	rpcResp.Error = floatNumbers(rpcResp.Error, false)
+	return nil
+}
+
+// floatNumbers converts the json.Numbers in value to float64 in place,
+// keeping value itself a json.Number when exact is set.
+func floatNumbers(value interface{}, exact bool) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if exact {
+			return v
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return v
+		}
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = floatNumbers(item, exactNumberFields[key])
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = floatNumbers(item, false)
+		}
+	}
+	return value
+}
+
+// parseUint64Result reads an unsigned integer from an RPC result. The
+// reference node returns a JSON number, read as a float64 or, for
+// exactNumberFields, a json.Number. Some providers send a decimal string or
+// wrap the number in an object under "value"; those are accepted unless
+// strictRPCResults is set. Negative and fractional numbers are rejected.
+func parseUint64Result(result interface{}) (uint64, bool) {
+	switch value := result.(type) {
+	case float64:
+		if math.IsNaN(value) || value < 0 || value != math.Trunc(value) {
+			return 0, false
+		}
+		return uint64FromFloat(value), true
+	case json.Number:
+		return parseUint64String(value.String())
+	case string:
+		if strictRPCResults {
+			return 0, false
+		}
+		return parseUint64String(value)
+	case map[string]interface{}:
+		if strictRPCResults {
+			return 0, false
+		}
+		// Only a number is unwrapped, never a nested object.
+		if _, nested := value["value"].(map[string]interface{}); nested {
+			return 0, false
+		}
+		return parseUint64Result(value["value"])
+	default:
+		return 0, false
+	}
+}
+
+// parseUint64String parses a decimal integer exactly, falling back to a
+// float for exponent notation such as "1e9".
+func parseUint64String(value string) (uint64, bool) {
+	value = strings.TrimSpace(value)
+	if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return n, true
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return parseUint64Result(f)
+}
+
+// unwrapContext splits the {"context": {"slot": ...}, "value": ...} envelope
+// that account, balance, supply and similar methods wrap their results in.
+// contextSlot is the slot the value was read at, or 0 if the node left it
+// out; callers expose it so clients can tell how fresh the data is. value
+// may be nil, e.g. for an account that does not exist.
+func unwrapContext(method string, result interface{}) (value interface{}, contextSlot uint64, err error) {
+	envelope, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, 0, &ParseError{Method: method, Detail: "result is not an object"}
+	}
+	value, ok = envelope["value"]
+	if !ok {
+		return nil, 0, &ParseError{Method: method, Detail: "value is missing"}
+	}
+	if context, ok := envelope["context"].(map[string]interface{}); ok {
+		contextSlot, _ = parseUint64Result(context["slot"])
+	}
+	return value, contextSlot, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseUint64Result(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     interface{}
+		want       uint64
+		wantOK     bool
+		wantStrict bool
+	}{
+		{"float64", float64(312000000), 312000000, true, true},
+		{"zero", float64(0), 0, true, true},
+		{"negative float64", float64(-1), 0, false, false},
+		{"fractional float64", 1.5, 0, false, false},
+		{"json.Number", json.Number("312000000"), 312000000, true, true},
+		{"json.Number u64 max", json.Number("18446744073709551615"), 18446744073709551615, true, true},
+		{"json.Number exponent", json.Number("1e9"), 1000000000, true, true},
+		{"numeric string", "312000000", 312000000, true, false},
+		{"padded string", " 312000000 ", 312000000, true, false},
+		{"exponent string", "1e9", 1000000000, true, false},
+		{"negative string", "-5", 0, false, false},
+		{"non-numeric string", "slot", 0, false, false},
+		{"wrapped number", map[string]interface{}{"value": float64(42)}, 42, true, false},
+		{"wrapped string", map[string]interface{}{"value": "42"}, 42, true, false},
+		{"nested wrap", map[string]interface{}{"value": map[string]interface{}{"value": float64(42)}}, 0, false, false},
+		{"object without value", map[string]interface{}{"slot": float64(42)}, 0, false, false},
+		{"bool", true, 0, false, false},
+		{"nil", nil, 0, false, false},
+	}
+
+	defer func(strict bool) { strictRPCResults = strict }(strictRPCResults)
+
+	for _, strict := range []bool{false, true} {
+		strictRPCResults = strict
+		for _, tt := range tests {
+			name := tt.name
+			if strict {
+				name += " strict"
+			}
+			t.Run(name, func(t *testing.T) {
+				wantOK := tt.wantOK
+				if strict {
+					wantOK = tt.wantStrict
+				}
+				got, ok := parseUint64Result(tt.result)
+				if ok != wantOK {
+					t.Fatalf("got ok %v, want %v", ok, wantOK)
+				}
+				if ok && got != tt.want {
+					t.Errorf("got %d, want %d", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodeRPCResponseNumbers(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want uint64
+	}{
+		{"number result", `{"jsonrpc":"2.0","id":1,"result":312000000}`, 312000000},
+		{"string result", `{"jsonrpc":"2.0","id":1,"result":"312000000"}`, 312000000},
+		{"wrapped result", `{"jsonrpc":"2.0","id":1,"result":{"value":312000000}}`, 312000000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp RPCResponse
+			if err := decodeRPCResponse(strings.NewReader(tt.body), &resp); err != nil {
+				t.Fatalf("decodeRPCResponse: %v", err)
+			}
+			got, ok := parseUint64Result(resp.Result)
+			if !ok || got != tt.want {
+				t.Errorf("got %d, %v; want %d", got, ok, tt.want)
+			}
+		})
+	}
+
+	t.Run("exact fields", func(t *testing.T) {
+		body := `{"jsonrpc":"2.0","id":1,"result":{"value":{"lamports":1000,"rentEpoch":18446744073709551615}}}`
+		var resp RPCResponse
+		if err := decodeRPCResponse(strings.NewReader(body), &resp); err != nil {
+			t.Fatalf("decodeRPCResponse: %v", err)
+		}
+		value := resp.Result.(map[string]interface{})["value"].(map[string]interface{})
+		if _, ok := value["lamports"].(float64); !ok {
+			t.Errorf("lamports is %T, want float64", value["lamports"])
+		}
+		if n, ok := value["rentEpoch"].(json.Number); !ok || n.String() != "18446744073709551615" {
+			t.Errorf("rentEpoch is %T %v, want the exact json.Number", value["rentEpoch"], value["rentEpoch"])
+		}
+	})
+}
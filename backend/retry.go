@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryPolicy controls how makeRPCCallWithRetry treats a method. MaxRetries
+// counts attempts after the first; methods that are not Retryable are tried
+// exactly once, whatever MaxRetries says.
+type retryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	Retryable  bool
+}
+
+// defaultMaxTotalRetryWait bounds the time one makeRPCCallWithRetry call
+// spends waiting across all its attempts. Override with RPC_MAX_TOTAL_WAIT;
+// 0 disables the bound.
+const defaultMaxTotalRetryWait = 30 * time.Second
+
+var defaultRetryPolicy = retryPolicy{MaxRetries: 2, BaseDelay: 1 * time.Second, Retryable: true}
+
+// defaultRetryPolicies covers methods that differ from defaultRetryPolicy.
+// Writes are never retried because a resend can land twice; cheap reads are
+// retried quickly; scans that are expensive for the provider back off hard.
+var defaultRetryPolicies = map[string]retryPolicy{
+	"sendTransaction":    {MaxRetries: 0, Retryable: false},
+	"getProgramAccounts": {MaxRetries: 1, BaseDelay: 5 * time.Second, Retryable: true},
+
+	"getSlot":             {MaxRetries: 3, BaseDelay: 500 * time.Millisecond, Retryable: true},
+	"getBalance":          {MaxRetries: 3, BaseDelay: 500 * time.Millisecond, Retryable: true},
+	"getAccountInfo":      {MaxRetries: 3, BaseDelay: 500 * time.Millisecond, Retryable: true},
+	"getMultipleAccounts": {MaxRetries: 3, BaseDelay: 500 * time.Millisecond, Retryable: true},
+	"getTokenSupply":      {MaxRetries: 3, BaseDelay: 500 * time.Millisecond, Retryable: true},
+}
+
+// retryPolicyFor returns the policy for method: an override, else its
+// default, else defaultRetryPolicy.
+func (s *SolanaRPCClient) retryPolicyFor(method string) retryPolicy {
+	if policy, ok := s.retryPolicies[method]; ok {
+		return policy
+	}
+	if policy, ok := defaultRetryPolicies[method]; ok {
+		return policy
+	}
+	return defaultRetryPolicy
+}
+
+// sleepContext waits for d, returning early with ctx.Err() if ctx is done
+// first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryPolicies reads RPC_RETRY_POLICY overrides, a comma-separated list
+// of method=maxRetries or method=maxRetries:baseDelay entries, e.g.
+// "getProgramAccounts=0,getBalance=5:200ms". Zero retries makes a method
+// non-retryable.
+func parseRetryPolicies(raw string) (map[string]retryPolicy, error) {
+	policies := make(map[string]retryPolicy)
+	for _, entry := range parseCommaList(raw) {
+		method, spec, found := strings.Cut(entry, "=")
+		if !found || method == "" {
+			return nil, fmt.Errorf("invalid retry policy %q", entry)
+		}
+
+		retriesRaw, delayRaw, hasDelay := strings.Cut(spec, ":")
+		retries, err := strconv.Atoi(retriesRaw)
+		if err != nil || retries < 0 {
+			return nil, fmt.Errorf("invalid retry count in %q", entry)
+		}
+
+		policy := defaultRetryPolicies[method]
+		if policy.BaseDelay == 0 {
+			policy.BaseDelay = defaultRetryPolicy.BaseDelay
+		}
+		if hasDelay {
+			delay, err := time.ParseDuration(delayRaw)
+			if err != nil || delay < 0 {
+				return nil, fmt.Errorf("invalid base delay in %q", entry)
+			}
+			policy.BaseDelay = delay
+		}
+		policy.MaxRetries = retries
+		policy.Retryable = retries > 0
+		policies[method] = policy
+	}
+	return policies, nil
+}
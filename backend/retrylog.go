@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultRetryLogInterval = 1 * time.Minute
+
+// retryCounts aggregates what happened on the retry path for one method
+// since the last summary.
+type retryCounts struct {
+	// Retries counts attempts after the first, whatever triggered them.
+	Retries int
+	// RateLimited counts 429 responses, Errors transport and HTTP failures.
+	RateLimited int
+	Errors      int
+	// Exhausted counts calls that failed after their last attempt.
+	Exhausted int
+	// WaitExceeded counts calls cut short by the total wait budget, Slept
+	// the time calls spent waiting for the limiter and between attempts.
+	WaitExceeded int
+	Slept        time.Duration
+}
+
+// retryLog keeps the retry path quiet under load. Per-attempt lines are only
+// written in debug mode; otherwise the counts are logged once per interval
+// as one key=value line per method, e.g.
+//
+//	retry_summary method=getBalance interval=1m0s retries=12 rate_limited=9 errors=3 exhausted=1 wait_exceeded=0 slept=41.5s
+type retryLog struct {
+	verbose bool
+
+	mutex  sync.Mutex
+	counts map[string]*retryCounts
+}
+
+func newRetryLog() *retryLog {
+	return &retryLog{counts: make(map[string]*retryCounts)}
+}
+
+// debugf writes a per-attempt line when verbose logging is enabled, tagged
+// with the ID of the request making the call, if any.
+func (l *retryLog) debugf(ctx context.Context, format string, args ...interface{}) {
+	if l.verbose {
+		log.Printf(requestLogPrefix(ctx)+format, args...)
+	}
+}
+
+// record applies update to the counts of method.
+func (l *retryLog) record(method string, update func(*retryCounts)) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	counts, ok := l.counts[method]
+	if !ok {
+		counts = &retryCounts{}
+		l.counts[method] = counts
+	}
+	update(counts)
+}
+
+// run logs and resets the counts every interval. Methods without retries,
+// failures or waits in the interval are not logged.
+func (l *retryLog) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		l.mutex.Lock()
+		counts := l.counts
+		l.counts = make(map[string]*retryCounts)
+		l.mutex.Unlock()
+
+		methods := make([]string, 0, len(counts))
+		for method := range counts {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			c := counts[method]
+			log.Printf("retry_summary method=%s interval=%s retries=%d rate_limited=%d errors=%d exhausted=%d wait_exceeded=%d slept=%s",
+				method, interval, c.Retries, c.RateLimited, c.Errors, c.Exhausted, c.WaitExceeded, c.Slept)
+		}
+	}
+}
+
+// serverTimingHeader carries, in debug mode, the time a request's RPC calls
+// spent waiting, as "rpc-wait;dur=<milliseconds>".
+const serverTimingHeader = "Server-Timing"
+
+type requestWaitKey struct{}
+
+// requestWait adds up the waits of every RPC call made on behalf of one
+// request, which may run concurrently.
+type requestWait struct {
+	total atomic.Int64
+}
+
+// addRequestWait counts d towards the request ctx belongs to, if
+// retryWaitMiddleware is tracking it.
+func addRequestWait(ctx context.Context, d time.Duration) {
+	if wait, ok := ctx.Value(requestWaitKey{}).(*requestWait); ok {
+		wait.total.Add(int64(d))
+	}
+}
+
+// retryWaitWriter sets the Server-Timing header just before the response
+// headers are sent, once the handler's RPC calls are done.
+type retryWaitWriter struct {
+	gin.ResponseWriter
+	wait *requestWait
+	sent bool
+}
+
+func (w *retryWaitWriter) setHeader() {
+	if w.sent || w.ResponseWriter.Written() {
+		return
+	}
+	w.sent = true
+	waited := time.Duration(w.wait.total.Load())
+	w.Header().Set(serverTimingHeader, fmt.Sprintf("rpc-wait;dur=%.1f", float64(waited)/float64(time.Millisecond)))
+}
+
+func (w *retryWaitWriter) WriteHeaderNow() {
+	w.setHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *retryWaitWriter) Write(data []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *retryWaitWriter) WriteString(data string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(data)
+}
+
+// retryWaitMiddleware reports, in debug mode, how long the request's RPC
+// calls spent waiting for the rate limiter and between attempts, in the
+// Server-Timing header: the per-request counterpart of retry_summary's
+// slept.
+func retryWaitMiddleware(debugMode bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !debugMode {
+			c.Next()
+			return
+		}
+
+		wait := &requestWait{}
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestWaitKey{}, wait))
+		writer := &retryWaitWriter{ResponseWriter: c.Writer, wait: wait}
+		c.Writer = writer
+		c.Next()
+		writer.setHeader()
+		c.Writer = writer.ResponseWriter
+	}
+}
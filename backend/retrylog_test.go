@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRetryWaitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		debugMode  bool
+		failures   int32
+		wantHeader bool
+		minWaitMs  float64
+	}{
+		{"debug off", false, 1, false, 0},
+		{"no wait", true, 0, true, 0},
+		{"one failed attempt", true, 1, true, float64(defaultRetryPolicies["getBalance"].BaseDelay.Milliseconds())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failures atomic.Int32
+			failures.Store(tt.failures)
+			node := &testRPCNode{respond: func(method string, _ []interface{}) interface{} {
+				if method != "getBalance" {
+					return nil
+				}
+				return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": 1e9}
+			}}
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				if bytes.Contains(body, []byte(`"getBalance"`)) && failures.Add(-1) >= 0 {
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				node.serveHTTP(w, r)
+			}))
+			t.Cleanup(upstream.Close)
+			client := NewSolanaClient(upstream.URL)
+			t.Cleanup(client.Close)
+
+			r := gin.New()
+			r.Use(retryWaitMiddleware(tt.debugMode))
+			r.GET("/api/balance/:address", func(c *gin.Context) {
+				balance, err := client.GetBalance(c.Request.Context(), c.Param("address"))
+				if err != nil {
+					c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"balance": balance})
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/balance/Account1111111111111111111111111111111111111", nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("got status %d (%s)", w.Code, w.Body)
+			}
+
+			header := w.Header().Get(serverTimingHeader)
+			if (header != "") != tt.wantHeader {
+				t.Fatalf("%s = %q, want header %v", serverTimingHeader, header, tt.wantHeader)
+			}
+			if !tt.wantHeader {
+				return
+			}
+			duration, found := strings.CutPrefix(header, "rpc-wait;dur=")
+			if !found {
+				t.Fatalf("%s = %q, want rpc-wait;dur=<ms>", serverTimingHeader, header)
+			}
+			waitedMs, err := strconv.ParseFloat(duration, 64)
+			if err != nil || waitedMs < tt.minWaitMs {
+				t.Errorf("waited %sms, want at least %vms", duration, tt.minWaitMs)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxStatusStreamSignatures is the most signatures getSignatureStatuses
+	// accepts in one call, so a stream never needs more than one per poll.
+	maxStatusStreamSignatures = 256
+	// statusStreamPollInterval is about two slots: confirmations show up
+	// promptly without a call per slot per stream.
+	statusStreamPollInterval = 1 * time.Second
+	// A stream closes after ?timeout= (default defaultStatusStreamTimeout,
+	// at most maxStatusStreamTimeout) even if signatures are still pending.
+	defaultStatusStreamTimeout = 2 * time.Minute
+	maxStatusStreamTimeout     = 10 * time.Minute
+)
+
+// SignatureStatus is where a transaction stands: pending while the node has
+// no record of it, then processed, confirmed or finalized, or failed when it
+// landed with an error. Confirmations is nil once the block is finalized.
+type SignatureStatus struct {
+	Signature     string      `json:"signature"`
+	Status        string      `json:"status"`
+	Slot          BigUint     `json:"slot,omitempty"`
+	Confirmations *uint64     `json:"confirmations,omitempty"`
+	Err           interface{} `json:"err,omitempty"`
+}
+
+// commitmentRank orders the statuses a signature moves through.
+var commitmentRank = map[string]int{
+	"processed": 1,
+	"confirmed": 2,
+	"finalized": 3,
+}
+
+// reached reports whether the status is final for a client waiting for
+// commitment: the transaction failed or got at least that far.
+func (st SignatureStatus) reached(commitment string) bool {
+	return st.Status == "failed" || commitmentRank[st.Status] >= commitmentRank[commitment]
+}
+
+// GetSignatureStatuses looks up every signature in one getSignatureStatuses
+// call, returning the statuses in the same order. With searchHistory the
+// node also searches its ledger, not just its recent status cache, which is
+// slower but finds transactions older than a few minutes.
+func (s *SolanaRPCClient) GetSignatureStatuses(ctx context.Context, signatures []string, searchHistory bool) ([]SignatureStatus, error) {
+	params := []interface{}{
+		signatures,
+		map[string]interface{}{"searchTransactionHistory": searchHistory},
+	}
+	resp, err := s.makeRPCCallWithRetry(ctx, "getSignatureStatuses", params)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, _, err := unwrapContext("getSignatureStatuses", resp.Result)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != len(signatures) {
+		return nil, &ParseError{Method: "getSignatureStatuses", Detail: "value does not match the signatures"}
+	}
+
+	statuses := make([]SignatureStatus, len(signatures))
+	for i, value := range values {
+		statuses[i] = SignatureStatus{Signature: signatures[i], Status: "pending"}
+		entry, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		slot, _ := entry["slot"].(float64)
+		statuses[i].Slot = BigUint(slot)
+		if confirmations, ok := entry["confirmations"].(float64); ok {
+			value := uint64(confirmations)
+			statuses[i].Confirmations = &value
+		}
+
+		switch status, _ := entry["confirmationStatus"].(string); {
+		case entry["err"] != nil:
+			statuses[i].Status = "failed"
+			statuses[i].Err = entry["err"]
+		case commitmentRank[status] > 0:
+			statuses[i].Status = status
+		case statuses[i].Confirmations == nil:
+			// Nodes predating confirmationStatus only null out
+			// confirmations once the block is finalized.
+			statuses[i].Status = "finalized"
+		default:
+			statuses[i].Status = "processed"
+		}
+	}
+
+	return statuses, nil
+}
+
+// writeEvent sends one Server-Sent Event carrying value as JSON.
+func writeEvent(w io.Writer, event string, value interface{}) {
+	payload, _ := json.Marshal(value)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+// handleSignatureStatusStream watches ?signatures= until each reaches
+// ?commitment= (default confirmed) or fails, polling getSignatureStatuses
+// for the unsettled ones with one call per statusStreamPollInterval. Events:
+// status with every signature's status on connect and then the ones that
+// changed, error when a poll failed (the next one is still made), done with
+// the final statuses once all are settled, and timeout with the still
+// unsettled signatures when ?timeout= ran out.
+func handleSignatureStatusStream(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var signatures []string
+		seen := make(map[string]bool)
+		for _, signature := range parseCommaList(c.Query("signatures")) {
+			if !isValidSignature(signature) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction signature: " + signature})
+				return
+			}
+			if !seen[signature] {
+				seen[signature] = true
+				signatures = append(signatures, signature)
+			}
+		}
+		if len(signatures) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "signatures must list at least one transaction signature"})
+			return
+		}
+		if len(signatures) > maxStatusStreamSignatures {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d signatures can be watched at once", maxStatusStreamSignatures)})
+			return
+		}
+
+		commitment := c.DefaultQuery("commitment", "confirmed")
+		if !validCommitments[commitment] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Commitment must be processed, confirmed or finalized"})
+			return
+		}
+
+		timeout := defaultStatusStreamTimeout
+		if raw := c.Query("timeout"); raw != "" {
+			parsed, err := parseSecondsOrDuration(raw)
+			if err != nil || parsed <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "timeout must be a number of seconds or a duration such as 90s"})
+				return
+			}
+			timeout = min(parsed, maxStatusStreamTimeout)
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		// Stops nginx from buffering the stream.
+		c.Header("X-Accel-Buffering", "no")
+
+		ctx := c.Request.Context()
+		deadline := time.NewTimer(timeout)
+		defer deadline.Stop()
+		poll := time.NewTicker(statusStreamPollInterval)
+		defer poll.Stop()
+		keepAlive := time.NewTicker(sseKeepAliveInterval)
+		defer keepAlive.Stop()
+
+		latest := make(map[string]SignatureStatus, len(signatures))
+		pending := signatures
+		first, searchHistory := true, true
+		c.Stream(func(w io.Writer) bool {
+			if !first {
+				select {
+				case <-poll.C:
+				case <-keepAlive.C:
+					fmt.Fprint(w, ": keep-alive\n\n")
+					return true
+				case <-deadline.C:
+					writeEvent(w, "timeout", versioned(c, gin.H{"pending": pending}))
+					return false
+				case <-ctx.Done():
+					return false
+				}
+			}
+
+			first = false
+			// Only the first successful poll searches the ledger, for
+			// signatures that settled before the stream opened; anything
+			// newer is in the node's recent status cache.
+			statuses, err := client.GetSignatureStatuses(ctx, pending, searchHistory)
+			if err != nil {
+				if ctx.Err() != nil {
+					return false
+				}
+				writeEvent(w, "error", gin.H{"error": "Failed to get signature statuses", "details": err.Error()})
+				return true
+			}
+
+			changed := []SignatureStatus{}
+			stillPending := []string{}
+			for _, status := range statuses {
+				if previous, ok := latest[status.Signature]; searchHistory || !ok || previous.Status != status.Status {
+					changed = append(changed, status)
+				}
+				latest[status.Signature] = status
+				if !status.reached(commitment) {
+					stillPending = append(stillPending, status.Signature)
+				}
+			}
+			pending = stillPending
+			searchHistory = false
+
+			if len(pending) == 0 {
+				final := make([]SignatureStatus, len(signatures))
+				for i, signature := range signatures {
+					final[i] = latest[signature]
+				}
+				writeEvent(w, "done", versioned(c, gin.H{"statuses": final}))
+				return false
+			}
+			if len(changed) > 0 {
+				writeEvent(w, "status", versioned(c, gin.H{"statuses": changed}))
+			}
+			return true
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The slot cache lasts defaultSlotCacheBlocks block times, roughly one slot:
+// long enough that a dashboard polling /api/slot from many tabs costs one
+// RPC call per slot, short enough that the counter never visibly lags.
+// Override the multiplier with SLOT_CACHE_BLOCKS. The TTL is clamped so a
+// bad block time estimate cannot disable the cache or freeze the counter.
+const (
+	defaultSlotCacheBlocks = 1.0
+	minSlotCacheTTL        = 100 * time.Millisecond
+	maxSlotCacheTTL        = 5 * time.Second
+)
+
+var validCommitments = map[string]bool{
+	"processed": true,
+	"confirmed": true,
+	"finalized": true,
+}
+
+type SlotInfo struct {
+	Slot        BigUint  `json:"slot"`
+	BlockHeight *BigUint `json:"blockHeight,omitempty"`
+	Epoch       *uint64  `json:"epoch,omitempty"`
+	SlotIndex   *uint64  `json:"slotIndex,omitempty"`
+	Commitment  string   `json:"commitment"`
+}
+
+// slotCacheTTL follows the measured block time, so the slot cache refreshes
+// at the network's actual pace. It reads the last estimate rather than
+// calling GetCachedBlockTime, which would start a new measurement on every
+// cache miss once the estimate is old; the metrics fetch keeps it current.
+func (s *SolanaRPCClient) slotCacheTTL() time.Duration {
+	s.mutex.RLock()
+	blockTime := s.lastBlockTime
+	s.mutex.RUnlock()
+
+	ttl := time.Duration(blockTime * s.slotCacheBlocks * float64(time.Second))
+	return min(max(ttl, minSlotCacheTTL), maxSlotCacheTTL)
+}
+
+// GetSlotInfo returns the current slot at the given commitment. With
+// details, getEpochInfo is used instead of getSlot so the block height and
+// epoch position come from the same call and refer to the same slot.
+func (s *SolanaRPCClient) GetSlotInfo(ctx context.Context, commitment string, details bool) (*SlotInfo, error) {
+	cacheKey := s.cacheKeyAt(commitment, "slot", details)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if info, ok := cached.(*SlotInfo); ok {
+			return info, nil
+		}
+	}
+
+	params := []interface{}{map[string]interface{}{"commitment": commitment}}
+	info := &SlotInfo{Commitment: commitment}
+	if details {
+		resp, err := s.makeRPCCallWithRetry(ctx, "getEpochInfo", params)
+		if err != nil {
+			return nil, err
+		}
+
+		epochInfo, ok := resp.Result.(map[string]interface{})
+		if !ok {
+			return nil, &ParseError{Method: "getEpochInfo", Detail: "result is not an object"}
+		}
+		absoluteSlot, ok := epochInfo["absoluteSlot"].(float64)
+		if !ok {
+			return nil, &ParseError{Method: "getEpochInfo", Detail: "absoluteSlot is not a number"}
+		}
+		info.Slot = BigUint(absoluteSlot)
+		if blockHeight, ok := epochInfo["blockHeight"].(float64); ok {
+			value := BigUint(blockHeight)
+			info.BlockHeight = &value
+		}
+		if epoch, ok := epochInfo["epoch"].(float64); ok {
+			value := uint64(epoch)
+			info.Epoch = &value
+		}
+		if slotIndex, ok := epochInfo["slotIndex"].(float64); ok {
+			value := uint64(slotIndex)
+			info.SlotIndex = &value
+		}
+	} else {
+		resp, err := s.makeRPCCallWithRetry(ctx, "getSlot", params)
+		if err != nil {
+			return nil, err
+		}
+
+		slot, ok := parseUint64Result(resp.Result)
+		if !ok {
+			return nil, &ParseError{Method: "getSlot", Detail: "result is not a number"}
+		}
+		info.Slot = BigUint(slot)
+	}
+
+	s.setCache(ctx, cacheKey, info, s.slotCacheTTL())
+
+	return info, nil
+}
+
+func handleSlot(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		commitment := c.DefaultQuery("commitment", client.commitment)
+		if !validCommitments[commitment] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Commitment must be processed, confirmed or finalized"})
+			return
+		}
+
+		info, err := client.GetSlotInfo(c.Request.Context(), commitment, c.Query("details") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get slot"})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, info)
+	}
+}
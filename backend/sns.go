@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mr-tron/base58"
+)
+
+const (
+	nameServiceProgramID  = "namesLPneVptA9Z5rqUDD9tMTWEJwofgaYwp8cawRkX"
+	solTLDAuthority       = "58PwtjSDuFHuUkYjH9BYnnQKHfwo9reZhC2zMJv9JPkx"
+	nameServiceHashPrefix = "SPL Name Service"
+	reverseLookupClass    = "33m47vH6Eav6jr5Ry86XjhRft2jRBLDnDgPSHoquXi2Z"
+	nameOffersProgramID   = "85iDfUvr3HJyLM2zcq5BXSiDvUWfw6cSE1FfNBo8Ap29"
+
+	// nameRegistryHeaderSize covers the parent, owner and class pubkeys that
+	// precede the data of every name registry account.
+	nameRegistryHeaderSize = 96
+	maxDomainLabelLength   = 63
+	maxWalletDomains       = 50
+)
+
+var errDomainNotFound = errors.New("domain not found")
+
+type DomainResolution struct {
+	Name        string `json:"name"`
+	NameAccount string `json:"nameAccount"`
+	Owner       string `json:"owner"`
+}
+
+type WalletDomains struct {
+	Address   string   `json:"address"`
+	Domains   []string `json:"domains"`
+	Favorite  *string  `json:"favorite"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+func hashedName(name string) []byte {
+	sum := sha256.Sum256([]byte(nameServiceHashPrefix + name))
+	return sum[:]
+}
+
+// nameAccountKey derives a name registry address. A nil class or parent is
+// encoded as the default (all-zero) pubkey.
+func nameAccountKey(hashed, class, parent []byte) (string, error) {
+	zero := make([]byte, 32)
+	if class == nil {
+		class = zero
+	}
+	if parent == nil {
+		parent = zero
+	}
+	address, _, err := findProgramAddress([][]byte{hashed, class, parent}, nameServiceProgramID)
+	return address, err
+}
+
+// parseDomainName validates a .sol name and returns its labels from the
+// top-level domain down, e.g. "pay.bonfida.sol" yields [bonfida pay].
+func parseDomainName(name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !strings.HasSuffix(name, ".sol") {
+		return nil, fmt.Errorf("name must end with .sol")
+	}
+
+	labels := strings.Split(strings.TrimSuffix(name, ".sol"), ".")
+	if len(labels) > 2 {
+		return nil, fmt.Errorf("only domains and single-level subdomains are supported")
+	}
+
+	for _, label := range labels {
+		if label == "" || len(label) > maxDomainLabelLength {
+			return nil, fmt.Errorf("invalid domain label %q", label)
+		}
+		for _, r := range label {
+			if unicode.IsSpace(r) || unicode.IsControl(r) {
+				return nil, fmt.Errorf("invalid domain label %q", label)
+			}
+		}
+	}
+
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return labels, nil
+}
+
+// domainNameAccount derives the name registry account for parsed labels.
+// Subdomains are registered under their parent with a leading NUL byte.
+func domainNameAccount(labels []string) (string, error) {
+	parent, err := decodePublicKey(solTLDAuthority)
+	if err != nil {
+		return "", err
+	}
+
+	var account string
+	for i, label := range labels {
+		if i > 0 {
+			label = "\x00" + label
+		}
+		account, err = nameAccountKey(hashedName(label), nil, parent)
+		if err != nil {
+			return "", err
+		}
+		parent, err = decodePublicKey(account)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return account, nil
+}
+
+// readNameRegistry fetches a name registry account and returns its owner and
+// the data following the header.
+func (s *SolanaRPCClient) readNameRegistry(ctx context.Context, address string) (string, []byte, error) {
+	account, err := s.GetAccountInfoWithOptions(ctx, address, AccountInfoOptions{DataEncoding: "base64"})
+	if err != nil {
+		return "", nil, err
+	}
+	if !account.IsValid {
+		return "", nil, errDomainNotFound
+	}
+
+	data, err := base64.StdEncoding.DecodeString(account.Data)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(data) < nameRegistryHeaderSize {
+		return "", nil, fmt.Errorf("invalid name registry account")
+	}
+
+	return base58.Encode(data[32:64]), data[nameRegistryHeaderSize:], nil
+}
+
+func (s *SolanaRPCClient) ResolveDomain(ctx context.Context, name string) (*DomainResolution, error) {
+	labels, err := parseDomainName(name)
+	if err != nil {
+		return nil, err
+	}
+	normalized := strings.ToLower(strings.TrimSpace(name))
+
+	cacheKey := s.cacheKey("sns_resolve", normalized)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if resolution, ok := cached.(*DomainResolution); ok {
+			return resolution, nil
+		}
+	}
+
+	nameAccount, err := domainNameAccount(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, _, err := s.readNameRegistry(ctx, nameAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	resolution := &DomainResolution{
+		Name:        normalized,
+		NameAccount: nameAccount,
+		Owner:       owner,
+	}
+	s.setCache(ctx, cacheKey, resolution, 10*time.Minute)
+
+	return resolution, nil
+}
+
+func handleResolveDomain(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		if _, err := parseDomainName(name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid .sol domain name", "details": err.Error()})
+			return
+		}
+
+		resolution, err := client.ResolveDomain(c.Request.Context(), name)
+		if errors.Is(err, errDomainNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve domain"})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, resolution)
+	}
+}
+
+// reverseLookup returns the .sol name stored in the reverse lookup registry
+// for a top-level domain's name account.
+func (s *SolanaRPCClient) reverseLookup(ctx context.Context, nameAccount string) (string, error) {
+	class, err := decodePublicKey(reverseLookupClass)
+	if err != nil {
+		return "", err
+	}
+	reverseAccount, err := nameAccountKey(hashedName(nameAccount), class, nil)
+	if err != nil {
+		return "", err
+	}
+
+	_, data, err := s.readNameRegistry(ctx, reverseAccount)
+	if err != nil {
+		return "", err
+	}
+
+	name, err := (&borshReader{data: data}).string()
+	if err != nil {
+		return "", fmt.Errorf("invalid reverse lookup account: %w", err)
+	}
+	return name + ".sol", nil
+}
+
+// favoriteDomain returns the name account a wallet marked as its favorite in
+// the SNS name offers program, or "" when none is set.
+func (s *SolanaRPCClient) favoriteDomain(ctx context.Context, address string) (string, error) {
+	owner, err := decodePublicKey(address)
+	if err != nil {
+		return "", err
+	}
+	favoriteAccount, _, err := findProgramAddress([][]byte{[]byte("favourite_domain"), owner}, nameOffersProgramID)
+	if err != nil {
+		return "", err
+	}
+
+	account, err := s.GetAccountInfoWithOptions(ctx, favoriteAccount, AccountInfoOptions{DataEncoding: "base64"})
+	if err != nil {
+		return "", err
+	}
+	if !account.IsValid {
+		return "", nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(account.Data)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < 33 {
+		return "", fmt.Errorf("invalid favorite domain account")
+	}
+	return base58.Encode(data[1:33]), nil
+}
+
+// GetWalletDomains lists the top-level .sol domains owned by a wallet and
+// its favorite domain. It only covers the standard SNS registries: domains
+// held through a tokenized (NFT-wrapped) record are not found.
+func (s *SolanaRPCClient) GetWalletDomains(ctx context.Context, address string) (*WalletDomains, error) {
+	cacheKey := s.cacheKey("sns_domains", address)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if domains, ok := cached.(*WalletDomains); ok {
+			return domains, nil
+		}
+	}
+
+	filters := []interface{}{
+		map[string]interface{}{"memcmp": map[string]interface{}{"offset": 0, "bytes": solTLDAuthority}},
+		map[string]interface{}{"memcmp": map[string]interface{}{"offset": 32, "bytes": address}},
+	}
+	accounts, err := s.GetProgramAccountsWithOptions(ctx, nameServiceProgramID, ProgramAccountsOptions{
+		Filters:   filters,
+		DataSlice: &DataSlice{Offset: 0, Length: 0},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &WalletDomains{Address: address, Domains: []string{}}
+	if len(accounts) > maxWalletDomains {
+		accounts = accounts[:maxWalletDomains]
+		result.Truncated = true
+	}
+
+	nameByAccount := make(map[string]string, len(accounts))
+	for _, account := range accounts {
+		name, err := s.reverseLookup(ctx, account.Pubkey)
+		if err != nil {
+			continue
+		}
+		nameByAccount[account.Pubkey] = name
+		result.Domains = append(result.Domains, name)
+	}
+
+	favoriteAccount, err := s.favoriteDomain(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	if favoriteAccount != "" {
+		name, known := nameByAccount[favoriteAccount]
+		if !known {
+			name, err = s.reverseLookup(ctx, favoriteAccount)
+		}
+		if err == nil {
+			result.Favorite = &name
+		}
+	}
+
+	s.setCache(ctx, cacheKey, result, 10*time.Minute)
+
+	return result, nil
+}
+
+func handleWalletDomains(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		address := c.Param("address")
+		if _, err := decodePublicKey(address); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+			return
+		}
+
+		domains, err := client.GetWalletDomains(c.Request.Context(), address)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get domains"})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, domains)
+	}
+}
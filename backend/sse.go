@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseKeepAliveInterval spaces the comment lines sent on an idle event
+// stream, so proxies do not close it for inactivity.
+const sseKeepAliveInterval = 30 * time.Second
+
+// handleMetricsEvents streams metrics as Server-Sent Events, one data: line
+// of SolanaMetrics JSON per update. Subscribers join the same hub as
+// /ws/metrics, so the RPC is polled once however many streams are open.
+// Each event carries the update's ID; metrics are snapshots, so a client
+// reconnecting with Last-Event-ID only needs the newest one, which it is
+// sent right away unless that is the one it last saw. The retry field asks
+// the client to reconnect after one interval.
+func handleMetricsEvents(hub *metricsHub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		interval, err := parseStreamInterval(c.Query("interval"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be a number of seconds or a duration such as 5s"})
+			return
+		}
+
+		sub := &metricsSubscriber{
+			interval:      interval,
+			send:          make(chan metricsUpdate, hub.sendBuffer),
+			lastEventID:   c.GetHeader("Last-Event-ID"),
+			bigIntStrings: apiVersion(c) >= bigIntStringsVersion,
+		}
+		hub.register(sub)
+		defer hub.unregister(sub)
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		// Stops nginx from buffering the stream.
+		c.Header("X-Accel-Buffering", "no")
+
+		keepAlive := time.NewTicker(sseKeepAliveInterval)
+		defer keepAlive.Stop()
+
+		ctx := c.Request.Context()
+		fmt.Fprintf(c.Writer, "retry: %d\n\n", interval.Milliseconds())
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case update, ok := <-sub.send:
+				if !ok {
+					// The hub dropped a slow subscriber.
+					return false
+				}
+				fmt.Fprintf(w, "id: %s\ndata: %s\n\n", update.id, update.payloadFor(sub))
+				return true
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const stakeProgramID = "Stake11111111111111111111111111111111111111"
+
+// stakeStateStake is the StakeStateV2 tag of a delegated stake account. The
+// delegation follows the 4-byte tag and the 120-byte Meta.
+const (
+	stakeStateStake = 2
+	stakeMetaSize   = 120
+)
+
+// nominalSlotsPerYear is the slot count the inflation schedule assumes
+// (400ms slots), which is what the runtime uses to size each epoch's rewards.
+const nominalSlotsPerYear = 365.25 * 24 * 60 * 60 / 0.4
+
+var (
+	errNotStakeAccount  = errors.New("account is not a stake account")
+	errStakeUndelegated = errors.New("stake account is not delegated")
+	errStakeNotFound    = errors.New("stake account not found")
+)
+
+type stakeDelegation struct {
+	Voter             string
+	Stake             uint64
+	ActivationEpoch   uint64
+	DeactivationEpoch uint64
+}
+
+type InflationRate struct {
+	Total      float64 `json:"total"`
+	Validator  float64 `json:"validator"`
+	Foundation float64 `json:"foundation"`
+	Epoch      uint64  `json:"epoch"`
+}
+
+type voteAccount struct {
+	Commission     float64
+	ActivatedStake uint64
+	Delinquent     bool
+}
+
+type voteAccounts struct {
+	TotalStake uint64
+	Accounts   map[string]voteAccount
+}
+
+type StakeRewardEstimate struct {
+	Address      string  `json:"address"`
+	Voter        string  `json:"voter"`
+	Status       string  `json:"status"`
+	Stake        BigUint `json:"stake"`
+	Commission   float64 `json:"commission"`
+	CurrentEpoch uint64  `json:"currentEpoch"`
+	// Epoch is the epoch the estimate covers; its rewards are paid out
+	// when it ends.
+	Epoch                   uint64   `json:"epoch"`
+	EstimatedRewardLamports BigUint  `json:"estimatedRewardLamports"`
+	EstimatedReward         float64  `json:"estimatedReward"`
+	EstimatedAPY            float64  `json:"estimatedApy"`
+	ValidatorInflationRate  float64  `json:"validatorInflationRate"`
+	StakingRatio            float64  `json:"stakingRatio"`
+	IsEstimate              bool     `json:"isEstimate"`
+	Assumptions             []string `json:"assumptions"`
+	Warnings                []string `json:"warnings"`
+}
+
+var stakeEstimateAssumptions = []string{
+	"The validator earns the network-average vote credits over the epoch",
+	"Inflation, commission, total supply and total active stake stay at their current values",
+	"Epoch rewards are sized for nominal 400ms slots, as the runtime does; slower epochs lower the realized APY",
+	"Rewards are not compounded",
+}
+
+// parseStakeDelegation reads the delegation from a StakeStateV2 account.
+func parseStakeDelegation(data []byte) (*stakeDelegation, error) {
+	r := &borshReader{data: data}
+	tag, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	if tag != stakeStateStake {
+		return nil, errStakeUndelegated
+	}
+	if _, err := r.bytes(stakeMetaSize); err != nil {
+		return nil, err
+	}
+
+	delegation := &stakeDelegation{}
+	if delegation.Voter, err = r.pubkey(); err != nil {
+		return nil, err
+	}
+	if delegation.Stake, err = r.u64(); err != nil {
+		return nil, err
+	}
+	if delegation.ActivationEpoch, err = r.u64(); err != nil {
+		return nil, err
+	}
+	if delegation.DeactivationEpoch, err = r.u64(); err != nil {
+		return nil, err
+	}
+	return delegation, nil
+}
+
+// GetInflationRate returns the current epoch's inflation rates.
+func (s *SolanaRPCClient) GetInflationRate(ctx context.Context) (*InflationRate, error) {
+	cacheKey := s.cacheKey("inflation_rate")
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if rate, ok := cached.(*InflationRate); ok {
+			return rate, nil
+		}
+	}
+
+	resp, err := s.makeRPCCallWithRetry(ctx, "getInflationRate", []interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, ok := resp.Result.(map[string]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getInflationRate", Detail: "result is not an object"}
+	}
+	validator, ok := result["validator"].(float64)
+	if !ok {
+		return nil, &ParseError{Method: "getInflationRate", Detail: "validator is not a number"}
+	}
+	total, _ := result["total"].(float64)
+	foundation, _ := result["foundation"].(float64)
+	epoch, _ := result["epoch"].(float64)
+
+	rate := &InflationRate{Total: total, Validator: validator, Foundation: foundation, Epoch: uint64(epoch)}
+
+	// The rate only changes at epoch boundaries.
+	s.setCache(ctx, cacheKey, rate, 1*time.Hour)
+
+	return rate, nil
+}
+
+// getVoteAccounts returns every vote account, current and delinquent, with
+// the total activated stake across them.
+func (s *SolanaRPCClient) getVoteAccounts(ctx context.Context) (*voteAccounts, error) {
+	cacheKey := s.cacheKey("vote_accounts")
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if accounts, ok := cached.(*voteAccounts); ok {
+			return accounts, nil
+		}
+	}
+
+	resp, err := s.makeRPCCallWithRetry(ctx, "getVoteAccounts", []interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, ok := resp.Result.(map[string]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getVoteAccounts", Detail: "result is not an object"}
+	}
+
+	accounts := &voteAccounts{Accounts: make(map[string]voteAccount)}
+	for _, group := range []string{"current", "delinquent"} {
+		entries, _ := result[group].([]interface{})
+		for _, entry := range entries {
+			account, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			votePubkey, ok := account["votePubkey"].(string)
+			if !ok {
+				continue
+			}
+			commission, _ := account["commission"].(float64)
+			activatedStake, _ := account["activatedStake"].(float64)
+
+			accounts.Accounts[votePubkey] = voteAccount{
+				Commission:     commission,
+				ActivatedStake: uint64FromFloat(activatedStake),
+				Delinquent:     group == "delinquent",
+			}
+			accounts.TotalStake += uint64FromFloat(activatedStake)
+		}
+	}
+
+	s.setCache(ctx, cacheKey, accounts, 5*time.Minute)
+
+	return accounts, nil
+}
+
+// getTotalSupply returns the total SOL supply in lamports.
+func (s *SolanaRPCClient) getTotalSupply(ctx context.Context) (uint64, error) {
+	cacheKey := s.cacheKey("total_supply")
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if supply, ok := cached.(uint64); ok {
+			return supply, nil
+		}
+	}
+
+	params := []interface{}{map[string]interface{}{"excludeNonCirculatingAccountsList": true}}
+	resp, err := s.makeRPCCallWithRetry(ctx, "getSupply", params)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.Error != nil {
+		return 0, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	result, _, err := unwrapContext("getSupply", resp.Result)
+	if err != nil {
+		return 0, err
+	}
+	value, ok := result.(map[string]interface{})
+	if !ok {
+		return 0, &ParseError{Method: "getSupply", Detail: "value is not an object"}
+	}
+	total, ok := value["total"].(float64)
+	if !ok {
+		return 0, &ParseError{Method: "getSupply", Detail: "total is not a number"}
+	}
+
+	supply := uint64FromFloat(total)
+	s.setCache(ctx, cacheKey, supply, 1*time.Hour)
+
+	return supply, nil
+}
+
+// EstimateStakeReward estimates what a stake account earns over the next
+// full epoch. Its share of the epoch's validator inflation is its share of
+// the total active stake, less the validator's commission.
+func (s *SolanaRPCClient) EstimateStakeReward(ctx context.Context, address string) (*StakeRewardEstimate, error) {
+	account, err := s.GetAccountInfoWithOptions(ctx, address, AccountInfoOptions{DataEncoding: "base64"})
+	if err != nil {
+		return nil, err
+	}
+	if !account.IsValid {
+		return nil, errStakeNotFound
+	}
+	if account.Owner != stakeProgramID {
+		return nil, errNotStakeAccount
+	}
+	data, err := base64.StdEncoding.DecodeString(account.Data)
+	if err != nil {
+		return nil, err
+	}
+	delegation, err := parseStakeDelegation(data)
+	if err != nil {
+		return nil, err
+	}
+
+	epochInfo, err := s.GetEpochInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	currentEpoch, ok := epochInfo["epoch"].(float64)
+	if !ok {
+		return nil, &ParseError{Method: "getEpochInfo", Detail: "epoch is not a number"}
+	}
+	schedule, err := s.GetEpochSchedule(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inflation, err := s.GetInflationRate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	votes, err := s.getVoteAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	supply, err := s.getTotalSupply(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate := &StakeRewardEstimate{
+		Address:                address,
+		Voter:                  delegation.Voter,
+		Status:                 "active",
+		Stake:                  BigUint(delegation.Stake),
+		CurrentEpoch:           uint64(currentEpoch),
+		Epoch:                  uint64(currentEpoch) + 1,
+		ValidatorInflationRate: inflation.Validator,
+		StakingRatio:           safeDivide(float64(votes.TotalStake), float64(supply)),
+		IsEstimate:             true,
+		Assumptions:            stakeEstimateAssumptions,
+		Warnings:               []string{},
+	}
+
+	validator, found := votes.Accounts[delegation.Voter]
+	if found {
+		estimate.Commission = validator.Commission
+		if validator.Delinquent {
+			estimate.Warnings = append(estimate.Warnings, "The validator is delinquent and earns no rewards while it is not voting")
+		}
+	} else {
+		estimate.Warnings = append(estimate.Warnings, "The validator's vote account was not found; no rewards are expected")
+	}
+
+	// Deactivation takes effect from the epoch it was requested in, so a
+	// deactivating stake is cooling down by the estimated epoch.
+	if delegation.DeactivationEpoch != math.MaxUint64 {
+		estimate.Status = "deactivating"
+		if delegation.DeactivationEpoch < estimate.CurrentEpoch {
+			estimate.Status = "inactive"
+		}
+		estimate.Warnings = append(estimate.Warnings, "The stake is deactivating; it earns no rewards once its cooldown starts, so none are estimated for the next epoch")
+		return estimate, nil
+	}
+	if delegation.ActivationEpoch >= estimate.CurrentEpoch {
+		estimate.Status = "activating"
+		estimate.Warnings = append(estimate.Warnings, "The stake is still activating; the estimate assumes it is fully active, which network warmup limits may delay")
+	}
+	if !found || validator.Delinquent {
+		return estimate, nil
+	}
+
+	epochYears := float64(schedule.SlotsInEpoch(estimate.Epoch)) / nominalSlotsPerYear
+	epochRewards := inflation.Validator * float64(supply) * epochYears
+	share := safeDivide(float64(delegation.Stake), float64(votes.TotalStake))
+	reward := epochRewards * share * (1 - validator.Commission/100)
+
+	estimate.EstimatedRewardLamports = BigUint(uint64FromFloat(reward))
+	estimate.EstimatedReward = reward / 1e9
+	estimate.EstimatedAPY = jsonSafeFloat(safeDivide(inflation.Validator, estimate.StakingRatio) * (1 - validator.Commission/100) * 100)
+
+	return estimate, nil
+}
+
+func handleStakeEstimate(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		address := c.Param("address")
+		if _, err := decodePublicKey(address); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+			return
+		}
+
+		estimate, err := client.EstimateStakeReward(c.Request.Context(), address)
+		switch {
+		case errors.Is(err, errStakeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Stake account not found"})
+			return
+		case errors.Is(err, errNotStakeAccount):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Account is not a stake account"})
+			return
+		case errors.Is(err, errStakeUndelegated):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Stake account is not delegated"})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to estimate stake rewards"})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, estimate)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenAccounts lists a wallet's token accounts. EmptyExcluded counts the
+// zero-balance accounts left out unless includeEmpty is set.
+type TokenAccounts struct {
+	Owner         string         `json:"owner"`
+	Mint          string         `json:"mint,omitempty"`
+	TokenAccounts []TokenAccount `json:"tokenAccounts"`
+	Count         int            `json:"count"`
+	EmptyExcluded int            `json:"emptyExcluded"`
+}
+
+// handleTokenAccounts serves GET /api/account/:address/tokens. ?mint=
+// restricts the list to one mint and ?includeEmpty=true keeps accounts with
+// a zero balance.
+func handleTokenAccounts(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owner := c.Param("address")
+		if _, err := decodePublicKey(owner); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+			return
+		}
+		mint := c.Query("mint")
+		if mint != "" && !isValidSolanaAddress(mint) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mint address"})
+			return
+		}
+
+		accounts, err := client.GetTokenAccountsByOwner(c.Request.Context(), owner, mint)
+		if errors.Is(err, errNotTokenMint) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Address is not a token mint", "reason": notAMintReason, "mintAddress": mint})
+			return
+		}
+		if err != nil {
+			log.Printf("Error getting token accounts of %s: %v", owner, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get token accounts"})
+			return
+		}
+
+		result := TokenAccounts{Owner: owner, Mint: mint, TokenAccounts: []TokenAccount{}}
+		includeEmpty := c.Query("includeEmpty") == "true"
+		for _, account := range accounts {
+			// The raw amount is exact; uiAmount may round a tiny balance.
+			if !includeEmpty && (account.Amount == "0" || account.Amount == "") {
+				result.EmptyExcluded++
+				continue
+			}
+			result.TokenAccounts = append(result.TokenAccounts, account)
+		}
+		result.Count = len(result.TokenAccounts)
+
+		respondJSON(c, http.StatusOK, result)
+	}
+}
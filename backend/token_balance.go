@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenBalance is how much of one mint a wallet holds, summed over all of
+// its token accounts for that mint. A wallet holding none has a zero
+// balance and no token accounts.
+type TokenBalance struct {
+	Owner         string   `json:"owner"`
+	Mint          string   `json:"mint"`
+	Amount        string   `json:"amount"`
+	Decimals      int      `json:"decimals"`
+	UIAmount      float64  `json:"uiAmount"`
+	TokenProgram  string   `json:"tokenProgram,omitempty"`
+	TokenAccounts []string `json:"tokenAccounts"`
+	// ContextSlot is the slot the token accounts were read at.
+	ContextSlot BigUint `json:"contextSlot,omitempty"`
+}
+
+// GetTokenBalance looks up owner's balance of mint with a single
+// getTokenAccountsByOwner call filtered by mint, which also finds accounts
+// other than the associated one. When there are none the decimals come from
+// getTokenSupply. Addresses that are not mints return errNotTokenMint. Like
+// SOL balances, the result is only cached for pinned owners.
+func (s *SolanaRPCClient) GetTokenBalance(ctx context.Context, owner, mint string) (*TokenBalance, error) {
+	cacheKey := s.cacheKey("token_balance", owner, mint)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if balance, ok := cached.(*TokenBalance); ok {
+			return balance, nil
+		}
+	}
+
+	resp, err := s.queryTokenAccountsByOwner(ctx, owner, map[string]interface{}{"mint": mint})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil && isInvalidParams(resp.Error) {
+		return nil, errNotTokenMint
+	}
+	accounts, contextSlot, err := parseTokenAccounts(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &TokenBalance{Owner: owner, Mint: mint, TokenAccounts: []string{}, ContextSlot: BigUint(contextSlot)}
+	var total uint64
+	for _, account := range accounts {
+		amount, err := strconv.ParseUint(account.Amount, 10, 64)
+		if err != nil {
+			return nil, &ParseError{Method: "getTokenAccountsByOwner", Detail: "token amount is not an integer"}
+		}
+		total += amount
+		balance.UIAmount += account.UIAmount
+		balance.Decimals = account.Decimals
+		balance.TokenProgram = account.TokenProgram
+		balance.TokenAccounts = append(balance.TokenAccounts, account.Address)
+	}
+	balance.Amount = strconv.FormatUint(total, 10)
+
+	if len(accounts) == 0 {
+		tokenInfo, err := s.GetTokenSupply(ctx, mint)
+		if err != nil {
+			return nil, err
+		}
+		if !tokenInfo.IsValid {
+			return nil, errNotTokenMint
+		}
+		balance.Decimals = tokenInfo.Decimals
+	}
+
+	s.setPinnedCache(ctx, cacheKey, balance)
+
+	return balance, nil
+}
+
+func handleTokenBalance(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owner := c.Param("address")
+		mint := c.Param("mint")
+		if !isValidSolanaAddress(owner) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+			return
+		}
+		if !isValidSolanaAddress(mint) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mint address"})
+			return
+		}
+
+		balance, err := client.GetTokenBalance(c.Request.Context(), owner, mint)
+		if errors.Is(err, errNotTokenMint) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Address is not a token mint", "reason": notAMintReason, "mintAddress": mint})
+			return
+		}
+		if err != nil {
+			log.Printf("Error getting %s balance of %s: %v", mint, owner, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get token balance"})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, balance)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	tokenProgramID     = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	token2022ProgramID = "TokenzQdBNbLqP4VNvG2wWZDqX8pAqTwzM6tAa4xvNa"
+)
+
+// tokenPrograms are the programs a mint can belong to.
+var tokenPrograms = map[string]bool{
+	tokenProgramID:     true,
+	token2022ProgramID: true,
+}
+
+var errNotTokenMint = errors.New("account is not owned by a token program")
+
+// rpcInvalidParams is the JSON-RPC code a node returns when a parameter is
+// unusable, which for getTokenSupply means the address is not a mint.
+const rpcInvalidParams = -32602
+
+// notAMintReason is the TokenInfo reason for an address that is not a mint.
+const notAMintReason = "not_a_mint"
+
+func isInvalidParams(rpcErr interface{}) bool {
+	errorMap, ok := rpcErr.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	code, _ := errorMap["code"].(float64)
+	return code == rpcInvalidParams
+}
+
+// GetTokenProgram returns the program owning a mint: tokenProgramID or
+// token2022ProgramID. A mint cannot change owner, so the answer is cached
+// for a day; accounts that do not exist or belong to another program return
+// errNotTokenMint and are not cached, since the address may become a mint
+// later. A failed lookup, rate limits included, is returned as is.
+func (s *SolanaRPCClient) GetTokenProgram(ctx context.Context, mintAddress string) (string, error) {
+	cacheKey := s.cacheKey("token_program", mintAddress)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if program, ok := cached.(string); ok {
+			return program, nil
+		}
+	}
+
+	account, err := s.GetAccountInfo(ctx, mintAddress)
+	if err != nil {
+		return "", err
+	}
+	if !account.IsValid || !tokenPrograms[account.Owner] {
+		return "", errNotTokenMint
+	}
+
+	s.setImmutableCache(ctx, cacheKey, account.Owner, 24*time.Hour)
+
+	return account.Owner, nil
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mr-tron/base58"
+)
+
+const idempotencyKeyTTL = 5 * time.Minute
+
+// defaultMaxTransactionVersion is sent as maxSupportedTransactionVersion so
+// that v0 transactions are returned instead of rejected by the node.
+const defaultMaxTransactionVersion = 0
+
+type SendTransactionRequest struct {
+	Transaction   string `json:"transaction" binding:"required"`
+	Encoding      string `json:"encoding"`
+	SkipPreflight bool   `json:"skipPreflight"`
+}
+
+type TransactionInfo struct {
+	Signature            string           `json:"signature"`
+	Slot                 BigUint          `json:"slot"`
+	BlockTime            *int64           `json:"blockTime"`
+	Version              string           `json:"version"`
+	Fee                  uint64           `json:"fee"`
+	Success              bool             `json:"success"`
+	Error                interface{}      `json:"error,omitempty"`
+	ComputeUnitsConsumed *uint64          `json:"computeUnitsConsumed,omitempty"`
+	LogMessages          []string         `json:"logMessages,omitempty"`
+	AccountKeys          []string         `json:"accountKeys"`
+	StaticAccountKeys    []string         `json:"staticAccountKeys"`
+	LoadedAddresses      *LoadedAddresses `json:"loadedAddresses,omitempty"`
+	BalanceChanges       []BalanceChange  `json:"balanceChanges,omitempty"`
+	IsValid              bool             `json:"isValid"`
+
+	Raw        map[string]interface{} `json:"raw,omitempty"`
+	rpcResults map[string]interface{}
+}
+
+// BalanceChange is one account's SOL balance before and after a
+// transaction, in lamports.
+type BalanceChange struct {
+	Account     string `json:"account"`
+	PreBalance  uint64 `json:"preBalance"`
+	PostBalance uint64 `json:"postBalance"`
+	Change      int64  `json:"change"`
+}
+
+// parseBalanceChanges pairs meta.preBalances and meta.postBalances with the
+// account keys they are indexed by, static keys first and then the loaded
+// writable and read-only addresses. Lists that do not line up with the keys
+// are ignored rather than misattributed.
+func parseBalanceChanges(meta map[string]interface{}, accountKeys []string) []BalanceChange {
+	pre, _ := meta["preBalances"].([]interface{})
+	post, _ := meta["postBalances"].([]interface{})
+	if len(pre) != len(accountKeys) || len(post) != len(accountKeys) {
+		return nil
+	}
+
+	changes := make([]BalanceChange, 0, len(accountKeys))
+	for i, account := range accountKeys {
+		before, _ := pre[i].(float64)
+		after, _ := post[i].(float64)
+		changes = append(changes, BalanceChange{
+			Account:     account,
+			PreBalance:  uint64(before),
+			PostBalance: uint64(after),
+			Change:      int64(after) - int64(before),
+		})
+	}
+	return changes
+}
+
+type idempotentSend struct {
+	RequestHash string
+	Signature   string
+	Pending     bool
+}
+
+// SendTransaction submits a signed transaction. It is never retried, nor
+// failed over to another endpoint: a transport error does not mean the
+// transaction was not received, so the caller decides whether to resubmit.
+func (s *SolanaRPCClient) SendTransaction(ctx context.Context, transaction, encoding string, skipPreflight bool) (string, error) {
+	if encoding == "" {
+		encoding = "base64"
+	}
+
+	params := []interface{}{
+		transaction,
+		map[string]interface{}{
+			"encoding":      encoding,
+			"skipPreflight": skipPreflight,
+		},
+	}
+	resp, err := s.makeRPCCall(ctx, "sendTransaction", params)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.Error != nil {
+		return "", fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	signature, ok := resp.Result.(string)
+	if !ok {
+		return "", &ParseError{Method: "sendTransaction", Detail: "result is not a string"}
+	}
+
+	return signature, nil
+}
+
+func isValidSignature(signature string) bool {
+	decoded, err := base58.Decode(signature)
+	return err == nil && len(decoded) == 64
+}
+
+// parseMaxTransactionVersion reads the optional maxSupportedTransactionVersion
+// query parameter, defaulting to defaultMaxTransactionVersion.
+func parseMaxTransactionVersion(c *gin.Context) (int, error) {
+	raw := c.Query("maxSupportedTransactionVersion")
+	if raw == "" {
+		return defaultMaxTransactionVersion, nil
+	}
+	version, err := strconv.Atoi(raw)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid maxSupportedTransactionVersion %q", raw)
+	}
+	return version, nil
+}
+
+// transactionVersion renders the version field of getTransaction and getBlock
+// results, which is the string "legacy" or a version number.
+func transactionVersion(raw interface{}) string {
+	switch version := raw.(type) {
+	case string:
+		return version
+	case float64:
+		return strconv.Itoa(int(version))
+	default:
+		return "legacy"
+	}
+}
+
+// GetTransaction fetches a confirmed transaction. Unknown signatures return
+// an entry with IsValid false rather than an error.
+func (s *SolanaRPCClient) GetTransaction(ctx context.Context, signature string, maxVersion int) (*TransactionInfo, error) {
+	cacheKey := s.cacheKey("transaction", signature, maxVersion)
+	if cached, found := s.getFromCache(ctx, cacheKey); found {
+		if info, ok := cached.(*TransactionInfo); ok {
+			return info, nil
+		}
+	}
+
+	params := []interface{}{
+		signature,
+		map[string]interface{}{
+			"encoding":                       "jsonParsed",
+			"maxSupportedTransactionVersion": maxVersion,
+		},
+	}
+	resp, err := s.makeRPCCallWithRetry(ctx, "getTransaction", params)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	if resp.Result == nil {
+		return &TransactionInfo{Signature: signature, AccountKeys: []string{}, StaticAccountKeys: []string{}, IsValid: false}, nil
+	}
+
+	result, ok := resp.Result.(map[string]interface{})
+	if !ok {
+		return nil, &ParseError{Method: "getTransaction", Detail: "result is not an object"}
+	}
+
+	slot, _ := result["slot"].(float64)
+	info := &TransactionInfo{
+		Signature:         signature,
+		Slot:              BigUint(slot),
+		Version:           transactionVersion(result["version"]),
+		AccountKeys:       []string{},
+		StaticAccountKeys: []string{},
+		IsValid:           true,
+		rpcResults:        map[string]interface{}{"getTransaction": resp.Result},
+	}
+	if blockTime, ok := result["blockTime"].(float64); ok {
+		t := int64(blockTime)
+		info.BlockTime = &t
+	}
+
+	if meta, ok := result["meta"].(map[string]interface{}); ok {
+		fee, _ := meta["fee"].(float64)
+		info.Fee = uint64(fee)
+		info.Error = meta["err"]
+		info.Success = meta["err"] == nil
+		if units, ok := meta["computeUnitsConsumed"].(float64); ok {
+			consumed := uint64(units)
+			info.ComputeUnitsConsumed = &consumed
+		}
+		// logMessages is null when the node does not record logs.
+		if logs, ok := meta["logMessages"].([]interface{}); ok {
+			info.LogMessages = make([]string, 0, len(logs))
+			for _, line := range logs {
+				if line, ok := line.(string); ok {
+					info.LogMessages = append(info.LogMessages, line)
+				}
+			}
+		}
+	}
+
+	transaction, _ := result["transaction"].(map[string]interface{})
+	message, _ := transaction["message"].(map[string]interface{})
+	keys, _ := message["accountKeys"].([]interface{})
+	for _, key := range keys {
+		switch key := key.(type) {
+		case map[string]interface{}:
+			// jsonParsed already appends loaded addresses to accountKeys;
+			// they are rebuilt from the lookup tables below.
+			if source, _ := key["source"].(string); source == "lookupTable" {
+				continue
+			}
+			if pubkey, ok := key["pubkey"].(string); ok {
+				info.StaticAccountKeys = append(info.StaticAccountKeys, pubkey)
+			}
+		case string:
+			info.StaticAccountKeys = append(info.StaticAccountKeys, key)
+		}
+	}
+	info.AccountKeys = append(info.AccountKeys, info.StaticAccountKeys...)
+
+	if lookups := parseAddressTableLookups(message["addressTableLookups"]); len(lookups) > 0 {
+		loaded, err := s.resolveLoadedAddresses(ctx, lookups)
+		if err != nil {
+			// A closed table can no longer be read; the node's own record of
+			// the loaded addresses is the next best thing.
+			log.Printf("Failed to resolve lookup tables for %s: %v", signature, err)
+			meta, _ := result["meta"].(map[string]interface{})
+			loaded = parseLoadedAddresses(meta["loadedAddresses"])
+		}
+		info.LoadedAddresses = loaded
+		info.AccountKeys = append(info.AccountKeys, loaded.Writable...)
+		info.AccountKeys = append(info.AccountKeys, loaded.Readonly...)
+	}
+
+	// Confirmed transactions should always have meta, but a null one only
+	// leaves the balances out.
+	if meta, ok := result["meta"].(map[string]interface{}); ok {
+		info.BalanceChanges = parseBalanceChanges(meta, info.AccountKeys)
+	}
+
+	// A confirmed transaction does not change, but keeping every one
+	// looked up would grow the cache without bound, so only keep it for a
+	// while.
+	s.setCache(ctx, cacheKey, info, 10*time.Minute)
+
+	return info, nil
+}
+
+// claimIdempotencyKey reserves key for a send of the request identified by
+// requestHash. When the key is already known the stored entry is returned
+// instead and the caller must not submit.
+func (s *SolanaRPCClient) claimIdempotencyKey(key, requestHash string) (*idempotentSend, bool) {
+	cacheKey := s.cacheKey("idempotency", key)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entry, exists := s.cache[cacheKey]; exists && time.Now().Before(entry.ExpiresAt) {
+		if send, ok := entry.Data.(*idempotentSend); ok {
+			return send, false
+		}
+	}
+
+	now := time.Now()
+	s.putCacheEntry(cacheKey, CacheEntry{
+		Data:      &idempotentSend{RequestHash: requestHash, Pending: true},
+		CreatedAt: now,
+		ExpiresAt: now.Add(idempotencyKeyTTL),
+	})
+	return nil, true
+}
+
+// completeIdempotencyKey records the signature a send produced. It is not a
+// cached response, so it is kept even for a request pinned with ?rpc=.
+func (s *SolanaRPCClient) completeIdempotencyKey(key, requestHash, signature string) {
+	s.setCache(context.Background(), s.cacheKey("idempotency", key), &idempotentSend{RequestHash: requestHash, Signature: signature}, idempotencyKeyTTL)
+}
+
+func (s *SolanaRPCClient) releaseIdempotencyKey(key string) {
+	s.mutex.Lock()
+	s.deleteCacheEntry(s.cacheKey("idempotency", key))
+	s.mutex.Unlock()
+}
+
+func handleGetTransaction(client *SolanaRPCClient, debugMode bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		signature := c.Param("signature")
+		if !isValidSignature(signature) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction signature"})
+			return
+		}
+
+		maxVersion, err := parseMaxTransactionVersion(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "maxSupportedTransactionVersion must be a non-negative integer"})
+			return
+		}
+
+		raw, ok := rawRequested(c, debugMode)
+		if !ok {
+			return
+		}
+
+		info, err := client.GetTransaction(c.Request.Context(), signature, maxVersion)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get transaction", "details": err.Error()})
+			return
+		}
+		if raw {
+			// GetTransaction may return a cached value; attach to a copy.
+			withRaw := *info
+			withRaw.Raw = info.rpcResults
+			info = &withRaw
+		}
+
+		respondJSON(c, http.StatusOK, info)
+	}
+}
+
+func handleSendTransaction(client *SolanaRPCClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SendTransactionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A signed transaction is required"})
+			return
+		}
+
+		idempotencyKey := c.GetHeader("Idempotency-Key")
+		var requestHash string
+		if idempotencyKey != "" {
+			sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%t", req.Transaction, req.Encoding, req.SkipPreflight)))
+			requestHash = hex.EncodeToString(sum[:])
+
+			if previous, claimed := client.claimIdempotencyKey(idempotencyKey, requestHash); !claimed {
+				switch {
+				case previous.RequestHash != requestHash:
+					c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different transaction"})
+				case previous.Pending:
+					c.JSON(http.StatusConflict, gin.H{"error": "A send with this Idempotency-Key is still in progress"})
+				default:
+					respondJSON(c, http.StatusOK, gin.H{"signature": previous.Signature, "idempotentReplay": true})
+				}
+				return
+			}
+		}
+
+		signature, err := client.SendTransaction(c.Request.Context(), req.Transaction, req.Encoding, req.SkipPreflight)
+		if err != nil {
+			if idempotencyKey != "" {
+				client.releaseIdempotencyKey(idempotencyKey)
+			}
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send transaction", "details": err.Error()})
+			return
+		}
+
+		if idempotencyKey != "" {
+			client.completeIdempotencyKey(idempotencyKey, requestHash, signature)
+		}
+
+		respondJSON(c, http.StatusOK, gin.H{"signature": signature, "idempotentReplay": false})
+	}
+}
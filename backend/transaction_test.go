@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// v0TransactionResult is a jsonParsed getTransaction result for a v0
+// transaction loading one writable and one read-only address from a lookup
+// table.
+const v0TransactionResult = `{
+	"slot": 312000000,
+	"blockTime": 1735000000,
+	"version": 0,
+	"meta": {
+		"err": null,
+		"fee": 5000,
+		"computeUnitsConsumed": 3000,
+		"logMessages": ["Program 11111111111111111111111111111111 invoke [1]"],
+		"preBalances": [2000000, 0, 1, 1],
+		"postBalances": [1995000, 0, 1, 1],
+		"loadedAddresses": {
+			"writable": ["Wr1teab1e111111111111111111111111111111111"],
+			"readonly": ["Readon1y11111111111111111111111111111111111"]
+		}
+	},
+	"transaction": {
+		"signatures": ["5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW"],
+		"message": {
+			"accountKeys": [
+				{"pubkey": "Payer11111111111111111111111111111111111111", "signer": true, "source": "transaction", "writable": true},
+				{"pubkey": "11111111111111111111111111111111", "signer": false, "source": "transaction", "writable": false},
+				{"pubkey": "Wr1teab1e111111111111111111111111111111111", "signer": false, "source": "lookupTable", "writable": true},
+				{"pubkey": "Readon1y11111111111111111111111111111111111", "signer": false, "source": "lookupTable", "writable": false}
+			],
+			"addressTableLookups": [
+				{"accountKey": "Tab1e111111111111111111111111111111111111111", "writableIndexes": [0], "readonlyIndexes": [1]}
+			]
+		}
+	}
+}`
+
+// v0BlockResult is a getBlock result, with signatures only, for a block
+// containing the v0 transaction.
+const v0BlockResult = `{
+	"blockhash": "EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N",
+	"previousBlockhash": "8dJkqGzTDmuN3Xg1tt3ezMVmoHzzyhiwEqvRXGFGLNJb",
+	"parentSlot": 311999999,
+	"blockHeight": 290000000,
+	"blockTime": 1735000000,
+	"signatures": ["5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW"]
+}`
+
+// versionedNode answers getTransaction and getBlock like a node holding a
+// v0 transaction: without maxSupportedTransactionVersion they fail.
+func versionedNode(method string, params []interface{}) interface{} {
+	var result string
+	switch method {
+	case "getTransaction":
+		result = v0TransactionResult
+	case "getBlock":
+		result = v0BlockResult
+	default:
+		return nil
+	}
+	config, _ := params[len(params)-1].(map[string]interface{})
+	if _, ok := config["maxSupportedTransactionVersion"]; !ok {
+		return &testRPCError{Code: -32015, Message: "Transaction version (0) is not supported by the requesting client. Please try the request again with the following configuration parameter: \"maxSupportedTransactionVersion\": 0"}
+	}
+	return rawResults(map[string]string{method: result})(method, params)
+}
+
+func TestGetTransactionV0(t *testing.T) {
+	node, client := newTestRPCNode(t, versionedNode)
+
+	info, err := client.GetTransaction(context.Background(), "5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW", defaultMaxTransactionVersion)
+	if err != nil {
+		t.Fatalf("GetTransaction: %v", err)
+	}
+
+	if !info.IsValid || info.Version != "0" {
+		t.Errorf("got valid %v, version %q; want a valid version 0 transaction", info.IsValid, info.Version)
+	}
+	wantStatic := []string{"Payer11111111111111111111111111111111111111", "11111111111111111111111111111111"}
+	if !reflect.DeepEqual(info.StaticAccountKeys, wantStatic) {
+		t.Errorf("StaticAccountKeys = %v, want %v", info.StaticAccountKeys, wantStatic)
+	}
+	// The lookup table cannot be read from this node, so the addresses come
+	// from meta.loadedAddresses.
+	wantKeys := append(append([]string{}, wantStatic...), "Wr1teab1e111111111111111111111111111111111", "Readon1y11111111111111111111111111111111111")
+	if !reflect.DeepEqual(info.AccountKeys, wantKeys) {
+		t.Errorf("AccountKeys = %v, want %v", info.AccountKeys, wantKeys)
+	}
+
+	calls := node.callsTo("getTransaction")
+	if len(calls) != 1 {
+		t.Fatalf("got %d getTransaction calls, want 1", len(calls))
+	}
+	config, _ := calls[0].Params[1].(map[string]interface{})
+	if version, _ := config["maxSupportedTransactionVersion"].(float64); version != defaultMaxTransactionVersion {
+		t.Errorf("maxSupportedTransactionVersion = %v, want %d", config["maxSupportedTransactionVersion"], defaultMaxTransactionVersion)
+	}
+}
+
+func TestGetBlockWithV0Transaction(t *testing.T) {
+	_, client := newTestRPCNode(t, versionedNode)
+
+	block, err := client.GetBlock(context.Background(), 312000000, defaultMaxTransactionVersion)
+	if err != nil {
+		t.Fatalf("GetBlock: %v", err)
+	}
+	if block.TransactionCount != 1 || len(block.Signatures) != 1 {
+		t.Errorf("got %d transactions (%v), want the v0 transaction", block.TransactionCount, block.Signatures)
+	}
+}
+
+func TestParseMaxTransactionVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		query   string
+		want    int
+		wantErr bool
+	}{
+		{"", defaultMaxTransactionVersion, false},
+		{"?maxSupportedTransactionVersion=0", 0, false},
+		{"?maxSupportedTransactionVersion=1", 1, false},
+		{"?maxSupportedTransactionVersion=-1", 0, true},
+		{"?maxSupportedTransactionVersion=legacy", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/api/transaction/sig"+tt.query, nil)
+
+			got, err := parseMaxTransactionVersion(c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMaxWatches        = 100
+	defaultWatchPollInterval = 30 * time.Second
+	watchSignatureLimit      = 100
+
+	webhookTimeout   = 5 * time.Second
+	webhookAttempts  = 4
+	webhookBaseDelay = 1 * time.Second
+)
+
+var errTooManyWatches = errors.New("watch limit reached")
+
+type Watch struct {
+	ID            string     `json:"id"`
+	Address       string     `json:"address"`
+	WebhookURL    string     `json:"webhookUrl"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	LastSignature string     `json:"lastSignature,omitempty"`
+	LastDelivery  *time.Time `json:"lastDelivery,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+
+	delivering bool
+	// apiVersion is the version of the request that created the watch;
+	// webhooks are sent in its response shape.
+	apiVersion int
+}
+
+type WatchRequest struct {
+	Address    string `json:"address" binding:"required"`
+	WebhookURL string `json:"webhookUrl" binding:"required"`
+}
+
+// webhookPayload is POSTed to a watch's webhook with the signatures seen
+// since the last delivery, oldest first. Truncated means more transactions
+// arrived between polls than one delivery carries.
+type webhookPayload struct {
+	WatchID    string          `json:"watchId"`
+	Address    string          `json:"address"`
+	Signatures []SignatureInfo `json:"signatures"`
+	Truncated  bool            `json:"truncated"`
+}
+
+// watchRegistry polls getSignaturesForAddress for every watched address and
+// forwards new signatures to the watch's webhook. Polling is sequential and
+// goes through the rate limiter; deliveries run in the background and a
+// watch is not polled again until its delivery has finished.
+type watchRegistry struct {
+	client     *SolanaRPCClient
+	httpClient *http.Client
+	maxWatches int
+	interval   time.Duration
+
+	mutex   sync.Mutex
+	watches map[string]*Watch
+}
+
+func newWatchRegistry(client *SolanaRPCClient, maxWatches int, interval time.Duration, allowPrivate bool) *watchRegistry {
+	return &watchRegistry{
+		client:     client,
+		httpClient: newWebhookHTTPClient(allowPrivate),
+		maxWatches: maxWatches,
+		interval:   interval,
+		watches:    make(map[string]*Watch),
+	}
+}
+
+// newWebhookHTTPClient refuses to connect to loopback, private and
+// link-local addresses unless allowPrivate is set, so a webhook URL cannot
+// be used to reach services on the proxy's own network. The check runs on
+// the resolved address and therefore also covers redirects and DNS tricks.
+func newWebhookHTTPClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+				return fmt.Errorf("webhook address %s is not public", host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}
+}
+
+// getSignaturesSince returns the address's signatures newer than until,
+// newest first. An empty until returns only the latest signature, which
+// becomes the watch's starting point.
+func (s *SolanaRPCClient) getSignaturesSince(ctx context.Context, address, until string) ([]SignatureInfo, error) {
+	options := map[string]interface{}{"limit": watchSignatureLimit}
+	if until == "" {
+		options["limit"] = 1
+	} else {
+		options["until"] = until
+	}
+
+	resp, err := s.makeRPCCallWithRetry(ctx, "getSignaturesForAddress", []interface{}{address, options})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	return parseSignatureInfos(resp.Result)
+}
+
+func (w *watchRegistry) add(ctx context.Context, address, webhookURL string, apiVersion int) (*Watch, error) {
+	// The starting point is taken before the watch is visible so the first
+	// poll only reports transactions made after registration.
+	var lastSignature string
+	if latest, err := w.client.getSignaturesSince(ctx, address, ""); err != nil {
+		log.Printf("Watch %s: failed to get latest signature, starting from the first poll: %v", address, err)
+	} else if len(latest) > 0 {
+		lastSignature = latest[0].Signature
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.watches) >= w.maxWatches {
+		return nil, errTooManyWatches
+	}
+	watch := &Watch{
+		ID:            newRequestID(),
+		Address:       address,
+		WebhookURL:    webhookURL,
+		CreatedAt:     time.Now(),
+		LastSignature: lastSignature,
+		apiVersion:    apiVersion,
+	}
+	w.watches[watch.ID] = watch
+	copied := *watch
+	return &copied, nil
+}
+
+func (w *watchRegistry) get(id string) (*Watch, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	watch, ok := w.watches[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *watch
+	return &copied, true
+}
+
+func (w *watchRegistry) remove(id string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.watches[id]; !ok {
+		return false
+	}
+	delete(w.watches, id)
+	return true
+}
+
+func (w *watchRegistry) run() {
+	ctx := context.Background()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.mutex.Lock()
+		pending := make([]Watch, 0, len(w.watches))
+		for _, watch := range w.watches {
+			if !watch.delivering {
+				pending = append(pending, *watch)
+			}
+		}
+		w.mutex.Unlock()
+
+		for _, watch := range pending {
+			w.poll(ctx, watch)
+		}
+	}
+}
+
+func (w *watchRegistry) poll(ctx context.Context, snapshot Watch) {
+	signatures, err := w.client.getSignaturesSince(ctx, snapshot.Address, snapshot.LastSignature)
+	if err != nil {
+		log.Printf("Watch %s: failed to get signatures for %s: %v", snapshot.ID, snapshot.Address, err)
+		return
+	}
+	if len(signatures) == 0 {
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	watch, ok := w.watches[snapshot.ID]
+	if !ok {
+		return
+	}
+	if snapshot.LastSignature == "" {
+		watch.LastSignature = signatures[0].Signature
+		return
+	}
+
+	watch.delivering = true
+	payload := webhookPayload{
+		WatchID:    watch.ID,
+		Address:    watch.Address,
+		Signatures: make([]SignatureInfo, 0, len(signatures)),
+		Truncated:  len(signatures) == watchSignatureLimit,
+	}
+	for i := len(signatures) - 1; i >= 0; i-- {
+		payload.Signatures = append(payload.Signatures, signatures[i])
+	}
+	var body interface{} = payload
+	if watch.apiVersion >= bigIntStringsVersion {
+		body = withBigIntStrings(payload)
+	}
+	go w.deliver(watch.ID, watch.WebhookURL, body, signatures[0].Signature)
+}
+
+// deliver POSTs payload with exponential backoff. The watch only advances
+// past the delivered signatures on success, so failed deliveries are
+// retried with whatever is new at the next poll.
+func (w *watchRegistry) deliver(id, webhookURL string, payload interface{}, newest string) {
+	body, err := json.Marshal(payload)
+	if err == nil {
+		for attempt := 0; attempt < webhookAttempts; attempt++ {
+			if attempt > 0 {
+				time.Sleep(webhookBaseDelay << (attempt - 1))
+			}
+			err = w.post(webhookURL, body)
+			if err == nil {
+				break
+			}
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	watch, ok := w.watches[id]
+	if !ok {
+		return
+	}
+	watch.delivering = false
+	if err != nil {
+		watch.LastError = err.Error()
+		log.Printf("Watch %s: webhook delivery failed after %d attempts: %v", id, webhookAttempts, err)
+		return
+	}
+	now := time.Now()
+	watch.LastSignature = newest
+	watch.LastDelivery = &now
+	watch.LastError = ""
+}
+
+func (w *watchRegistry) post(webhookURL string, body []byte) error {
+	resp, err := w.httpClient.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func handleCreateWatch(registry *watchRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req WatchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "An address and a webhookUrl are required"})
+			return
+		}
+		if _, err := decodePublicKey(req.Address); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+			return
+		}
+		webhookURL, err := url.Parse(req.WebhookURL)
+		if err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Host == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "webhookUrl must be an http(s) URL"})
+			return
+		}
+
+		watch, err := registry.add(c.Request.Context(), req.Address, req.WebhookURL, apiVersion(c))
+		if errors.Is(err, errTooManyWatches) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "The maximum number of watches has been reached"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create watch"})
+			return
+		}
+
+		respondJSON(c, http.StatusCreated, watch)
+	}
+}
+
+func handleGetWatch(registry *watchRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		watch, ok := registry.get(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Watch not found"})
+			return
+		}
+
+		respondJSON(c, http.StatusOK, watch)
+	}
+}
+
+func handleDeleteWatch(registry *watchRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !registry.remove(c.Param("id")) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Watch not found"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsPingInterval = 30 * time.Second
+	wsPongTimeout  = 2 * wsPingInterval
+	wsWriteTimeout = 10 * time.Second
+
+	// defaultWSSendBuffer is how many updates may queue for a connection
+	// that is slower than the poller. Override with WS_SEND_BUFFER.
+	defaultWSSendBuffer = 16
+
+	// Clients pick their update cadence with ?interval=, clamped to these
+	// bounds so a single client cannot make the shared poller hammer the RPC.
+	minMetricsStreamInterval     = 1 * time.Second
+	maxMetricsStreamInterval     = 60 * time.Second
+	defaultMetricsStreamInterval = 5 * time.Second
+)
+
+// metricsSubscriber is a /ws/metrics connection or, with a nil conn, a
+// /api/metrics/stream event stream. lastEventID is the update an event
+// stream's client last saw, from its Last-Event-ID header. bigIntStrings is
+// set when the connecting request's API version encodes BigUint as strings.
+type metricsSubscriber struct {
+	conn          *websocket.Conn
+	interval      time.Duration
+	send          chan metricsUpdate
+	lastSent      time.Time
+	lastEventID   string
+	bigIntStrings bool
+}
+
+// metricsUpdate is one metrics snapshot, encoded with BigUint values as
+// numbers in payload and as strings in stringPayload. id, the time it was
+// taken in Unix milliseconds, is the event ID on event streams.
+type metricsUpdate struct {
+	id            string
+	payload       []byte
+	stringPayload []byte
+}
+
+// payloadFor returns the encoding sub asked for.
+func (u metricsUpdate) payloadFor(sub *metricsSubscriber) []byte {
+	if sub.bigIntStrings {
+		return u.stringPayload
+	}
+	return u.payload
+}
+
+// metricsHub fans metrics out to every /ws/metrics connection and
+// /api/metrics/stream event stream from a single
+// poller, so RPC load does not grow with the number of viewers. The poller
+// runs at the fastest interval any subscriber asked for and only exists
+// while someone is connected. Each update is encoded once per API version
+// shape and the same bytes are queued for every subscriber.
+type metricsHub struct {
+	client *SolanaRPCClient
+	// sendBuffer sizes each subscriber's queue. A subscriber whose queue is
+	// full misses the update, or is disconnected with disconnectSlow.
+	sendBuffer     int
+	disconnectSlow bool
+
+	mutex       sync.Mutex
+	subscribers map[*metricsSubscriber]bool
+	latest      *metricsUpdate
+	polling     bool
+	wake        chan struct{}
+}
+
+func newMetricsHub(client *SolanaRPCClient, sendBuffer int, disconnectSlow bool) *metricsHub {
+	return &metricsHub{
+		client:         client,
+		sendBuffer:     sendBuffer,
+		disconnectSlow: disconnectSlow,
+		subscribers:    make(map[*metricsSubscriber]bool),
+		wake:           make(chan struct{}, 1),
+	}
+}
+
+func (h *metricsHub) register(sub *metricsSubscriber) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.subscribers[sub] = true
+	// A reconnecting event stream that already has the latest update waits
+	// for the next one rather than getting it twice.
+	if h.latest != nil && h.latest.id != sub.lastEventID {
+		sub.send <- *h.latest
+		sub.lastSent = time.Now()
+	}
+	if !h.polling {
+		h.polling = true
+		go h.poll()
+		return
+	}
+
+	// The new subscriber may want updates sooner than the poller's current
+	// interval.
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (h *metricsHub) unregister(sub *metricsSubscriber) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.subscribers[sub] {
+		delete(h.subscribers, sub)
+		close(sub.send)
+	}
+}
+
+// fastestInterval returns the shortest interval requested by a subscriber,
+// or false when nobody is subscribed. Callers must hold the mutex.
+func (h *metricsHub) fastestInterval() (time.Duration, bool) {
+	fastest := time.Duration(0)
+	for sub := range h.subscribers {
+		if fastest == 0 || sub.interval < fastest {
+			fastest = sub.interval
+		}
+	}
+	return fastest, fastest > 0
+}
+
+func (h *metricsHub) poll() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			h.broadcast()
+		case <-h.wake:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+
+		h.mutex.Lock()
+		interval, ok := h.fastestInterval()
+		if !ok {
+			h.polling = false
+			h.latest = nil
+			h.mutex.Unlock()
+			return
+		}
+		h.mutex.Unlock()
+		timer.Reset(interval)
+	}
+}
+
+// broadcast fetches metrics once and sends them to every subscriber whose
+// interval has elapsed. Sends never block, so a slow subscriber cannot hold
+// up the others.
+func (h *metricsHub) broadcast() {
+	h.mutex.Lock()
+	idle := len(h.subscribers) == 0
+	h.mutex.Unlock()
+	if idle {
+		return
+	}
+
+	// The poll is shared by every subscriber, so it is not tied to any
+	// one connection's request. It joins a fetch started by /api/metrics
+	// rather than duplicating it, and waits for it however long it takes.
+	metrics, err := h.client.sharedMetrics(context.Background(), 0)
+	if err != nil {
+		log.Printf("Metrics stream: failed to get metrics: %v", err)
+		return
+	}
+	payload, err := json.Marshal(metrics)
+	if err != nil {
+		log.Printf("Metrics stream: failed to encode metrics: %v", err)
+		return
+	}
+	stringPayload, err := json.Marshal(withBigIntStrings(metrics))
+	if err != nil {
+		log.Printf("Metrics stream: failed to encode metrics: %v", err)
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := time.Now()
+	update := metricsUpdate{id: strconv.FormatInt(now.UnixMilli(), 10), payload: payload, stringPayload: stringPayload}
+	h.latest = &update
+	fastest, _ := h.fastestInterval()
+	for sub := range h.subscribers {
+		// Allow half a poll of slack so a client asking for a multiple of
+		// the poll interval is not pushed back by timer jitter.
+		if now.Sub(sub.lastSent) < sub.interval-fastest/2 {
+			continue
+		}
+		select {
+		case sub.send <- update:
+			sub.lastSent = now
+		default:
+			if h.disconnectSlow {
+				// Closing the connection ends readLoop, whose unregister
+				// finds the subscriber already gone. An event stream ends
+				// when it finds send closed.
+				delete(h.subscribers, sub)
+				close(sub.send)
+				if sub.conn != nil {
+					sub.conn.Close()
+					log.Printf("Metrics stream: disconnected %s, its send buffer is full", sub.conn.RemoteAddr())
+				}
+			}
+		}
+	}
+}
+
+func (sub *metricsSubscriber) writeLoop() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	defer sub.conn.Close()
+
+	for {
+		select {
+		case update, ok := <-sub.send:
+			sub.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if !ok {
+				sub.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := sub.conn.WriteMessage(websocket.TextMessage, update.payloadFor(sub)); err != nil {
+				return
+			}
+		case <-ticker.C:
+			sub.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := sub.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readLoop discards client messages; it exists to process pongs and notice
+// disconnects.
+func (sub *metricsSubscriber) readLoop(hub *metricsHub) {
+	defer hub.unregister(sub)
+
+	sub.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	sub.conn.SetPongHandler(func(string) error {
+		return sub.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+	for {
+		if _, _, err := sub.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// parseSecondsOrDuration reads a whole number of seconds or a Go duration
+// such as 5s.
+func parseSecondsOrDuration(raw string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(raw); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(raw)
+}
+
+func parseStreamInterval(raw string) (time.Duration, error) {
+	if raw == "" {
+		return defaultMetricsStreamInterval, nil
+	}
+	interval, err := parseSecondsOrDuration(raw)
+	if err != nil {
+		return 0, err
+	}
+	if interval < minMetricsStreamInterval {
+		interval = minMetricsStreamInterval
+	}
+	if interval > maxMetricsStreamInterval {
+		interval = maxMetricsStreamInterval
+	}
+	return interval, nil
+}
+
+func handleMetricsStream(hub *metricsHub, allowedOrigins []string) gin.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+			for _, allowed := range allowedOrigins {
+				if origin == allowed || allowed == "*" {
+					return true
+				}
+			}
+			return false
+		},
+	}
+
+	return func(c *gin.Context) {
+		interval, err := parseStreamInterval(c.Query("interval"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be a number of seconds or a duration such as 5s"})
+			return
+		}
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// The upgrader has already written an error response.
+			return
+		}
+
+		sub := &metricsSubscriber{
+			conn:          conn,
+			interval:      interval,
+			send:          make(chan metricsUpdate, hub.sendBuffer),
+			bigIntStrings: apiVersion(c) >= bigIntStringsVersion,
+		}
+		hub.register(sub)
+		go sub.writeLoop()
+		sub.readLoop(hub)
+	}
+}
@@ -108,7 +108,9 @@ export const AccountDisplay: React.FC<AccountDisplayProps> = ({
             <div className="bg-gray-700 rounded-lg p-4">
               <div className="text-gray-400 text-sm mb-1">Rent Epoch</div>
               <div className="text-white font-mono">
-                {account.rentEpoch.toLocaleString()}
+                {account.rentExempt
+                  ? "Rent exempt"
+                  : account.rentEpoch?.toLocaleString()}
               </div>
             </div>
 
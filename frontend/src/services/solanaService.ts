@@ -37,7 +37,8 @@ export interface AccountInfo {
   balance: number;
   executable: boolean;
   owner: string;
-  rentEpoch: number;
+  rentEpoch?: number;
+  rentExempt: boolean;
   lamports: number;
   dataLength: number;
   isValid: boolean;
@@ -69,7 +70,8 @@ export interface TokenHoldersResponse {
       balance: number;
       executable: boolean;
       owner: string;
-      rentEpoch: number;
+      rentEpoch?: number;
+      rentExempt: boolean;
       lamports: number;
       dataLength: number;
       isValid: boolean;